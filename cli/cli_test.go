@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// run executes the command line args and fails the test on errors
+func run(t *testing.T, args ...string) {
+	t.Helper()
+	rootCmd.SetArgs(args)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+}
+
+func TestKrigDefaultFormat(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "pancake")
+	run(t, "krig", "--csv", "../data/pancake.csv", "--dx", "100", "--dy", "100", "--output", prefix)
+
+	b, err := os.ReadFile(prefix + "_krig.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nodes []struct {
+		X, Y     float64
+		Value    *float64
+		Variance *float64
+	}
+	if err := json.Unmarshal(b, &nodes); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if len(nodes) == 0 || nodes[0].Value == nil {
+		t.Errorf("got %d nodes, want estimations", len(nodes))
+	}
+}
+
+func TestReportTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.html")
+	run(t, "report", "--csv", "../data/pancake.csv", "--dx", "100", "--dy", "100", "--timeout", "1ns", "--output", path)
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "Kriging estimate") {
+		t.Error("report lacks the kriging map of the interrupted run")
+	}
+}
+
+func TestDelimitedDat(t *testing.T) {
+	b, err := os.ReadFile("../data/pancake.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "pancake.dat")
+	if err := os.WriteFile(input, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// delimited text is not mistaken for GSLIB
+	run(t, "vario", "--csv", input, "--format", "csv", "--output", filepath.Join(dir, "pancake"))
+	if _, err := os.Stat(filepath.Join(dir, "pancake_variogram.csv")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,366 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mmaelicke/go-geostat/internal/distance"
+	"github.com/mmaelicke/go-geostat/internal/estimator"
+	"github.com/mmaelicke/go-geostat/internal/kriging"
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"github.com/mmaelicke/go-geostat/io/csv"
+	"github.com/mmaelicke/go-geostat/io/geotiff"
+	"github.com/mmaelicke/go-geostat/io/netcdf"
+	"github.com/spf13/cobra"
+)
+
+// Config holds all configuration options for the variogram and kriging commands
+type Config struct {
+	// Input/Output options
+	CSVPath      string
+	InputPath    string
+	OutputPath   string
+	OutputFormat string
+
+	// Column specifications
+	XCol          string
+	YCol          string
+	ZCol          string
+	TCol          string
+	ValueCols     []string
+	CovariateCols []string
+	GroupCol      string
+	ErrorCol      string
+	DateCol       string
+
+	// Delimited text options
+	Delimiter    string
+	DecimalComma bool
+	Missing      []string
+	NoHeader     bool
+
+	// Variogram parameters
+	NLags  int
+	MaxLag float64
+
+	// Model parameters
+	ModelName     string
+	DistType      string
+	EstimatorName string
+	TimeFormats   []string
+	TimeZone      string
+	TimeAxis      string
+	TimeOrigin    string
+	Azimuth       float64
+	AnisoRatio    float64
+
+	// Processing options
+	MaxPoints    int
+	MinPoints    int
+	Radius       float64
+	Sectors      int
+	MaxPerSector int
+	MaxPerGroup  int
+	Workers      int
+	Timeout      time.Duration
+	Duplicates   string
+	DupTolerance float64
+	MaxCondition float64
+	ErrorMode    string
+	DX           float64
+	DY           float64
+	DZ           float64
+	Padding      float64
+	MaskPath     string
+	TemplatePath string
+	NoData       float64
+	Precision    int
+	EPSG         int
+	Geographic   bool
+	Float64      bool
+	Drift        []string
+	Registration string
+
+	// Flags
+	Performance bool
+	Fit         bool
+	UseKriging  bool
+	KrigingOnly bool
+	UseSGS      bool
+	SGSOnly     bool
+	SGSSimCount int
+	Diagnostics bool
+
+	// Cross-validation options
+	Folds int
+	Seed  int64
+}
+
+// newDefaultConfig returns a Config with default values
+func newDefaultConfig() *Config {
+	return &Config{
+		ValueCols:     []string{"value"},
+		NLags:         10,
+		MaxLag:        0,
+		MaxPoints:     100,
+		MinPoints:     1,
+		DX:            1.0,
+		DY:            1.0,
+		DZ:            1.0,
+		SGSSimCount:   1,
+		ModelName:     "spherical",
+		DistType:      "euclidean",
+		EstimatorName: "matheron",
+		Duplicates:    "keep",
+		ErrorMode:     "filtered",
+		NoData:        -9999,
+		Precision:     6,
+	}
+}
+
+// bindInputFlags registers the input, output and column flags
+func bindInputFlags(cmd *cobra.Command, config *Config) {
+	// Input/Output flags
+	cmd.Flags().StringVar(&config.CSVPath, "csv", "", "Path to input CSV file")
+	cmd.Flags().StringVar(&config.InputPath, "input", "", "Path to input file, read by extension: CSV, GeoJSON (.geojson, .json), GSLIB (.gslib, .gsl, .dat) or shapefile (.shp with .dbf)")
+	cmd.Flags().StringVar(&config.OutputPath, "output", "", "Path to output file")
+	cmd.Flags().StringVar(&config.OutputFormat, "format", "json", "Output format (json, csv, geojson, gslib, asc, tif, nc, vtk, vti, grd, grd6, grdtxt, xyz)")
+
+	// Column specification flags
+	cmd.Flags().StringVar(&config.XCol, "x", "x", "X coordinate column name")
+	cmd.Flags().StringVar(&config.YCol, "y", "y", "Y coordinate column name")
+	cmd.Flags().StringVar(&config.ZCol, "z", "", "Z coordinate column name")
+	cmd.Flags().StringVar(&config.TCol, "t", "", "Time column name")
+	cmd.Flags().StringSliceVar(&config.ValueCols, "value", []string{"value"}, "Value column or property name(s), comma separated or repeated")
+	cmd.Flags().StringVar(&config.GroupCol, "group", "", "Group id column name, e.g. drillhole")
+	cmd.Flags().StringVar(&config.ErrorCol, "errvar", "", "Measurement error variance column name")
+	cmd.Flags().StringSliceVar(&config.CovariateCols, "covariates", nil, "Covariate column name(s), comma separated or repeated")
+	cmd.Flags().StringVar(&config.DateCol, "date", "", "Date column name, joined with the time column (--t) as time of day")
+	cmd.Flags().StringArrayVar(&config.TimeFormats, "timeformat", nil, "Go time layout, unix or unixms; repeat for fallbacks (default ISO 8601)")
+	cmd.Flags().StringVar(&config.TimeZone, "timezone", "", "Time zone of times without zone, e.g. Europe/Berlin (default UTC)")
+	cmd.Flags().StringVar(&config.TimeAxis, "time-axis", "", "Use time as z coordinate in this unit (ms, s, min, h, d, w, y or a duration) for space-time analysis")
+	cmd.Flags().StringVar(&config.TimeOrigin, "time-origin", "", "Origin of the time axis (default earliest time of the data)")
+
+	// Delimited text flags
+	cmd.Flags().StringVar(&config.Delimiter, "delimiter", "", "Field delimiter of CSV input: a character, tab or space (runs of whitespace); default , or ; with --decimal-comma")
+	cmd.Flags().BoolVar(&config.DecimalComma, "decimal-comma", false, "Parse numbers with a decimal comma, e.g. 3,14")
+	cmd.Flags().StringSliceVar(&config.Missing, "na", nil, "Missing value sentinels of CSV input, e.g. NA,-9999")
+	cmd.Flags().BoolVar(&config.NoHeader, "noheader", false, "CSV input has no header row; select columns by 1-based index")
+}
+
+// bindVariogramFlags registers the empirical variogram and model flags
+func bindVariogramFlags(cmd *cobra.Command, config *Config) {
+	// Variogram parameter flags
+	cmd.Flags().IntVar(&config.NLags, "nlags", 10, "Number of lags")
+	cmd.Flags().Float64Var(&config.MaxLag, "maxlag", 0, "Maximum lag distance")
+
+	// Model parameter flags
+	cmd.Flags().StringVar(&config.ModelName, "model", "spherical", "Variogram model type")
+	cmd.Flags().StringVar(&config.DistType, "dist", "euclidean", "Distance metric")
+	cmd.Flags().StringVar(&config.EstimatorName, "estimator", "matheron", "Variogram estimator")
+	cmd.Flags().Float64Var(&config.Azimuth, "azimuth", 0, "Azimuth of the major anisotropy axis in degrees clockwise from north")
+	cmd.Flags().Float64Var(&config.AnisoRatio, "ratio", 1, "Minor to major range ratio for geometric anisotropy")
+
+	cmd.Flags().BoolVar(&config.Performance, "perf", false, "Enable performance profiling")
+}
+
+// bindGridFlags registers the neighbourhood and target grid flags
+func bindGridFlags(cmd *cobra.Command, config *Config) {
+	cmd.Flags().IntVar(&config.MaxPoints, "maxpoints", 100, "Maximum number of points to use")
+	cmd.Flags().IntVar(&config.MinPoints, "minpoints", 1, "Minimum number of points needed for an estimate")
+	cmd.Flags().Float64Var(&config.Radius, "radius", 0, "Search radius along the major axis, 0 for no limit")
+	cmd.Flags().IntVar(&config.Sectors, "sectors", 0, "Search sectors: 4 (quadrants) or 8 (octants)")
+	cmd.Flags().IntVar(&config.MaxPerSector, "maxpersector", 0, "Maximum number of points per search sector")
+	cmd.Flags().IntVar(&config.MaxPerGroup, "maxpergroup", 0, "Maximum number of points per group id")
+	cmd.Flags().BoolVar(&config.Diagnostics, "diagnostics", false, "Add kriging diagnostics columns to CSV output")
+	cmd.Flags().IntVar(&config.Workers, "workers", 0, "Number of kriging workers and of concurrent SGS realizations, 0 for one per CPU")
+	cmd.Flags().StringVar(&config.Duplicates, "duplicates", "keep", "Handling of duplicate locations (keep, average, first, measurement, error)")
+	cmd.Flags().Float64Var(&config.DupTolerance, "duptol", 0, "Distance below which points are duplicates")
+	cmd.Flags().Float64Var(&config.MaxCondition, "maxcond", 0, "Largest condition number solved without fallback, 0 for the default")
+	cmd.Flags().StringVar(&config.ErrorMode, "errormode", "filtered", "Use of measurement errors (filtered, exact)")
+	cmd.Flags().DurationVar(&config.Timeout, "timeout", 0, "Abort interpolation after this duration, e.g. 10m")
+	cmd.Flags().Float64Var(&config.DX, "dx", 1.0, "X grid spacing")
+	cmd.Flags().Float64Var(&config.DY, "dy", 1.0, "Y grid spacing")
+	cmd.Flags().Float64Var(&config.DZ, "dz", 1.0, "Z grid spacing")
+	cmd.Flags().StringVar(&config.MaskPath, "mask", "", "Prediction domain as WKT, GeoJSON polygons or ASC/GeoTIFF/Surfer/XYZ raster; other cells are NODATA")
+	cmd.Flags().StringVar(&config.TemplatePath, "template", "", "ASC, GeoTIFF, Surfer or XYZ raster defining the target grid; its NODATA cells are masked")
+	cmd.Flags().StringSliceVar(&config.Drift, "drift", nil, "External drift covariates as name (template values) or name=raster.asc|tif")
+	cmd.Flags().Float64Var(&config.NoData, "nodata", -9999, "NODATA value of raster and NetCDF output")
+	cmd.Flags().IntVar(&config.Precision, "precision", 6, "Decimals of raster output, -1 for full precision")
+	cmd.Flags().IntVar(&config.EPSG, "epsg", 0, "EPSG code of the coordinate reference system written to GeoTIFF output")
+	cmd.Flags().BoolVar(&config.Geographic, "geographic", false, "Write the EPSG code as geographic (longitude/latitude) instead of projected system")
+	cmd.Flags().BoolVar(&config.Float64, "float64", false, "Write 64-bit instead of 32-bit GeoTIFF and NetCDF values")
+	cmd.Flags().Float64Var(&config.Padding, "pad", 0, "Grow the grid by this distance beyond the data")
+	cmd.Flags().StringVar(&config.Registration, "registration", "center", "Grid registration relative to the data bounds (center, corner)")
+}
+
+// covariates returns the covariate columns to read, including the drift
+// covariates
+func (c *Config) covariates() []string {
+	cols := append([]string{}, c.CovariateCols...)
+	for _, drift := range c.Drift {
+		name, _, _ := strings.Cut(drift, "=")
+		found := false
+		for _, col := range cols {
+			found = found || col == name
+		}
+		if !found {
+			cols = append(cols, name)
+		}
+	}
+	return cols
+}
+
+// driftNames returns the names of the drift covariates
+func (c *Config) driftNames() []string {
+	names := make([]string, len(c.Drift))
+	for i, drift := range c.Drift {
+		names[i], _, _ = strings.Cut(drift, "=")
+	}
+	return names
+}
+
+// neighborhood returns the kriging search neighborhood described by config
+func (c *Config) neighborhood() kriging.Neighborhood {
+	nh := kriging.DefaultNeighborhood(c.MaxPoints)
+	if c.Radius > 0 {
+		nh.MaxDistance = c.Radius
+	}
+	nh.MinPoints = c.MinPoints
+	nh.Sectors = c.Sectors
+	nh.MaxPerSector = c.MaxPerSector
+	nh.MaxPerGroup = c.MaxPerGroup
+	return nh
+}
+
+// kriging returns an ordinary kriging interpolator for model configured by
+// the neighborhood, solver and duplicate options
+func (c *Config) kriging(model types.SpatialFunction, dist types.Distance) (*kriging.OrdinaryKriging, error) {
+	policy, err := kriging.ParseDuplicatePolicy(c.Duplicates)
+	if err != nil {
+		return nil, err
+	}
+	kr := kriging.New(model, c.MaxPoints, dist, false)
+	kr.SetNeighborhood(c.neighborhood())
+	kr.SetWorkers(c.Workers)
+	kr.SetDiagnostics(c.Diagnostics)
+	kr.SetDuplicates(policy, c.DupTolerance)
+	kr.SetDrift(c.driftNames()...)
+	switch strings.ToLower(c.ErrorMode) {
+	case "filtered", "":
+		kr.SetErrorMode(kriging.Filtered)
+	case "exact":
+		kr.SetErrorMode(kriging.Exact)
+	default:
+		return nil, fmt.Errorf("unsupported error mode: %s", c.ErrorMode)
+	}
+	if c.MaxCondition > 0 {
+		solver := kriging.DefaultSolver()
+		solver.MaxCondition = c.MaxCondition
+		kr.SetSolver(solver)
+	}
+	return kr, nil
+}
+
+// extension returns the file extension of the output format. All Surfer
+// formats are written to .grd files.
+func (c *Config) extension() string {
+	switch c.OutputFormat {
+	case "grd", "grd6", "grdtxt":
+		return "grd"
+	}
+	return c.OutputFormat
+}
+
+// singleBand reports whether the output format holds a single grid per file
+func (c *Config) singleBand() bool {
+	switch c.OutputFormat {
+	case "asc", "grd", "grd6", "grdtxt":
+		return true
+	}
+	return false
+}
+
+// csv returns the options of delimited text input
+func (c *Config) csv() (csv.Options, error) {
+	opts := csv.Options{
+		DecimalComma: c.DecimalComma,
+		Missing:      c.Missing,
+		NoHeader:     c.NoHeader,
+		TimeLayouts:  c.TimeFormats,
+	}
+	loc, err := c.location()
+	if err != nil {
+		return opts, err
+	}
+	opts.Location = loc
+	switch c.Delimiter {
+	case "":
+	case "tab", "\\t":
+		opts.Delimiter = '\t'
+	case "space", " ":
+		opts.Delimiter = ' '
+	default:
+		r := []rune(c.Delimiter)
+		if len(r) != 1 {
+			return opts, fmt.Errorf("invalid delimiter %q, need a single character, tab or space", c.Delimiter)
+		}
+		opts.Delimiter = r[0]
+	}
+	return opts, nil
+}
+
+// location returns the time zone of times without zone
+func (c *Config) location() (*time.Location, error) {
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", c.TimeZone, err)
+	}
+	return loc, nil
+}
+
+// geotiff returns the options of GeoTIFF output
+func (c *Config) geotiff() geotiff.Options {
+	return geotiff.Options{Float64: c.Float64, NoData: c.NoData, EPSG: c.EPSG, Geographic: c.Geographic}
+}
+
+// netcdf returns the options of NetCDF output
+func (c *Config) netcdf() netcdf.Options {
+	return netcdf.Options{Float64: c.Float64, FillValue: c.NoData}
+}
+
+// distance returns the configured distance metric, including anisotropy
+func (c *Config) distance() (types.Distance, error) {
+	var dist types.Distance
+	switch strings.ToLower(c.DistType) {
+	case "chebyshev":
+		dist = &distance.ChebyshevDistance{}
+	case "manhattan":
+		dist = &distance.ManhattanDistance{}
+	case "euclidean":
+		dist = &distance.EuclideanDistance{}
+	default:
+		return nil, fmt.Errorf("unsupported distance type: %s", c.DistType)
+	}
+	if c.AnisoRatio != 0 && c.AnisoRatio != 1 {
+		dist = &distance.AnisotropicDistance{
+			Metric:  dist,
+			Azimuth: c.Azimuth,
+			Ratio:   c.AnisoRatio,
+		}
+	}
+	return dist, nil
+}
+
+// estimator returns the configured semi-variance estimator
+func (c *Config) estimator() (types.Estimator, error) {
+	switch strings.ToLower(c.EstimatorName) {
+	case "matheron":
+		return &estimator.Matheron{}, nil
+	case "cressie":
+		return &estimator.Cressie{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported estimator: %s", c.EstimatorName)
+	}
+}
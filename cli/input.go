@@ -0,0 +1,339 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/mask"
+	"github.com/mmaelicke/go-geostat/internal/timeaxis"
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"github.com/mmaelicke/go-geostat/io/asc"
+	"github.com/mmaelicke/go-geostat/io/csv"
+	"github.com/mmaelicke/go-geostat/io/geojson"
+	"github.com/mmaelicke/go-geostat/io/geotiff"
+	"github.com/mmaelicke/go-geostat/io/gslib"
+	"github.com/mmaelicke/go-geostat/io/shapefile"
+	"github.com/mmaelicke/go-geostat/io/surfer"
+	"github.com/mmaelicke/go-geostat/io/xyz"
+)
+
+// readData reads the input points from the configured file or stdin. GeoJSON,
+// GSLIB and shapefile input is chosen by the extension of the input path,
+// .dat files are read as GSLIB if they start with a GSLIB header. Anything
+// else is read as delimited text, which may be gzip compressed. Skipped rows are
+// reported on stderr. With a time axis, time becomes the z coordinate.
+func readData(config *Config) (csv.PointData, error) {
+	data, err := readPoints(config)
+	if err != nil || config.TimeAxis == "" {
+		return data, err
+	}
+	return spaceTime(config, data)
+}
+
+// readPoints reads the input points in the format chosen by readData
+func readPoints(config *Config) (csv.PointData, error) {
+	path := config.CSVPath
+	if config.InputPath != "" {
+		path = config.InputPath
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".geojson", ".json":
+		return readGeoJSON(config, path)
+	case ".gslib", ".gsl":
+		return readGSLIB(config, path)
+	case ".dat":
+		if isGSLIB(path) {
+			return readGSLIB(config, path)
+		}
+	case ".shp":
+		return readShapefile(config, path)
+	}
+
+	cols := csv.Columns{
+		X:          config.XCol,
+		Y:          config.YCol,
+		Z:          config.ZCol,
+		T:          config.TCol,
+		Values:     config.ValueCols,
+		Covariates: config.covariates(),
+		Group:      config.GroupCol,
+
+		ErrorVariance: config.ErrorCol,
+		Date:          config.DateCol,
+	}
+
+	opts, err := config.csv()
+	if err != nil {
+		return csv.PointData{}, err
+	}
+	var data csv.PointData
+	var report csv.Report
+	if path != "" {
+		data, report, err = csv.ReadFile(path, cols, opts)
+	} else {
+		data, report, err = csv.ReadFromReader(os.Stdin, cols, opts)
+	}
+	if err != nil {
+		return csv.PointData{}, fmt.Errorf("error reading CSV: %v", err)
+	}
+	if len(report.Skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", report)
+	}
+	return data, nil
+}
+
+// readGeoJSON reads the input points from the GeoJSON file at path, with the
+// configured columns as property names
+func readGeoJSON(config *Config, path string) (csv.PointData, error) {
+	props := geojson.Properties{
+		Values:        config.ValueCols,
+		Covariates:    config.covariates(),
+		Group:         config.GroupCol,
+		ErrorVariance: config.ErrorCol,
+		Time:          config.TCol,
+		TimeLayouts:   config.TimeFormats,
+		Date:          config.DateCol,
+	}
+	loc, err := config.location()
+	if err != nil {
+		return csv.PointData{}, err
+	}
+	props.Location = loc
+	points, err := geojson.ReadPoints(path, props)
+	if err != nil {
+		return csv.PointData{}, fmt.Errorf("error reading GeoJSON: %v", err)
+	}
+	return csv.PointData{
+		Points:     points.Points,
+		Is3D:       points.Is3D,
+		Variables:  config.ValueCols,
+		Covariates: props.Covariates,
+	}, nil
+}
+
+// spaceTime maps the time of the points onto the configured time axis and
+// stores it as their z coordinate. The axis starts at the configured origin,
+// or at the earliest time of the data.
+func spaceTime(config *Config, data csv.PointData) (csv.PointData, error) {
+	unit, err := timeaxis.ParseUnit(config.TimeAxis)
+	if err != nil {
+		return csv.PointData{}, err
+	}
+	axis := timeaxis.Axis{Unit: unit}
+	if config.TimeOrigin != "" {
+		loc, err := config.location()
+		if err != nil {
+			return csv.PointData{}, err
+		}
+		parser := timeaxis.NewParser()
+		parser.Location = loc
+		if axis.Origin, err = parser.Parse(config.TimeOrigin); err != nil {
+			return csv.PointData{}, fmt.Errorf("invalid time origin: %w", err)
+		}
+	} else {
+		origin, ok := timeaxis.Earliest(data.Points)
+		if !ok {
+			return csv.PointData{}, fmt.Errorf("time axis needs a time column, see --t")
+		}
+		axis.Origin = origin
+	}
+
+	points, err := axis.SpaceTime(data.Read())
+	if err != nil {
+		return csv.PointData{}, fmt.Errorf("error building time axis: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Time axis: z in %s\n", axis)
+	data.Points, data.Is3D = points.Points, true
+	return data, nil
+}
+
+// readShapefile reads the input points from the shapefile at path, with the
+// configured columns as DBF attribute names
+func readShapefile(config *Config, path string) (csv.PointData, error) {
+	fields := shapefile.Fields{
+		Values:        config.ValueCols,
+		Covariates:    config.covariates(),
+		Group:         config.GroupCol,
+		ErrorVariance: config.ErrorCol,
+		Time:          config.TCol,
+		TimeLayouts:   config.TimeFormats,
+		Date:          config.DateCol,
+	}
+	loc, err := config.location()
+	if err != nil {
+		return csv.PointData{}, err
+	}
+	fields.Location = loc
+	points, report, err := shapefile.ReadPoints(path, fields)
+	if err != nil {
+		return csv.PointData{}, fmt.Errorf("error reading shapefile: %v", err)
+	}
+	if len(report.Skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", report)
+	}
+	return csv.PointData{
+		Points:     points.Points,
+		Is3D:       points.Is3D,
+		Variables:  config.ValueCols,
+		Covariates: fields.Covariates,
+	}, nil
+}
+
+// isGSLIB reports whether the file at path starts with a GSLIB header
+func isGSLIB(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	return gslib.Detect(f)
+}
+
+// readGSLIB reads the input points from the GSLIB file at path, with the
+// configured columns as variable names. GSLIB files have no groups, error
+// variances or times.
+func readGSLIB(config *Config, path string) (csv.PointData, error) {
+	for _, col := range []struct{ flag, name string }{
+		{"--group", config.GroupCol},
+		{"--errvar", config.ErrorCol},
+		{"--t", config.TCol},
+		{"--date", config.DateCol},
+	} {
+		if col.name != "" {
+			return csv.PointData{}, fmt.Errorf("%s is not supported for GSLIB input", col.flag)
+		}
+	}
+	cols := gslib.Columns{
+		X:          config.XCol,
+		Y:          config.YCol,
+		Z:          config.ZCol,
+		Values:     config.ValueCols,
+		Covariates: config.covariates(),
+	}
+	points, report, err := gslib.ReadPoints(path, cols)
+	if err != nil {
+		return csv.PointData{}, fmt.Errorf("error reading GSLIB: %v", err)
+	}
+	if len(report.Skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", report)
+	}
+	return csv.PointData{
+		Points:     points.Points,
+		Is3D:       points.Is3D,
+		Variables:  config.ValueCols,
+		Covariates: cols.Covariates,
+	}, nil
+}
+
+// domain describes where to estimate: on the grid of an optional template
+// raster, with the drift covariates sampled on that grid, and inside the mask
+type domain struct {
+	template   *grid.Spec
+	covariates map[string][]float64
+	mask       types.Mask
+}
+
+// locations returns the nodes of spec, with the drift covariates attached
+func (d domain) locations(spec grid.Spec) types.Locations {
+	if len(d.covariates) == 0 {
+		return spec
+	}
+	return grid.CovariateGrid{Spec: spec, Covariates: d.covariates}
+}
+
+// readDomain reads the template raster, drift covariate rasters and mask.
+func readDomain(config *Config) (domain, error) {
+	d := domain{}
+	m, err := readMask(config)
+	if err != nil {
+		return d, fmt.Errorf("error reading mask: %w", err)
+	}
+	masks := mask.All{}
+	if m != nil {
+		masks = append(masks, m)
+	}
+
+	var templateValues []float64
+	if config.TemplatePath != "" {
+		spec, values, err := readRaster(config.TemplatePath)
+		if err != nil {
+			return d, fmt.Errorf("error reading template: %w", err)
+		}
+		d.template = &spec
+		templateValues = values
+		// NODATA cells of the template are outside of the domain
+		masks = append(masks, mask.NewRaster(spec, values))
+	}
+
+	for _, drift := range config.Drift {
+		name, path, _ := strings.Cut(drift, "=")
+		if d.template == nil {
+			return d, fmt.Errorf("drift %s needs a template grid", name)
+		}
+		values := templateValues
+		if path != "" {
+			spec, v, err := readRaster(path)
+			if err != nil {
+				return d, fmt.Errorf("error reading drift %s: %w", name, err)
+			}
+			if err := d.template.Aligned(spec); err != nil {
+				return d, fmt.Errorf("drift %s does not align with the template: %w", name, err)
+			}
+			values = v
+		}
+		if d.covariates == nil {
+			d.covariates = make(map[string][]float64)
+		}
+		d.covariates[name] = values
+	}
+
+	if len(masks) > 0 {
+		d.mask = masks
+	}
+	return d, nil
+}
+
+// readMask reads the prediction domain from a WKT, GeoJSON, ESRI ASCII,
+// GeoTIFF, Surfer or XYZ file, chosen by the file extension. Without a mask
+// path, it returns nil.
+func readMask(config *Config) (types.Mask, error) {
+	if config.MaskPath == "" {
+		return nil, nil
+	}
+	switch strings.ToLower(filepath.Ext(config.MaskPath)) {
+	case ".wkt", ".txt":
+		b, err := os.ReadFile(config.MaskPath)
+		if err != nil {
+			return nil, err
+		}
+		return mask.ParseWKT(string(b))
+	case ".geojson", ".json":
+		return mask.ReadGeoJSONFile(config.MaskPath)
+	case ".asc", ".tif", ".tiff", ".grd", ".xyz":
+		spec, values, err := readRaster(config.MaskPath)
+		if err != nil {
+			return nil, err
+		}
+		return mask.NewRaster(spec, values), nil
+	default:
+		return nil, fmt.Errorf("unsupported mask file %s, need .wkt, .geojson, .asc, .tif, .grd or .xyz", config.MaskPath)
+	}
+}
+
+// readRaster reads a GeoTIFF for .tif and .tiff files, a Surfer grid for .grd
+// files, XYZ text for .xyz files and an ESRI ASCII grid otherwise.
+func readRaster(path string) (grid.Spec, []float64, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tif", ".tiff":
+		return geotiff.ReadFile(path)
+	case ".grd":
+		return surfer.ReadFile(path)
+	case ".xyz":
+		return xyz.ReadFile(path, xyz.DefaultOptions())
+	default:
+		return asc.ReadAsc(path)
+	}
+}
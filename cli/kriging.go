@@ -1,15 +1,33 @@
 package cli
 
-import "github.com/spf13/cobra"
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
 
 func init() {
+	config := newDefaultConfig()
+
 	krigingCmd := &cobra.Command{
 		Use:   "krig",
 		Short: "Kriging implementation",
-		Run: func(cmd *cobra.Command, args []string) {
+		Long: `Fit a variogram model and krige every requested value column onto a dense grid.
 
+Pass several value columns to --value to krige all of them in one run.
+Each variable is written to its own output file.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			config.UseKriging = true
+			config.KrigingOnly = true
+			if err := runVariogram(config); err != nil {
+				log.Fatalf("Error running kriging: %v", err)
+			}
 		},
 	}
 
+	bindInputFlags(krigingCmd, config)
+	bindVariogramFlags(krigingCmd, config)
+	bindGridFlags(krigingCmd, config)
+
 	rootCmd.AddCommand(krigingCmd)
 }
@@ -0,0 +1,306 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"github.com/mmaelicke/go-geostat/io/asc"
+	"github.com/mmaelicke/go-geostat/io/csv"
+	"github.com/mmaelicke/go-geostat/io/geojson"
+	"github.com/mmaelicke/go-geostat/io/geotiff"
+	"github.com/mmaelicke/go-geostat/io/gslib"
+	"github.com/mmaelicke/go-geostat/io/json"
+	"github.com/mmaelicke/go-geostat/io/netcdf"
+	"github.com/mmaelicke/go-geostat/io/surfer"
+	"github.com/mmaelicke/go-geostat/io/vtk"
+	"github.com/mmaelicke/go-geostat/io/xyz"
+)
+
+// fileSink closes the underlying file once the sink is flushed
+type fileSink struct {
+	types.EstimationSink
+	f *os.File
+}
+
+func (s fileSink) Flush() error {
+	if err := s.EstimationSink.Flush(); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
+
+// discard closes and removes the file without flushing the sink, for output
+// that is abandoned before it is complete
+func (s fileSink) discard() {
+	s.f.Close()
+	os.Remove(s.f.Name())
+}
+
+// separator announces the next of several results written to stdout, e.g.
+// a variable or realization. It goes to stderr, as stdout only carries the
+// results, so that they stay parseable.
+func separator(kind string, name any) {
+	fmt.Fprintf(os.Stderr, "--- %s %v ---\n", kind, name)
+}
+
+// discard removes the files of an abandoned sink, e.g. one interrupted by
+// kriging.OrdinaryKriging.InterpolateTo. Output to stdout is left as is.
+func discard(sink types.EstimationSink) {
+	switch s := sink.(type) {
+	case fileSink:
+		s.discard()
+	case types.MultiSink:
+		for _, sink := range s {
+			discard(sink)
+		}
+	}
+}
+
+// targetGrid returns the template grid of the domain, if any, or the grid
+// covering points, padded by the configured distance. Its nodes are generated
+// lazily in raster order.
+func targetGrid(points types.Points, config *Config, d domain) (grid.Spec, error) {
+	if d.template != nil {
+		if points.Is3D {
+			return grid.Spec{}, fmt.Errorf("a 2D template cannot be used with 3D points")
+		}
+		return *d.template, nil
+	}
+	if len(points.Points) == 0 {
+		return grid.Spec{}, fmt.Errorf("no points to derive the grid from")
+	}
+	reg, err := grid.ParseRegistration(config.Registration)
+	if err != nil {
+		return grid.Spec{}, err
+	}
+	dz := 0.0
+	if points.Is3D {
+		dz = config.DZ
+	}
+	return grid.FromBounds(grid.BoundsOf(points).Pad(config.Padding), config.DX, config.DY, dz, reg)
+}
+
+// newSink creates the estimation sink for the configured output format. With
+// an empty path, the sink writes to stdout. ASC and Surfer output writes the
+// first of the values, GeoTIFF output one band and XYZ output one column per
+// value. NetCDF output always holds field and variance, as do VTK files.
+func newSink(config *Config, path string, spec grid.Spec, values ...func(types.Estimation) float64) (types.EstimationSink, error) {
+	switch config.OutputFormat {
+	case "tif", "nc", "grd", "grd6", "vtk", "vti", "gslib":
+		if path == "" {
+			return nil, fmt.Errorf("%s output needs an output path", config.OutputFormat)
+		}
+	}
+	w := os.Stdout
+	var f *os.File
+	if path != "" {
+		var err error
+		f, err = os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create file: %w", err)
+		}
+		w = f
+	}
+
+	var sink types.EstimationSink
+	var err error
+	switch config.OutputFormat {
+	case "asc":
+		if spec.Is3D() {
+			err = fmt.Errorf("3D grids are not supported by the asc format")
+		} else {
+			header := asc.HeaderFromSpec(spec)
+			header.NoData = config.NoData
+			header.Precision = config.Precision
+			sink, err = asc.NewKrigAscSink(w, header, values[0])
+		}
+	case "tif":
+		sink, err = geotiff.NewSink(w, spec, config.geotiff(), values...)
+	case "grd", "grd6", "grdtxt":
+		format, _ := surfer.ParseFormat(config.OutputFormat)
+		sink, err = surfer.NewSink(w, spec, format, values[0])
+	case "xyz":
+		sink, err = xyz.NewSink(w, spec, xyz.Options{NoData: config.NoData, Precision: config.Precision}, values...)
+	case "geojson":
+		sink = geojson.NewKrigGeoJSONSink(w, spec.Is3D())
+	case "gslib":
+		sink, err = gslib.NewKrigGSLIBSink(w, spec)
+	case "nc":
+		sink, err = netcdf.NewSink(w, spec, config.netcdf())
+	case "vtk", "vti":
+		format, _ := vtk.ParseFormat(config.OutputFormat)
+		sink, err = vtk.NewSink(w, spec, format)
+	case "json":
+		sink = json.NewKrigJSONSink(w, spec.Is3D())
+	case "csv":
+		cs := csv.NewKrigCSVSink(w, spec.Is3D())
+		cs.SetDiagnostics(config.Diagnostics)
+		sink = cs
+	default:
+		err = fmt.Errorf("unsupported grid output format: %s", config.OutputFormat)
+	}
+	if err != nil {
+		if f != nil {
+			f.Close()
+		}
+		return nil, err
+	}
+	if f != nil {
+		return fileSink{EstimationSink: sink, f: f}, nil
+	}
+	return sink, nil
+}
+
+// krigPaths returns the files kriging results are written to, which is
+// none for stdout. ASC and Surfer files hold a single band, so field and
+// variance are written to separate files. GeoTIFF files hold them as two
+// bands, NetCDF files as two variables.
+func krigPaths(config *Config, prefix string) []string {
+	if prefix == "" {
+		return nil
+	}
+	ext := config.extension()
+	if !config.singleBand() {
+		return []string{prefix + "_krig." + ext}
+	}
+	return []string{prefix + "_krig_field." + ext, prefix + "_krig_variance." + ext}
+}
+
+// krigSink creates the sink for kriging results, see krigPaths.
+func krigSink(config *Config, prefix string, spec grid.Spec) (types.EstimationSink, error) {
+	paths := krigPaths(config, prefix)
+	if len(paths) < 2 {
+		path := ""
+		if len(paths) == 1 {
+			path = paths[0]
+		}
+		return newSink(config, path, spec, asc.Field, asc.Variance)
+	}
+
+	field, err := newSink(config, paths[0], spec, asc.Field)
+	if err != nil {
+		return nil, err
+	}
+	variance, err := newSink(config, paths[1], spec, asc.Variance)
+	if err != nil {
+		discard(field)
+		return nil, err
+	}
+	return types.MultiSink{field, variance}, nil
+}
+
+// checkAlignment verifies that the raster files at paths have exactly the
+// cells of the template grid of d. Without a template, there is nothing to
+// check.
+func checkAlignment(config *Config, d domain, paths []string) error {
+	if d.template == nil || (!config.singleBand() && config.OutputFormat != "tif") {
+		return nil
+	}
+	for _, path := range paths {
+		read := asc.ReadAscSpec
+		switch config.OutputFormat {
+		case "tif":
+			read = geotiff.ReadSpec
+		case "grd", "grd6", "grdtxt":
+			read = func(path string) (grid.Spec, error) {
+				spec, _, err := surfer.ReadFile(path)
+				return spec, err
+			}
+		}
+		spec, err := read(path)
+		if err != nil {
+			return err
+		}
+		if err := d.template.Aligned(spec); err != nil {
+			return fmt.Errorf("%s does not align with the template: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// simSink returns a factory for the sinks of the individual SGS realizations,
+// and a function closing the output once all realizations are written.
+// NetCDF, GeoTIFF, VTK and GSLIB output stack all realizations in a single
+// file, the other formats write one file per realization.
+func simSink(config *Config, prefix string, spec grid.Spec) (func(sim int) (types.EstimationSink, error), func() error, error) {
+	switch config.OutputFormat {
+	case "nc", "tif", "vtk", "vti", "gslib":
+		if prefix == "" {
+			return nil, nil, fmt.Errorf("%s output needs an output path", config.OutputFormat)
+		}
+		f, err := os.Create(prefix + "_sgs." + config.OutputFormat)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create file: %w", err)
+		}
+		if config.OutputFormat == "nc" {
+			ens, err := netcdf.NewEnsemble(f, spec, config.SGSSimCount, config.netcdf())
+			if err != nil {
+				f.Close()
+				return nil, nil, err
+			}
+			return ens.Realization, f.Close, nil
+		}
+		if config.OutputFormat == "tif" {
+			ens, err := geotiff.NewEnsemble(f, spec, config.SGSSimCount, config.geotiff())
+			if err != nil {
+				f.Close()
+				return nil, nil, err
+			}
+			return ens.Realization, f.Close, nil
+		}
+		var ens interface {
+			Realization(sim int) (types.EstimationSink, error)
+			Close() error
+		}
+		if config.OutputFormat == "gslib" {
+			ens, err = gslib.NewEnsemble(f, spec, config.SGSSimCount)
+		} else {
+			format, _ := vtk.ParseFormat(config.OutputFormat)
+			ens, err = vtk.NewEnsemble(f, spec, config.SGSSimCount, format)
+		}
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		// VTK and GSLIB ensembles check that all realizations are complete
+		closeSims := func() error {
+			if err := ens.Close(); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		}
+		return ens.Realization, closeSims, nil
+	}
+
+	newSim := func(sim int) (types.EstimationSink, error) {
+		path := ""
+		if prefix != "" {
+			path = fmt.Sprintf("%s_sgs_sim_%d.%s", prefix, sim, config.extension())
+		} else {
+			separator("Simulation", sim)
+		}
+		return newSink(config, path, spec, asc.Field)
+	}
+	return newSim, func() error { return nil }, nil
+}
+
+// writeDataPoints writes the conditioning data as VTK point cloud next to
+// VTK grid output, so that both can be shown together.
+func writeDataPoints(config *Config, prefix string, points types.Points) error {
+	if prefix == "" || (config.OutputFormat != "vtk" && config.OutputFormat != "vti") {
+		return nil
+	}
+	f, err := os.Create(prefix + "_data.vtu")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if err := vtk.WritePoints(f, points); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"github.com/mmaelicke/go-geostat/io/plot"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	config := newDefaultConfig()
+
+	plotCmd := &cobra.Command{
+		Use:   "plot (vario | cloud | xval | raster FILE)",
+		Short: "Plot variograms, cross-validation results and rasters as SVG or PNG",
+		Long: `Plot the input data or a raster as SVG or PNG image.
+
+  vario   empirical variogram with pair counts and the fitted model
+  cloud   variogram cloud of all point pairs up to --maxlag
+  xval    cross-validation scatter of observed and predicted values
+  raster  colour-mapped ASC, GeoTIFF, Surfer or XYZ raster FILE
+
+The image format is taken from the extension of --output, or from --format
+(svg, png). Without --output an SVG image is written to stdout. With several
+value columns the variable name is appended to the output file name.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if !cmd.Flags().Changed("format") {
+				config.OutputFormat = "svg"
+			}
+			if err := runPlot(config, args); err != nil {
+				log.Fatalf("Error plotting: %v", err)
+			}
+		},
+	}
+
+	bindInputFlags(plotCmd, config)
+	bindVariogramFlags(plotCmd, config)
+	bindGridFlags(plotCmd, config)
+	plotCmd.Flags().IntVar(&config.Folds, "folds", 0, "Number of cross-validation folds, 0 for leave-one-out")
+	plotCmd.Flags().Int64Var(&config.Seed, "seed", 42, "Random seed for the fold assignment")
+
+	rootCmd.AddCommand(plotCmd)
+}
+
+func runPlot(config *Config, args []string) error {
+	format, err := plotFormat(config)
+	if err != nil {
+		return err
+	}
+
+	kind := args[0]
+	if kind == "raster" {
+		if len(args) != 2 {
+			return fmt.Errorf("plot raster needs a raster file")
+		}
+		spec, values, err := readRaster(args[1])
+		if err != nil {
+			return err
+		}
+		fig, err := plot.Raster(spec, values, filepath.Base(args[1]), "")
+		if err != nil {
+			return err
+		}
+		return writeFigure(fig, config.OutputPath, format)
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("plot %s takes no file, use --input", kind)
+	}
+	if kind != "vario" && kind != "cloud" && kind != "xval" {
+		return fmt.Errorf("unknown plot %q, use vario, cloud, xval or raster", kind)
+	}
+
+	data, err := readData(config)
+	if err != nil {
+		return err
+	}
+	if len(data.Variables) > 1 && config.OutputPath == "" {
+		return fmt.Errorf("plotting %d variables needs --output", len(data.Variables))
+	}
+	if config.MaxLag == 0 && kind != "cloud" {
+		config.MaxLag = 1e6
+	}
+
+	for _, name := range data.Variables {
+		path := config.OutputPath
+		if len(data.Variables) > 1 {
+			ext := filepath.Ext(path)
+			path = strings.TrimSuffix(path, ext) + "_" + name + ext
+		}
+		fig, err := plotVariable(config, kind, data.Variable(name))
+		if err != nil {
+			return fmt.Errorf("variable %s: %w", name, err)
+		}
+		if err := writeFigure(fig, path, format); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// plotVariable draws the plot kind of a single variable
+func plotVariable(config *Config, kind string, points types.Points) (*plot.Figure, error) {
+	switch kind {
+	case "cloud":
+		dist, err := config.distance()
+		if err != nil {
+			return nil, err
+		}
+		return plot.Cloud(points, dist, config.MaxLag), nil
+	case "xval":
+		_, _, results, summary, err := crossValidate(context.Background(), config, points)
+		if err != nil {
+			return nil, err
+		}
+		return plot.CrossValidation(results, summary), nil
+	default:
+		vg, model, err := fitVariogram(config, points)
+		if err != nil {
+			return nil, err
+		}
+		return plot.Variogram(vg, model), nil
+	}
+}
+
+// plotFormat returns the image format given by the output extension, or by
+// --format if the output has none
+func plotFormat(config *Config) (plot.Format, error) {
+	if ext := filepath.Ext(config.OutputPath); ext != "" {
+		return plot.ParseFormat(ext)
+	}
+	return plot.ParseFormat(config.OutputFormat)
+}
+
+// writeFigure writes fig to path, or to stdout if path is empty
+func writeFigure(fig *plot.Figure, path string, format plot.Format) error {
+	if path == "" {
+		return fig.Write(os.Stdout, format)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if err := fig.Write(file, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
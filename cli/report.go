@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"github.com/mmaelicke/go-geostat/io/report"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	config := newDefaultConfig()
+	title := ""
+
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Write an HTML report of a variogram, cross-validation and kriging analysis",
+		Long: `Analyse every value column and write a single, self-contained HTML file with
+summary statistics, histogram and location map of the data, the empirical and
+fitted variogram with fit statistics, the cross-validation results and the
+kriging estimate and variance maps. All figures are embedded as SVG.
+
+Kriging maps are drawn for 2D data on the grid given by --dx, --dy or
+--template. Without --output the report is written to stdout.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runReport(config, title); err != nil {
+				log.Fatalf("Error writing report: %v", err)
+			}
+		},
+	}
+
+	bindInputFlags(reportCmd, config)
+	bindVariogramFlags(reportCmd, config)
+	bindGridFlags(reportCmd, config)
+	reportCmd.Flags().IntVar(&config.Folds, "folds", 0, "Number of cross-validation folds, 0 for leave-one-out")
+	reportCmd.Flags().Int64Var(&config.Seed, "seed", 42, "Random seed for the fold assignment")
+	reportCmd.Flags().StringVar(&title, "title", "Geostatistical analysis", "Title of the report")
+
+	rootCmd.AddCommand(reportCmd)
+}
+
+func runReport(config *Config, title string) error {
+	data, err := readData(config)
+	if err != nil {
+		return err
+	}
+	if config.MaxLag == 0 {
+		config.MaxLag = 1e6
+	}
+	domain, err := readDomain(config)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+
+	r := report.Report{
+		Title:    title,
+		Created:  time.Now(),
+		Settings: reportSettings(config),
+	}
+	if config.InputPath != "" {
+		r.Source = filepath.Base(config.InputPath)
+	} else if config.CSVPath != "" {
+		r.Source = filepath.Base(config.CSVPath)
+	}
+
+	for _, name := range data.Variables {
+		v, err := analyseVariable(ctx, config, data.Variable(name), domain)
+		if err != nil {
+			return fmt.Errorf("variable %s: %w", name, err)
+		}
+		v.Name = name
+		r.Variables = append(r.Variables, v)
+	}
+
+	if config.OutputPath == "" {
+		return report.Write(os.Stdout, r)
+	}
+	return report.WriteFile(config.OutputPath, r)
+}
+
+// analyseVariable fits the variogram of a single variable, cross-validates
+// kriging with it and, for 2D data, kriges the target grid
+func analyseVariable(ctx context.Context, config *Config, points types.Points, domain domain) (report.Variable, error) {
+	vg, model, results, summary, err := crossValidate(ctx, config, points)
+	if interrupted(err) {
+		// the points not validated before the interruption count as failed
+		fmt.Fprintf(os.Stderr, "Warning: cross-validation stopped after %d of %d points: %v\n", summary.N, len(results), err)
+	} else if err != nil {
+		return report.Variable{}, err
+	}
+	v := report.Variable{
+		Points:    points,
+		Variogram: vg,
+		Model:     model,
+		Results:   results,
+		Summary:   summary,
+	}
+	if points.Is3D {
+		fmt.Fprintln(os.Stderr, "Warning: kriging maps are only drawn for 2D data")
+		return v, nil
+	}
+
+	spec, err := targetGrid(points, config, domain)
+	if err != nil {
+		return v, fmt.Errorf("error creating grid: %w", err)
+	}
+	dist, err := config.distance()
+	if err != nil {
+		return v, err
+	}
+	kr, err := config.kriging(model, dist)
+	if err != nil {
+		return v, err
+	}
+	kr.SetMask(domain.mask)
+	kr.Fit(points)
+
+	values := &gridValues{}
+	err = kr.InterpolateTo(ctx, domain.locations(spec), values)
+	var failures *types.EstimationErrors
+	switch {
+	case err == nil:
+	case errors.As(err, &failures):
+		reportFailures(err)
+	case interrupted(err):
+		// the maps show the nodes kriged until the interruption
+		fmt.Fprintf(os.Stderr, "Warning: kriging stopped after %d of %d nodes: %v\n", len(values.field), spec.Len(), err)
+		values.pad(spec.Len())
+	default:
+		return v, fmt.Errorf("error kriging: %w", err)
+	}
+	v.Grid, v.Field, v.Variance = spec, values.field, values.variance
+	return v, nil
+}
+
+// interrupted reports whether err stems from a cancelled or timed out context
+func interrupted(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// gridValues collects field and variance of the estimations in grid order
+type gridValues struct {
+	field, variance []float64
+}
+
+func (g *gridValues) Write(p types.Point, e types.Estimation) error {
+	g.field = append(g.field, e.Field)
+	g.variance = append(g.variance, e.Variance)
+	return nil
+}
+
+func (g *gridValues) Flush() error {
+	return nil
+}
+
+// pad fills the values up to n nodes with NaN
+func (g *gridValues) pad(n int) {
+	for len(g.field) < n {
+		g.field = append(g.field, math.NaN())
+		g.variance = append(g.variance, math.NaN())
+	}
+}
+
+// reportSettings lists the analysis parameters shown in the report
+func reportSettings(config *Config) []report.Setting {
+	xval := "leave-one-out"
+	if config.Folds > 0 {
+		xval = fmt.Sprintf("%d-fold, seed %d", config.Folds, config.Seed)
+	}
+	settings := []report.Setting{
+		{Name: "Model", Value: config.ModelName},
+		{Name: "Estimator", Value: config.EstimatorName},
+		{Name: "Distance", Value: config.DistType},
+		{Name: "Lags", Value: strconv.Itoa(config.NLags)},
+		{Name: "Maximum lag", Value: strconv.FormatFloat(config.MaxLag, 'g', -1, 64)},
+		{Name: "Neighbours", Value: fmt.Sprintf("%d to %d", config.MinPoints, config.MaxPoints)},
+		{Name: "Cross-validation", Value: xval},
+	}
+	if config.AnisoRatio != 1 {
+		settings = append(settings, report.Setting{
+			Name:  "Anisotropy",
+			Value: fmt.Sprintf("azimuth %g°, ratio %g", config.Azimuth, config.AnisoRatio),
+		})
+	}
+	if config.Radius > 0 {
+		settings = append(settings, report.Setting{Name: "Search radius", Value: strconv.FormatFloat(config.Radius, 'g', -1, 64)})
+	}
+	return settings
+}
@@ -1,79 +1,22 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"strconv"
-	"strings"
+	"os/signal"
 
-	"github.com/mmaelicke/go-geostat/internal/distance"
 	"github.com/mmaelicke/go-geostat/internal/empirical"
-	"github.com/mmaelicke/go-geostat/internal/estimator"
 	"github.com/mmaelicke/go-geostat/internal/kriging"
 	"github.com/mmaelicke/go-geostat/internal/sgs"
 	"github.com/mmaelicke/go-geostat/internal/types"
-	"github.com/mmaelicke/go-geostat/io/asc"
 	"github.com/mmaelicke/go-geostat/io/csv"
+	"github.com/mmaelicke/go-geostat/io/gslib"
 	"github.com/spf13/cobra"
 )
 
-// Config holds all configuration options for the variogram command
-type Config struct {
-	// Input/Output options
-	CSVPath      string
-	OutputPath   string
-	OutputFormat string
-
-	// Column specifications
-	XCol     string
-	YCol     string
-	ZCol     string
-	TCol     string
-	ValueCol string
-
-	// Variogram parameters
-	NLags  int
-	MaxLag float64
-
-	// Model parameters
-	ModelName     string
-	DistType      string
-	EstimatorName string
-	TimeFormat    string
-
-	// Processing options
-	MaxPoints int
-	DX        float64
-	DY        float64
-	DZ        float64
-
-	// Flags
-	Performance bool
-	Fit         bool
-	UseKriging  bool
-	KrigingOnly bool
-	UseSGS      bool
-	SGSOnly     bool
-	SGSSimCount int
-}
-
-// newDefaultConfig returns a Config with default values
-func newDefaultConfig() *Config {
-	return &Config{
-		NLags:         10,
-		MaxLag:        0,
-		MaxPoints:     100,
-		DX:            1.0,
-		DY:            1.0,
-		DZ:            1.0,
-		SGSSimCount:   1,
-		ModelName:     "spherical",
-		DistType:      "euclidean",
-		EstimatorName: "matheron",
-	}
-}
-
 func init() {
 	config := newDefaultConfig()
 
@@ -87,36 +30,11 @@ func init() {
 		},
 	}
 
-	// Input/Output flags
-	varioCmd.Flags().StringVar(&config.CSVPath, "csv", "", "Path to input CSV file")
-	varioCmd.Flags().StringVar(&config.OutputPath, "output", "", "Path to output file")
-	varioCmd.Flags().StringVar(&config.OutputFormat, "format", "json", "Output format (json, csv)")
-
-	// Column specification flags
-	varioCmd.Flags().StringVar(&config.XCol, "x", "x", "X coordinate column name")
-	varioCmd.Flags().StringVar(&config.YCol, "y", "y", "Y coordinate column name")
-	varioCmd.Flags().StringVar(&config.ZCol, "z", "", "Z coordinate column name")
-	varioCmd.Flags().StringVar(&config.TCol, "t", "", "Time column name")
-	varioCmd.Flags().StringVar(&config.ValueCol, "value", "value", "Value column name")
-
-	// Variogram parameter flags
-	varioCmd.Flags().IntVar(&config.NLags, "nlags", 10, "Number of lags")
-	varioCmd.Flags().Float64Var(&config.MaxLag, "maxlag", 0, "Maximum lag distance")
-
-	// Model parameter flags
-	varioCmd.Flags().StringVar(&config.ModelName, "model", "spherical", "Variogram model type")
-	varioCmd.Flags().StringVar(&config.DistType, "dist", "euclidean", "Distance metric")
-	varioCmd.Flags().StringVar(&config.EstimatorName, "estimator", "matheron", "Variogram estimator")
-	varioCmd.Flags().StringVar(&config.TimeFormat, "timeformat", "", "Time format string")
-
-	// Processing option flags
-	varioCmd.Flags().IntVar(&config.MaxPoints, "maxpoints", 100, "Maximum number of points to use")
-	varioCmd.Flags().Float64Var(&config.DX, "dx", 1.0, "X grid spacing")
-	varioCmd.Flags().Float64Var(&config.DY, "dy", 1.0, "Y grid spacing")
-	varioCmd.Flags().Float64Var(&config.DZ, "dz", 1.0, "Z grid spacing")
+	bindInputFlags(varioCmd, config)
+	bindVariogramFlags(varioCmd, config)
+	bindGridFlags(varioCmd, config)
 
 	// Feature flags
-	varioCmd.Flags().BoolVar(&config.Performance, "perf", false, "Enable performance profiling")
 	varioCmd.Flags().BoolVar(&config.Fit, "fit", false, "Fit variogram model")
 	varioCmd.Flags().BoolVar(&config.UseKriging, "krig", false, "Perform kriging")
 	varioCmd.Flags().BoolVar(&config.KrigingOnly, "krigonly", false, "Only perform kriging")
@@ -128,50 +46,64 @@ func init() {
 }
 
 func runVariogram(config *Config) error {
-	var data csv.PointData
-	var err error
-
 	if config.UseKriging && config.UseSGS {
 		return fmt.Errorf("kriging and SGS cannot be performed at the same time")
 	}
-
-	if config.CSVPath != "" {
-		data, err = csv.ReadCSV(config.CSVPath, config.XCol, config.YCol, config.ZCol,
-			config.TCol, config.ValueCol, config.TimeFormat, false)
-	} else {
-		data, err = csv.ReadCSVFromReader(os.Stdin, config.XCol, config.YCol, config.ZCol,
-			config.TCol, config.ValueCol, config.TimeFormat, false)
+	if config.UseSGS && len(config.Drift) > 0 {
+		return fmt.Errorf("a drift is only supported for kriging")
 	}
+
+	data, err := readData(config)
 	if err != nil {
-		return fmt.Errorf("error reading CSV: %v", err)
+		return err
 	}
 
-	points := data.Read()
 	if config.MaxLag == 0 {
 		config.MaxLag = 1e6
 	}
 
-	var dist types.Distance
-	var est types.Estimator
+	domain, err := readDomain(config)
+	if err != nil {
+		return err
+	}
+
+	// interpolation can be interrupted by the user or time out
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
 
-	switch strings.ToLower(config.DistType) {
-	case "chebyshev":
-		dist = &distance.ChebyshevDistance{}
-	case "manhattan":
-		dist = &distance.ManhattanDistance{}
-	case "euclidean":
-		dist = &distance.EuclideanDistance{}
-	default:
-		log.Fatalf("Unsupported distance type: %s", config.DistType)
+	// every variable is processed on its own, sharing the parsed input
+	for _, name := range data.Variables {
+		prefix := config.OutputPath
+		if len(data.Variables) > 1 {
+			if prefix != "" {
+				prefix += "_" + name
+			} else {
+				separator("Variable", name)
+			}
+		}
+		if err := runVariable(ctx, config, data.Variable(name), domain, prefix); err != nil {
+			return fmt.Errorf("variable %s: %w", name, err)
+		}
 	}
+	return nil
+}
 
-	switch strings.ToLower(config.EstimatorName) {
-	case "matheron":
-		est = &estimator.Matheron{}
-	case "cressie":
-		est = &estimator.Cressie{}
-	default:
-		log.Fatalf("Unsupported estimator: %s", config.EstimatorName)
+// runVariable processes a single variable on the grid and inside the mask
+// of domain. All output files are prefixed with prefix, or written to
+// stdout if prefix is empty.
+func runVariable(ctx context.Context, config *Config, points types.Points, domain domain, prefix string) error {
+	dist, err := config.distance()
+	if err != nil {
+		log.Fatal(err)
+	}
+	est, err := config.estimator()
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	vg := empirical.NewEmpiricalVariogram(points, config.NLags, config.MaxLag, dist, est)
@@ -181,7 +113,7 @@ func runVariogram(config *Config) error {
 	}
 
 	var model types.SpatialFunction
-	if config.Fit || config.UseKriging {
+	if config.Fit || config.UseKriging || config.UseSGS {
 		model, err = vg.Fit(config.ModelName)
 		if err != nil {
 			log.Fatalf("Error fitting model: %v", err)
@@ -204,19 +136,67 @@ func runVariogram(config *Config) error {
 		fmt.Printf("# Total time:         %v\n", profile.TotalTime)
 	}
 
-	var estimation []types.Estimation
-	var grid types.Points
+	if !config.KrigingOnly && !config.SGSOnly {
+		if config.OutputFormat == "gslib" {
+			if prefix != "" {
+				err = gslib.WriteVarioGSLIB(prefix+"_variogram.gslib", vg, model)
+			} else {
+				err = gslib.WriteVarioGSLIBToWriter(os.Stdout, vg, model)
+			}
+			if err != nil {
+				log.Fatalf("Error writing output: %v", err)
+			}
+		} else if prefix != "" {
+			err = csv.WriteVarioCSV(prefix+"_variogram.csv", vg, model)
+			if err != nil {
+				log.Fatalf("Error writing output: %v", err)
+			}
+		} else {
+			csv.WriteVarioCSVToWriter(os.Stdout, vg, model)
+		}
+	}
+
+	if config.UseKriging || config.UseSGS {
+		if err := writeDataPoints(config, prefix, points); err != nil {
+			log.Fatalf("Error writing data points: %v", err)
+		}
+	}
+
 	if config.UseKriging {
-		grid, err = kriging.DenseGrid(points, config.DX, config.DY, config.DZ)
+		spec, err := targetGrid(points, config, domain)
 		if err != nil {
-			log.Fatalf("Error creating dense grid: %v", err)
+			log.Fatalf("Error creating grid: %v", err)
 		}
 
-		kr := kriging.New(model, config.MaxPoints, dist, false)
+		kr, err := config.kriging(model, dist)
+		if err != nil {
+			log.Fatal(err)
+		}
+		kr.SetMask(domain.mask)
 		kr.Fit(points)
-		estimation, err = kr.Interpolate(grid)
+		if dups := kr.Duplicates(); len(dups) > 0 {
+			if policy, _ := kriging.ParseDuplicatePolicy(config.Duplicates); policy == kriging.DuplicatesError {
+				log.Fatalf("Error fitting kriging: %d duplicate locations, e.g. points %v, see --duplicates", len(dups), dups[0])
+			}
+			fmt.Fprintf(os.Stderr, "Warning: %d duplicate locations, handled by policy %q\n", len(dups), config.Duplicates)
+		}
+
+		// estimations are written while they are produced
+		sink, err := krigSink(config, prefix, spec)
 		if err != nil {
-			log.Fatalf("Error interpolating: %v", err)
+			log.Fatalf("Error creating output: %v", err)
+		}
+		err = kr.InterpolateTo(ctx, domain.locations(spec), sink)
+		if err != nil {
+			// an interrupted grid is incomplete and not flushed
+			var failures *types.EstimationErrors
+			if !errors.As(err, &failures) {
+				discard(sink)
+			}
+			reportFailures(err)
+		}
+		if err := checkAlignment(config, domain, krigPaths(config, prefix)); err != nil {
+			log.Fatalf("Error checking output: %v", err)
 		}
 
 		if config.Performance {
@@ -230,80 +210,41 @@ func runVariogram(config *Config) error {
 		}
 	}
 
-	var sims [][]types.Estimation
 	if config.UseSGS {
-		grid, err = kriging.DenseGrid(points, config.DX, config.DY, config.DZ)
+		spec, err := targetGrid(points, config, domain)
 		if err != nil {
-			log.Fatalf("Error creating dense grid: %v", err)
+			log.Fatalf("Error creating grid: %v", err)
 		}
-
 		s := sgs.New(model, config.MaxPoints, dist, true)
+		s.SetWorkers(config.Workers)
+		s.SetMask(domain.mask)
 		s.Fit(points)
 
-		sims, err = s.Simulate(grid, config.SGSSimCount)
+		// every realization is written as soon as it is complete
+		newSim, closeSims, err := simSink(config, prefix, spec)
+		if err != nil {
+			log.Fatalf("Error creating output: %v", err)
+		}
+		err = s.SimulateTo(spec, config.SGSSimCount, newSim)
 		if err != nil {
 			log.Fatalf("Error simulating: %v", err)
 		}
-	}
-
-	if !config.KrigingOnly && !config.SGSOnly {
-		if config.OutputPath != "" {
-			err = csv.WriteVarioCSV(config.OutputPath+"_variogram.csv", vg, model)
-			if err != nil {
-				log.Fatalf("Error writing output: %v", err)
-			}
-		} else {
-			csv.WriteVarioCSVToWriter(os.Stdout, vg, model)
+		if err := closeSims(); err != nil {
+			log.Fatalf("Error writing output: %v", err)
 		}
 	}
-	if config.UseKriging {
-		field := make([]float64, len(estimation))
-		variance := make([]float64, len(estimation))
-		for i, e := range estimation {
-			field[i] = e.Field
-			variance[i] = e.Variance
-		}
+	return nil
+}
 
-		if config.OutputPath != "" {
-			if config.OutputFormat == "asc" {
-				asc.WriteKrigAsc(config.OutputPath+"_krig_field.asc", grid, field)
-				asc.WriteKrigAsc(config.OutputPath+"_krig_variance.asc", grid, variance)
-			}
-			if config.OutputFormat == "csv" {
-				csv.WriteKrigCSV(config.OutputPath+"_krig.csv", grid, estimation)
-			}
-		} else {
-			if config.OutputFormat == "asc" {
-				asc.WriteKrigAscToWriter(os.Stdout, grid, field)
-			} else if config.OutputFormat == "csv" {
-				csv.WriteKrigCSVToWriter(os.Stdout, grid, estimation)
-			}
-		}
+// reportFailures prints a summary of the grid nodes that could not be
+// estimated to stderr. Any other error is fatal.
+func reportFailures(err error) {
+	var failures *types.EstimationErrors
+	if !errors.As(err, &failures) {
+		log.Fatalf("Error interpolating: %v", err)
 	}
-	if config.UseSGS {
-		for sim_idx, sim := range sims {
-			field := make([]float64, len(sim))
-			for i, e := range sim {
-				field[i] = e.Field
-			}
-
-			if config.OutputPath != "" {
-				if config.OutputFormat == "asc" {
-					asc.WriteKrigAsc(config.OutputPath+"_sgs_sim_"+strconv.Itoa(sim_idx)+".asc", grid, field)
-				}
-				if config.OutputFormat == "csv" {
-					csv.WriteKrigCSV(config.OutputPath+"_sgs_sim_"+strconv.Itoa(sim_idx)+".csv", grid, sim)
-				}
-			} else {
-				if config.OutputFormat == "asc" {
-					fmt.Printf("--- Simulation %d ---\n", sim_idx)
-					asc.WriteKrigAscToWriter(os.Stdout, grid, field)
-				} else if config.OutputFormat == "csv" {
-					fmt.Printf("--- Simulation %d ---\n", sim_idx)
-					csv.WriteKrigCSVToWriter(os.Stdout, grid, sim)
-				}
-			}
-		}
+	fmt.Fprintf(os.Stderr, "Warning: %v\n", failures)
+	for _, e := range failures.Errors {
+		fmt.Fprintf(os.Stderr, "  %v\n", e)
 	}
-	return nil
 }
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/mmaelicke/go-geostat/internal/crossval"
+	"github.com/mmaelicke/go-geostat/internal/empirical"
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"github.com/mmaelicke/go-geostat/io/csv"
+	"github.com/mmaelicke/go-geostat/io/geojson"
+	"github.com/mmaelicke/go-geostat/io/json"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	config := newDefaultConfig()
+
+	xvalCmd := &cobra.Command{
+		Use:   "xval",
+		Short: "Cross-validate variogram model and kriging",
+		Long: `Fit a variogram model and cross-validate ordinary kriging with it.
+
+By default every sample is predicted from all others (leave-one-out).
+Use --folds for k-fold cross-validation.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runXval(config); err != nil {
+				log.Fatalf("Error running cross-validation: %v", err)
+			}
+		},
+	}
+
+	bindInputFlags(xvalCmd, config)
+	bindVariogramFlags(xvalCmd, config)
+	bindGridFlags(xvalCmd, config)
+	xvalCmd.Flags().IntVar(&config.Folds, "folds", 0, "Number of folds, 0 for leave-one-out")
+	xvalCmd.Flags().Int64Var(&config.Seed, "seed", 42, "Random seed for the fold assignment")
+
+	rootCmd.AddCommand(xvalCmd)
+}
+
+func runXval(config *Config) error {
+	data, err := readData(config)
+	if err != nil {
+		return err
+	}
+	if config.MaxLag == 0 {
+		config.MaxLag = 1e6
+	}
+
+	for _, name := range data.Variables {
+		prefix := config.OutputPath
+		if len(data.Variables) > 1 {
+			if prefix != "" {
+				prefix += "_" + name
+			} else {
+				separator("Variable", name)
+			}
+		}
+		if err := xvalVariable(config, data.Variable(name), prefix); err != nil {
+			return fmt.Errorf("variable %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// xvalVariable cross-validates a single variable and writes the results
+func xvalVariable(config *Config, points types.Points, prefix string) error {
+	_, _, results, summary, err := crossValidate(context.Background(), config, points)
+	if err != nil {
+		return err
+	}
+
+	switch config.OutputFormat {
+	case "csv":
+		if prefix != "" {
+			return csv.WriteXvalCSV(prefix+"_xval.csv", results, summary, points.Is3D)
+		}
+		return csv.WriteXvalCSVToWriter(os.Stdout, results, summary, points.Is3D)
+	case "json":
+		if prefix != "" {
+			return json.WriteXvalJson(prefix+"_xval.json", results, summary, points.Is3D)
+		}
+		return json.WriteXvalJsonToWriter(os.Stdout, results, summary, points.Is3D)
+	case "geojson":
+		if prefix != "" {
+			return geojson.WriteXvalGeoJSON(prefix+"_xval.geojson", results, summary, points.Is3D)
+		}
+		return geojson.WriteXvalGeoJSONToWriter(os.Stdout, results, summary, points.Is3D)
+	default:
+		return fmt.Errorf("unsupported output format: %s", config.OutputFormat)
+	}
+}
+
+// fitVariogram computes the empirical variogram of points and fits the
+// configured model to it
+func fitVariogram(config *Config, points types.Points) (*empirical.EmpiricalVariogram, types.SpatialFunction, error) {
+	dist, err := config.distance()
+	if err != nil {
+		return nil, nil, err
+	}
+	est, err := config.estimator()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vg := empirical.NewEmpiricalVariogram(points, config.NLags, config.MaxLag, dist, est)
+	if err := vg.Compute(); err != nil {
+		return nil, nil, fmt.Errorf("error computing empirical variogram: %w", err)
+	}
+	model, err := vg.Fit(config.ModelName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fitting model: %w", err)
+	}
+	return vg, model, nil
+}
+
+// crossValidate fits the variogram of points and cross-validates kriging
+// with the fitted model, by leave-one-out or k-fold as configured. If ctx is
+// done first, the results so far are returned with the context error.
+func crossValidate(ctx context.Context, config *Config, points types.Points) (*empirical.EmpiricalVariogram, types.SpatialFunction, []crossval.Result, crossval.Summary, error) {
+	vg, model, err := fitVariogram(config, points)
+	if err != nil {
+		return nil, nil, nil, crossval.Summary{}, err
+	}
+	dist, err := config.distance()
+	if err != nil {
+		return nil, nil, nil, crossval.Summary{}, err
+	}
+	kr, err := config.kriging(model, dist)
+	if err != nil {
+		return nil, nil, nil, crossval.Summary{}, err
+	}
+
+	var results []crossval.Result
+	if config.Folds > 0 {
+		results, err = crossval.KFold(ctx, kr, points, config.Folds, config.Seed)
+	} else {
+		results, err = crossval.LeaveOneOut(ctx, kr, points)
+	}
+	if interrupted(err) {
+		return vg, model, results, crossval.Summarize(results), err
+	}
+	if err != nil {
+		return nil, nil, nil, crossval.Summary{}, fmt.Errorf("error cross-validating: %w", err)
+	}
+	return vg, model, results, crossval.Summarize(results), nil
+}
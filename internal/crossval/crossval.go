@@ -0,0 +1,173 @@
+// Package crossval validates spatial interpolators by leave-one-out and
+// k-fold cross-validation over the conditioning points.
+package crossval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"gonum.org/v1/gonum/stat"
+)
+
+// Result holds the cross-validation outcome at a single conditioning point.
+type Result struct {
+	// Index of the point in the validated points
+	Index int
+	// Fold the point was predicted in
+	Fold      int
+	Point     types.Point
+	Observed  float64
+	Predicted float64
+	Variance  float64
+	// Error is Predicted - Observed
+	Error float64
+	// StdError is the error standardized by the kriging standard deviation
+	StdError float64
+}
+
+// Summary holds the cross-validation statistics over all successfully
+// predicted points.
+type Summary struct {
+	N      int
+	Failed int
+	// ME is the mean error, which should be close to 0
+	ME float64
+	// MAE is the mean absolute error
+	MAE float64
+	// RMSE is the root mean squared error
+	RMSE float64
+	// MSDR is the mean squared deviation ratio, which should be close to 1
+	MSDR float64
+	// Correlation of observed and predicted values
+	Correlation float64
+}
+
+// LeaveOneOut predicts every point from all other points. If ctx is done
+// before all points are predicted, the results are returned together with
+// ctx.Err(), and the remaining points are predicted as NaN.
+func LeaveOneOut(ctx context.Context, interp types.SpatialInterpolator, points types.Points) ([]Result, error) {
+	folds := make([]int, len(points.Points))
+	for i := range folds {
+		folds[i] = i
+	}
+	return run(ctx, interp, points, folds, len(folds))
+}
+
+// KFold splits the points randomly into k folds of similar size and predicts
+// every fold from the remaining ones. The split is reproducible for a seed.
+// ctx is checked before each fold, as in LeaveOneOut.
+func KFold(ctx context.Context, interp types.SpatialInterpolator, points types.Points, k int, seed int64) ([]Result, error) {
+	n := len(points.Points)
+	if k < 2 || k > n {
+		return nil, fmt.Errorf("number of folds must be between 2 and %d, got %d", n, k)
+	}
+	rng := rand.New(rand.NewSource(seed))
+	folds := make([]int, n)
+	for i, idx := range rng.Perm(n) {
+		folds[idx] = i % k
+	}
+	return run(ctx, interp, points, folds, k)
+}
+
+// run fits interp without, and predicts, each fold in turn
+func run(ctx context.Context, interp types.SpatialInterpolator, points types.Points, folds []int, k int) ([]Result, error) {
+	if len(points.Points) < 2 {
+		return nil, fmt.Errorf("cross-validation needs at least 2 points")
+	}
+
+	// points stay unpredicted until their fold is done
+	results := make([]Result, len(points.Points))
+	for i, p := range points.Points {
+		nan := math.NaN()
+		results[i] = Result{Index: i, Fold: folds[i], Point: p, Observed: p.Value,
+			Predicted: nan, Variance: nan, Error: nan, StdError: nan}
+	}
+	for f := 0; f < k; f++ {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		condition := types.Points{Is3D: points.Is3D}
+		target := types.Points{Is3D: points.Is3D}
+		indices := make([]int, 0)
+		for i, p := range points.Points {
+			if folds[i] == f {
+				target.Points = append(target.Points, p)
+				indices = append(indices, i)
+			} else {
+				condition.Points = append(condition.Points, p)
+			}
+		}
+		if len(target.Points) == 0 {
+			continue
+		}
+
+		interp.Fit(condition)
+		// points that could not be predicted are kept as NaN results
+		estimations, err := interp.Interpolate(target)
+		var failures *types.EstimationErrors
+		if err != nil && !errors.As(err, &failures) {
+			return nil, fmt.Errorf("fold %d: %w", f, err)
+		}
+
+		for j, idx := range indices {
+			e := estimations[j]
+			p := points.Points[idx]
+			r := Result{
+				Index:     idx,
+				Fold:      f,
+				Point:     p,
+				Observed:  p.Value,
+				Predicted: e.Field,
+				Variance:  e.Variance,
+				Error:     e.Field - p.Value,
+			}
+			r.StdError = r.Error / math.Sqrt(e.Variance)
+			results[idx] = r
+		}
+	}
+	return results, nil
+}
+
+// Summarize computes the cross-validation statistics. Points that could not
+// be predicted are counted as failed and excluded.
+func Summarize(results []Result) Summary {
+	s := Summary{}
+	observed := make([]float64, 0, len(results))
+	predicted := make([]float64, 0, len(results))
+	nStd := 0
+
+	for _, r := range results {
+		if math.IsNaN(r.Predicted) {
+			s.Failed++
+			continue
+		}
+		s.N++
+		s.ME += r.Error
+		s.MAE += math.Abs(r.Error)
+		s.RMSE += r.Error * r.Error
+		if !math.IsNaN(r.StdError) && !math.IsInf(r.StdError, 0) {
+			s.MSDR += r.StdError * r.StdError
+			nStd++
+		}
+		observed = append(observed, r.Observed)
+		predicted = append(predicted, r.Predicted)
+	}
+
+	if s.N == 0 {
+		return Summary{Failed: s.Failed, ME: math.NaN(), MAE: math.NaN(), RMSE: math.NaN(), MSDR: math.NaN(), Correlation: math.NaN()}
+	}
+	s.ME /= float64(s.N)
+	s.MAE /= float64(s.N)
+	s.RMSE = math.Sqrt(s.RMSE / float64(s.N))
+	if nStd > 0 {
+		s.MSDR /= float64(nStd)
+	} else {
+		s.MSDR = math.NaN()
+	}
+	s.Correlation = stat.Correlation(observed, predicted, nil)
+	return s
+}
@@ -0,0 +1,135 @@
+package crossval
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// meanInterpolator predicts the mean of the condition points everywhere
+type meanInterpolator struct {
+	mean float64
+}
+
+func (m *meanInterpolator) Fit(condition types.Points) {
+	m.mean = 0
+	for _, p := range condition.Points {
+		m.mean += p.Value
+	}
+	m.mean /= float64(len(condition.Points))
+}
+
+func (m *meanInterpolator) Interpolate(p types.Points) ([]types.Estimation, error) {
+	estimations := make([]types.Estimation, len(p.Points))
+	for i := range estimations {
+		estimations[i] = types.Estimation{Field: m.mean, Variance: 1}
+	}
+	return estimations, nil
+}
+
+func (m *meanInterpolator) Profile() types.Profile {
+	return types.Profile{}
+}
+
+func TestLeaveOneOut(t *testing.T) {
+	points := types.Points{Points: []types.Point{
+		{X: 0, Y: 0, Value: 1},
+		{X: 1, Y: 0, Value: 2},
+		{X: 2, Y: 0, Value: 3},
+	}}
+	results, err := LeaveOneOut(context.Background(), &meanInterpolator{}, points)
+	if err != nil {
+		t.Fatalf("LeaveOneOut() error = %v", err)
+	}
+
+	// predictions are 2.5, 2 and 1.5
+	expected := []float64{2.5, 2, 1.5}
+	for i, want := range expected {
+		if math.Abs(results[i].Predicted-want) > 1e-10 {
+			t.Errorf("prediction %d: got %v, want %v", i, results[i].Predicted, want)
+		}
+	}
+
+	s := Summarize(results)
+	if s.N != 3 || s.Failed != 0 {
+		t.Errorf("got N=%d, Failed=%d, want 3 and 0", s.N, s.Failed)
+	}
+	if math.Abs(s.ME) > 1e-10 {
+		t.Errorf("ME = %v, want 0", s.ME)
+	}
+	// errors are 1.5, 0, -1.5
+	if math.Abs(s.RMSE-math.Sqrt(1.5)) > 1e-10 {
+		t.Errorf("RMSE = %v, want %v", s.RMSE, math.Sqrt(1.5))
+	}
+	if math.Abs(s.MSDR-1.5) > 1e-10 {
+		t.Errorf("MSDR = %v, want 1.5", s.MSDR)
+	}
+	if math.Abs(s.Correlation+1) > 1e-10 {
+		t.Errorf("Correlation = %v, want -1", s.Correlation)
+	}
+}
+
+func TestKFold(t *testing.T) {
+	points := types.Points{}
+	for i := 0; i < 10; i++ {
+		points.Points = append(points.Points, types.Point{X: float64(i), Value: float64(i)})
+	}
+	results, err := KFold(context.Background(), &meanInterpolator{}, points, 3, 42)
+	if err != nil {
+		t.Fatalf("KFold() error = %v", err)
+	}
+
+	counts := make(map[int]int)
+	for i, r := range results {
+		if r.Index != i {
+			t.Errorf("result %d has index %d", i, r.Index)
+		}
+		counts[r.Fold]++
+	}
+	if len(counts) != 3 {
+		t.Errorf("got %d folds, want 3", len(counts))
+	}
+
+	if _, err := KFold(context.Background(), &meanInterpolator{}, points, 1, 42); err == nil {
+		t.Error("expected an error for a single fold")
+	}
+}
+
+// cancelInterpolator cancels its context after predicting a number of folds
+type cancelInterpolator struct {
+	meanInterpolator
+	cancel context.CancelFunc
+	folds  int
+}
+
+func (c *cancelInterpolator) Interpolate(p types.Points) ([]types.Estimation, error) {
+	if c.folds--; c.folds == 0 {
+		c.cancel()
+	}
+	return c.meanInterpolator.Interpolate(p)
+}
+
+func TestLeaveOneOutCanceled(t *testing.T) {
+	points := types.Points{}
+	for i := 0; i < 5; i++ {
+		points.Points = append(points.Points, types.Point{X: float64(i), Value: float64(i)})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results, err := LeaveOneOut(ctx, &cancelInterpolator{cancel: cancel, folds: 2}, points)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("LeaveOneOut() error = %v, want %v", err, context.Canceled)
+	}
+	// the points after the cancellation are not predicted
+	for i, r := range results {
+		if r.Index != i || r.Observed != float64(i) || math.IsNaN(r.Predicted) != (i >= 2) {
+			t.Errorf("unexpected result %d: %+v", i, r)
+		}
+	}
+	if s := Summarize(results); s.N != 2 || s.Failed != 3 {
+		t.Errorf("got N=%d, Failed=%d, want 2 and 3", s.N, s.Failed)
+	}
+}
@@ -0,0 +1,85 @@
+package distance
+
+import (
+	"math"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// Transformer is implemented by distances that map points into a search space
+// in which a plain metric applies. Spatial indices are built in that space.
+type Transformer interface {
+	Transform(p *types.Point) types.Point
+	Base() types.Distance
+}
+
+// AnisotropicDistance applies a geometric anisotropy before measuring the
+// distance with the wrapped Metric. The major axis points along Azimuth
+// (degrees clockwise from north) and plunges by Dip (degrees below the
+// horizontal). Ratio is the minor to major horizontal range ratio and RatioZ
+// the vertical to major range ratio; zero ratios are treated as isotropic.
+type AnisotropicDistance struct {
+	Metric  types.Distance
+	Azimuth float64
+	Dip     float64
+	Ratio   float64
+	RatioZ  float64
+	Is3D    bool
+}
+
+func (d *AnisotropicDistance) Compute(p1, p2 *types.Point) float64 {
+	t1 := d.Transform(p1)
+	t2 := d.Transform(p2)
+	return d.Base().Compute(&t1, &t2)
+}
+
+func (d *AnisotropicDistance) Set3D(is3D bool) {
+	d.Is3D = is3D
+	d.Base().Set3D(is3D)
+}
+
+// Base returns the wrapped metric, defaulting to the euclidean distance.
+func (d *AnisotropicDistance) Base() types.Distance {
+	if d.Metric == nil {
+		d.Metric = &EuclideanDistance{Is3D: d.Is3D}
+	}
+	return d.Metric
+}
+
+// Transform rotates p onto the anisotropy axes and stretches the minor axes,
+// so that isotropic distances in the result equal anisotropic ones in p.
+func (d *AnisotropicDistance) Transform(p *types.Point) types.Point {
+	az := d.Azimuth * math.Pi / 180
+	dip := 0.0
+	if d.Is3D {
+		dip = d.Dip * math.Pi / 180
+	}
+	ratio := d.Ratio
+	if ratio == 0 {
+		ratio = 1
+	}
+	ratioZ := d.RatioZ
+	if ratioZ == 0 {
+		ratioZ = 1
+	}
+
+	// major, minor and vertical axis unit vectors
+	u := [3]float64{math.Sin(az) * math.Cos(dip), math.Cos(az) * math.Cos(dip), -math.Sin(dip)}
+	v := [3]float64{math.Cos(az), -math.Sin(az), 0}
+	w := [3]float64{
+		u[1]*v[2] - u[2]*v[1],
+		u[2]*v[0] - u[0]*v[2],
+		u[0]*v[1] - u[1]*v[0],
+	}
+
+	t := *p
+	if !d.Is3D {
+		t.X = p.X*u[0] + p.Y*u[1]
+		t.Y = (p.X*v[0] + p.Y*v[1]) / ratio
+		return t
+	}
+	t.X = p.X*u[0] + p.Y*u[1] + p.Z*u[2]
+	t.Y = (p.X*v[0] + p.Y*v[1] + p.Z*v[2]) / ratio
+	t.Z = (p.X*w[0] + p.Y*w[1] + p.Z*w[2]) / ratioZ
+	return t
+}
@@ -220,3 +220,55 @@ func BenchmarkEuclideanDistance_3D(b *testing.B) {
 		d.Compute(p1, p2)
 	}
 }
+
+func TestAnisotropicDistance_2D(t *testing.T) {
+	tests := []struct {
+		name     string
+		azimuth  float64
+		p1, p2   types.Point
+		expected float64
+	}{
+		{
+			name:     "along major axis",
+			azimuth:  0,
+			p1:       types.Point{X: 0, Y: 0},
+			p2:       types.Point{X: 0, Y: 4},
+			expected: 4,
+		},
+		{
+			name:     "along minor axis",
+			azimuth:  0,
+			p1:       types.Point{X: 0, Y: 0},
+			p2:       types.Point{X: 2, Y: 0},
+			expected: 4,
+		},
+		{
+			name:     "rotated major axis",
+			azimuth:  90,
+			p1:       types.Point{X: 0, Y: 0},
+			p2:       types.Point{X: 4, Y: 0},
+			expected: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &AnisotropicDistance{Azimuth: tt.azimuth, Ratio: 0.5}
+			got := d.Compute(&tt.p1, &tt.p2)
+			if math.Abs(got-tt.expected) > 1e-10 {
+				t.Errorf("Compute() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAnisotropicDistance_Isotropic(t *testing.T) {
+	d := &AnisotropicDistance{Metric: &ManhattanDistance{}, Is3D: true}
+	d.Set3D(true)
+	p1 := types.Point{X: 1, Y: 2, Z: 3}
+	p2 := types.Point{X: -1, Y: 0, Z: 0}
+	got := d.Compute(&p1, &p2)
+	if math.Abs(got-7) > 1e-10 {
+		t.Errorf("Compute() = %v, want %v", got, 7.0)
+	}
+}
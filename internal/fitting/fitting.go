@@ -1 +1,62 @@
 package fitting
+
+import (
+	"math"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// Statistics describes how well a model fits the sample variogram it was
+// fitted to. Lag classes without a semivariance are ignored.
+type Statistics struct {
+	// N is the number of lag classes compared
+	N int
+	// RMSE is the root mean squared difference of model and semivariances
+	RMSE float64
+	// R2 is the coefficient of determination of the semivariances
+	R2 float64
+	// WRMSE weights the squared differences by the pair counts of the lags
+	WRMSE float64
+}
+
+// Goodness compares the model m to the semivariances of v at their lag
+// edges.
+func Goodness(v types.SampleVariogram, m types.SpatialFunction) Statistics {
+	edges, semivars, counts := v.GetEdges(), v.GetSemivariances(), v.GetHistogram()
+	s := Statistics{}
+	var sum, sse, wsse, weights float64
+	for i, h := range edges {
+		if i >= len(semivars) || math.IsNaN(semivars[i]) {
+			continue
+		}
+		diff := m.Evaluate(h) - semivars[i]
+		s.N++
+		sum += semivars[i]
+		sse += diff * diff
+		if i < len(counts) {
+			wsse += float64(counts[i]) * diff * diff
+			weights += float64(counts[i])
+		}
+	}
+	if s.N == 0 {
+		return Statistics{RMSE: math.NaN(), R2: math.NaN(), WRMSE: math.NaN()}
+	}
+
+	mean := sum / float64(s.N)
+	sst := 0.0
+	for i := range edges {
+		if i < len(semivars) && !math.IsNaN(semivars[i]) {
+			sst += (semivars[i] - mean) * (semivars[i] - mean)
+		}
+	}
+	s.RMSE = math.Sqrt(sse / float64(s.N))
+	s.R2 = math.NaN()
+	if sst > 0 {
+		s.R2 = 1 - sse/sst
+	}
+	s.WRMSE = math.NaN()
+	if weights > 0 {
+		s.WRMSE = math.Sqrt(wsse / weights)
+	}
+	return s
+}
@@ -0,0 +1,357 @@
+// Package grid describes regular target grids that generate their nodes lazily.
+package grid
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// Registration tells how the origin of a grid relates to its cells. The
+// nodes always lie in the cell centres.
+type Registration int
+
+const (
+	// CellCenter grids have their origin in the centre of the south-west cell,
+	// which is the south-west node.
+	CellCenter Registration = iota
+	// CellCorner grids have their origin in the outer south-west corner of the
+	// south-west cell.
+	CellCorner
+)
+
+func (r Registration) String() string {
+	if r == CellCorner {
+		return "corner"
+	}
+	return "center"
+}
+
+// ParseRegistration returns the registration called name: center or corner.
+func ParseRegistration(name string) (Registration, error) {
+	switch name {
+	case "center", "centre", "":
+		return CellCenter, nil
+	case "corner":
+		return CellCorner, nil
+	default:
+		return CellCenter, fmt.Errorf("unsupported grid registration: %s", name)
+	}
+}
+
+// Spec is a regular 2D or 3D grid of nodes. The nodes are traversed row by
+// row from north to south and west to east within a row, matching the layout
+// of raster files, so that estimations can be written as soon as they are
+// produced. 3D grids repeat this for every layer, from bottom to top.
+type Spec struct {
+	// X0, Y0 and Z0 are the origin of the grid, see Registration
+	X0, Y0, Z0 float64
+	DX, DY, DZ float64
+	// NZ is 0 for 2D grids
+	NX, NY, NZ   int
+	Registration Registration
+}
+
+// Bounds is an axis-aligned bounding box.
+type Bounds struct {
+	MinX, MinY, MinZ float64
+	MaxX, MaxY, MaxZ float64
+}
+
+// BoundsOf returns the bounding box of p.
+func BoundsOf(p types.Points) Bounds {
+	b := Bounds{
+		MinX: math.Inf(1), MinY: math.Inf(1), MinZ: math.Inf(1),
+		MaxX: math.Inf(-1), MaxY: math.Inf(-1), MaxZ: math.Inf(-1),
+	}
+	for _, c := range p.Points {
+		b.MinX = math.Min(b.MinX, c.X)
+		b.MaxX = math.Max(b.MaxX, c.X)
+		b.MinY = math.Min(b.MinY, c.Y)
+		b.MaxY = math.Max(b.MaxY, c.Y)
+		b.MinZ = math.Min(b.MinZ, c.Z)
+		b.MaxZ = math.Max(b.MaxZ, c.Z)
+	}
+	return b
+}
+
+// Pad returns the bounding box grown by d on all sides.
+func (b Bounds) Pad(d float64) Bounds {
+	return Bounds{
+		MinX: b.MinX - d, MinY: b.MinY - d, MinZ: b.MinZ - d,
+		MaxX: b.MaxX + d, MaxY: b.MaxY + d, MaxZ: b.MaxZ + d,
+	}
+}
+
+// FromPoints returns a grid with its nodes covering the bounding box of p,
+// including the maximum edges. dz is ignored for 2D points.
+func FromPoints(p types.Points, dx, dy, dz float64) (Spec, error) {
+	if len(p.Points) == 0 {
+		return Spec{}, fmt.Errorf("no points to derive the grid from")
+	}
+	if !p.Is3D {
+		dz = 0
+	}
+	return FromBounds(BoundsOf(p), dx, dy, dz, CellCenter)
+}
+
+// FromBounds returns the grid covering b. With CellCenter registration the
+// nodes span b, with CellCorner registration the cells do. A dz of 0 gives a
+// 2D grid.
+func FromBounds(b Bounds, dx, dy, dz float64, reg Registration) (Spec, error) {
+	if dx <= 0 || dy <= 0 || dz < 0 {
+		return Spec{}, fmt.Errorf("grid spacing must be positive")
+	}
+	if b.MaxX < b.MinX || b.MaxY < b.MinY || (dz > 0 && b.MaxZ < b.MinZ) {
+		return Spec{}, fmt.Errorf("invalid bounds %v", b)
+	}
+	s := Spec{
+		X0: b.MinX, Y0: b.MinY,
+		DX: dx, DY: dy,
+		NX:           count(b.MaxX-b.MinX, dx, reg),
+		NY:           count(b.MaxY-b.MinY, dy, reg),
+		Registration: reg,
+	}
+	if dz > 0 {
+		s.Z0 = b.MinZ
+		s.DZ = dz
+		s.NZ = count(b.MaxZ-b.MinZ, dz, reg)
+	}
+	return s, nil
+}
+
+// FromNodes derives the cell centre registered 2D grid holding nodes. The
+// spacing of a single row or column is taken from the other axis, or 1 for a
+// single node.
+func FromNodes(nodes []types.Point) Spec {
+	xs := make(map[float64]struct{})
+	ys := make(map[float64]struct{})
+	for _, p := range nodes {
+		xs[p.X] = struct{}{}
+		ys[p.Y] = struct{}{}
+	}
+	minX, dx := spacing(xs)
+	minY, dy := spacing(ys)
+	if dx == 0 {
+		dx = dy
+	}
+	if dy == 0 {
+		dy = dx
+	}
+	if dx == 0 {
+		dx, dy = 1, 1
+	}
+	nx, ny := 1, 1
+	if len(xs) > 1 {
+		nx = int(math.Round((maxKey(xs)-minX)/dx)) + 1
+	}
+	if len(ys) > 1 {
+		ny = int(math.Round((maxKey(ys)-minY)/dy)) + 1
+	}
+	return Spec{X0: minX, Y0: minY, DX: dx, DY: dy, NX: nx, NY: ny}
+}
+
+// spacing returns the smallest coordinate and the smallest difference of
+// consecutive coordinates, which is 0 for a single coordinate
+func spacing(coords map[float64]struct{}) (float64, float64) {
+	sorted := make([]float64, 0, len(coords))
+	for c := range coords {
+		sorted = append(sorted, c)
+	}
+	sort.Float64s(sorted)
+	d := 0.0
+	for i := 1; i < len(sorted); i++ {
+		if diff := sorted[i] - sorted[i-1]; d == 0 || diff < d {
+			d = diff
+		}
+	}
+	return sorted[0], d
+}
+
+func maxKey(coords map[float64]struct{}) float64 {
+	m := math.Inf(-1)
+	for c := range coords {
+		m = math.Max(m, c)
+	}
+	return m
+}
+
+// count returns the number of nodes or cells needed to cover extent
+func count(extent, d float64, reg Registration) int {
+	// tolerate rounding errors of extents that are multiples of d
+	n := int(math.Ceil(extent/d - 1e-9))
+	if reg == CellCenter {
+		return n + 1
+	}
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// Validate checks that the grid has positive spacing and at least one node.
+func (s Spec) Validate() error {
+	if s.DX <= 0 || s.DY <= 0 || (s.NZ > 0 && s.DZ <= 0) {
+		return fmt.Errorf("grid spacing must be positive")
+	}
+	if s.NX < 1 || s.NY < 1 || s.NZ < 0 {
+		return fmt.Errorf("grid must have at least one node, got %d x %d x %d", s.NX, s.NY, s.NZ)
+	}
+	return nil
+}
+
+// Is3D reports whether the grid has layers.
+func (s Spec) Is3D() bool {
+	return s.NZ > 0
+}
+
+// Len returns the number of grid nodes.
+func (s Spec) Len() int {
+	if s.NZ > 0 {
+		return s.NX * s.NY * s.NZ
+	}
+	return s.NX * s.NY
+}
+
+// Dims returns the number of spatial dimensions of the grid.
+func (s Spec) Dims() int {
+	if s.NZ > 0 {
+		return 3
+	}
+	return 2
+}
+
+// Index returns the column, row and layer of the i-th node. Rows are counted
+// from the north, layers from the bottom.
+func (s Spec) Index(i int) (col, row, layer int) {
+	plane := s.NX * s.NY
+	layer = i / plane
+	i %= plane
+	return i % s.NX, i / s.NX, layer
+}
+
+// Offset returns the position of the node in column col, row row and layer
+// layer in the order of At. It is the inverse of Index.
+func (s Spec) Offset(col, row, layer int) int {
+	return layer*s.NX*s.NY + row*s.NX + col
+}
+
+// Node returns the node in column col, row row and layer layer.
+func (s Spec) Node(col, row, layer int) types.Point {
+	x, y, z := s.Center()
+	p := types.Point{
+		X: x + float64(col)*s.DX,
+		Y: y + float64(s.NY-1-row)*s.DY,
+	}
+	if s.NZ > 0 {
+		p.Z = z + float64(layer)*s.DZ
+		p.Is3D = true
+	}
+	return p
+}
+
+// At returns the i-th node in row-major order, starting in the north-west.
+func (s Spec) At(i int) types.Point {
+	return s.Node(s.Index(i))
+}
+
+// Locate returns the position in the order of At of the cell containing
+// the point, and false if the point lies outside of the grid.
+func (s Spec) Locate(p types.Point) (int, bool) {
+	xll, yll, zll := s.Corner()
+	col := int(math.Floor((p.X - xll) / s.DX))
+	rowFromSouth := int(math.Floor((p.Y - yll) / s.DY))
+	layer := 0
+	if s.NZ > 0 {
+		layer = int(math.Floor((p.Z - zll) / s.DZ))
+		if layer < 0 || layer >= s.NZ {
+			return 0, false
+		}
+	}
+	if col < 0 || col >= s.NX || rowFromSouth < 0 || rowFromSouth >= s.NY {
+		return 0, false
+	}
+	return s.Offset(col, s.NY-1-rowFromSouth, layer), true
+}
+
+// Center returns the coordinates of the south-west (bottom) node.
+func (s Spec) Center() (x, y, z float64) {
+	if s.Registration == CellCorner {
+		return s.X0 + s.DX/2, s.Y0 + s.DY/2, s.Z0 + s.DZ/2
+	}
+	return s.X0, s.Y0, s.Z0
+}
+
+// Corner returns the outer south-west (bottom) corner of the grid.
+func (s Spec) Corner() (x, y, z float64) {
+	if s.Registration == CellCorner {
+		return s.X0, s.Y0, s.Z0
+	}
+	return s.X0 - s.DX/2, s.Y0 - s.DY/2, s.Z0 - s.DZ/2
+}
+
+// Bounds returns the outer edges of the grid cells.
+func (s Spec) Bounds() Bounds {
+	x, y, z := s.Corner()
+	return Bounds{
+		MinX: x, MinY: y, MinZ: z,
+		MaxX: x + float64(s.NX)*s.DX,
+		MaxY: y + float64(s.NY)*s.DY,
+		MaxZ: z + float64(s.NZ)*s.DZ,
+	}
+}
+
+// Points materializes all nodes of the grid, in the order of At.
+func (s Spec) Points() types.Points {
+	points := make([]types.Point, s.Len())
+	for i := range points {
+		points[i] = s.At(i)
+	}
+	return types.Points{Points: points, Is3D: s.Is3D()}
+}
+
+// Aligned returns an error unless o has the same cells as s, i.e. the same
+// counts, spacing and outer corner. Coordinates are compared with a tolerance
+// of a millionth of the cell size, which covers rounding in text formats.
+func (s Spec) Aligned(o Spec) error {
+	if s.NX != o.NX || s.NY != o.NY || s.NZ != o.NZ {
+		return fmt.Errorf("grid has %d x %d x %d cells, want %d x %d x %d", o.NX, o.NY, o.NZ, s.NX, s.NY, s.NZ)
+	}
+	near := func(a, b, d float64) bool {
+		return math.Abs(a-b) <= 1e-6*d
+	}
+	if !near(s.DX, o.DX, s.DX) || !near(s.DY, o.DY, s.DY) || (s.NZ > 0 && !near(s.DZ, o.DZ, s.DZ)) {
+		return fmt.Errorf("grid has cell size %g x %g, want %g x %g", o.DX, o.DY, s.DX, s.DY)
+	}
+	sx, sy, sz := s.Corner()
+	ox, oy, oz := o.Corner()
+	if !near(sx, ox, s.DX) || !near(sy, oy, s.DY) || (s.NZ > 0 && !near(sz, oz, s.DZ)) {
+		return fmt.Errorf("grid corner is (%g, %g), want (%g, %g)", ox, oy, sx, sy)
+	}
+	return nil
+}
+
+// CovariateGrid generates the nodes of a grid with covariates attached, e.g.
+// for kriging with external drift. Covariates holds one value per node in
+// the order of At for each named covariate. NaN values are left out.
+type CovariateGrid struct {
+	Spec
+	Covariates map[string][]float64
+}
+
+// At returns the i-th node of the grid with its covariates.
+func (g CovariateGrid) At(i int) types.Point {
+	p := g.Spec.At(i)
+	if len(g.Covariates) == 0 {
+		return p
+	}
+	p.Covariates = make(map[string]float64, len(g.Covariates))
+	for name, values := range g.Covariates {
+		if !math.IsNaN(values[i]) {
+			p.Covariates[name] = values[i]
+		}
+	}
+	return p
+}
@@ -0,0 +1,85 @@
+package grid
+
+import (
+	"testing"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+func TestFromPoints(t *testing.T) {
+	p := types.Points{Points: []types.Point{{X: 0, Y: 0}, {X: 30, Y: 20}}}
+	s, err := FromPoints(p, 10, 10, 10)
+	if err != nil {
+		t.Fatalf("FromPoints() error = %v", err)
+	}
+	// the maximum edges are included
+	if s.NX != 4 || s.NY != 3 {
+		t.Errorf("got %d x %d nodes, want 4 x 3", s.NX, s.NY)
+	}
+	if s.Len() != 12 || s.Is3D() {
+		t.Errorf("Len() = %d, want 12 2D nodes", s.Len())
+	}
+}
+
+func TestSpecAtRowMajor(t *testing.T) {
+	s := Spec{X0: 0, Y0: 0, DX: 1, DY: 2, NX: 3, NY: 2}
+
+	expected := []types.Point{
+		{X: 0, Y: 2}, {X: 1, Y: 2}, {X: 2, Y: 2},
+		{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0},
+	}
+	for i, want := range expected {
+		got := s.At(i)
+		if got.X != want.X || got.Y != want.Y {
+			t.Errorf("At(%d) = (%v, %v), want (%v, %v)", i, got.X, got.Y, want.X, want.Y)
+		}
+	}
+}
+
+func TestFromBoundsCorner(t *testing.T) {
+	s, err := FromBounds(Bounds{MinX: 0, MinY: 0, MaxX: 25, MaxY: 20}, 10, 10, 0, CellCorner)
+	if err != nil {
+		t.Fatalf("FromBounds() error = %v", err)
+	}
+	if s.NX != 3 || s.NY != 2 {
+		t.Errorf("got %d x %d cells, want 3 x 2", s.NX, s.NY)
+	}
+	// nodes lie in the cell centres
+	if p := s.At(3); p.X != 5 || p.Y != 5 {
+		t.Errorf("At(3) = (%v, %v), want (5, 5)", p.X, p.Y)
+	}
+	if b := s.Bounds(); b.MaxX != 30 || b.MaxY != 20 {
+		t.Errorf("Bounds() max = (%v, %v), want (30, 20)", b.MaxX, b.MaxY)
+	}
+}
+
+func TestSpecLocate3D(t *testing.T) {
+	s := Spec{DX: 1, DY: 1, DZ: 2, NX: 3, NY: 2, NZ: 2}
+	for i := 0; i < s.Len(); i++ {
+		p := s.At(i)
+		if !p.Is3D {
+			t.Fatalf("At(%d) is not 3D", i)
+		}
+		if j, ok := s.Locate(p); !ok || j != i {
+			t.Errorf("Locate(At(%d)) = %d, %v", i, j, ok)
+		}
+		if col, row, layer := s.Index(i); s.Offset(col, row, layer) != i {
+			t.Errorf("Offset(Index(%d)) = %d", i, s.Offset(col, row, layer))
+		}
+	}
+	if _, ok := s.Locate(types.Point{X: 5, Y: 0, Z: 0}); ok {
+		t.Error("Locate() should reject points outside of the grid")
+	}
+}
+
+func TestSpecAligned(t *testing.T) {
+	center := Spec{X0: 0.5, Y0: 0.5, DX: 1, DY: 1, NX: 3, NY: 2}
+	corner := Spec{X0: 0, Y0: 0, DX: 1, DY: 1, NX: 3, NY: 2, Registration: CellCorner}
+	if err := center.Aligned(corner); err != nil {
+		t.Errorf("Aligned() error = %v", err)
+	}
+	corner.X0 = 0.5
+	if err := center.Aligned(corner); err == nil {
+		t.Error("expected an error for shifted grids")
+	}
+}
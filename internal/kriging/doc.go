@@ -9,7 +9,18 @@ Key Features:
 
   - Ordinary kriging implementation
   - Support for various distance metrics
-  - Efficient neighbor selection
+  - Efficient neighbor selection using a k-d tree
+  - Geometric anisotropy through distance.AnisotropicDistance
+  - Search neighborhoods with radius, min/max points, sectors and group limits
+  - Optional per-target diagnostics: weights, Lagrange multiplier, slope of
+    regression and kriging efficiency, see SetDiagnostics
+  - Condition number checks with regularized and pseudo-inverse fallback
+    solvers, see Solver
+  - Detection of duplicate locations, merged according to a DuplicatePolicy
+  - Measurement-error kriging with per-point error variances, filtered or
+    exact, see SetErrorMode
+  - Kriging with external drift from point covariates, see SetDrift
+  - Prediction domains through a types.Mask, see SetMask and package mask
   - Variance estimation
   - Support for 2D and 3D datasets
 
@@ -34,10 +45,15 @@ The package provides several error types for handling common kriging issues:
   - ErrGridCreation: When grid creation fails
   - ErrInterpolation: When interpolation fails at a specific point
 
+Targets that fail are returned as NaN with their ErrCode and ErrInterpolation
+set, while Interpolate reports all failures in a *types.EstimationErrors.
+
 For performance optimization, the package includes:
 
   - Efficient matrix operations
-  - Neighbor selection algorithms
-  - Parallel processing capabilities
+  - k-nearest and radius neighbor queries on a k-d tree built by Fit
+  - A bounded worker pool, see SetWorkers
+  - Cancellation and timeouts through InterpolateContext
+  - Streaming of estimations in target order through InterpolateFunc
 */
 package kriging
@@ -0,0 +1,32 @@
+package kriging
+
+import (
+	"math"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// SetDrift turns ordinary kriging into kriging with external drift. The mean
+// is modelled as a linear function of the named covariates, which are read
+// from Point.Covariates of the condition points and of every target.
+// Condition points missing a covariate are left out by Fit, targets missing
+// one fail with ErrMissingCovariate. Call SetDrift before Fit.
+func (k *OrdinaryKriging) SetDrift(covariates ...string) {
+	k.drift = covariates
+}
+
+// Drift returns the names of the drift covariates.
+func (k *OrdinaryKriging) Drift() []string {
+	return k.drift
+}
+
+// hasCovariates reports whether p carries all drift covariates
+func (k *OrdinaryKriging) hasCovariates(p *types.Point) bool {
+	for _, name := range k.drift {
+		v, ok := p.Covariate(name)
+		if !ok || math.IsNaN(v) {
+			return false
+		}
+	}
+	return true
+}
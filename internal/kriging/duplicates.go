@@ -0,0 +1,225 @@
+package kriging
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"gonum.org/v1/gonum/stat"
+)
+
+// DuplicatePolicy selects how Fit handles condition points that share a
+// location. Duplicates make the kriging matrix singular.
+type DuplicatePolicy int
+
+const (
+	// DuplicatesKeep keeps all points and leaves the singular systems to the Solver
+	DuplicatesKeep DuplicatePolicy = iota
+	// DuplicatesAverage replaces duplicates by a single point with their mean value
+	DuplicatesAverage
+	// DuplicatesFirst keeps only the first of the duplicates
+	DuplicatesFirst
+	// DuplicatesMeasurementError keeps all points and treats the variance of
+	// their values as measurement error
+	DuplicatesMeasurementError
+	// DuplicatesError rejects condition points with duplicates: Fit leaves the
+	// interpolator unfitted, and interpolation returns the error
+	DuplicatesError
+)
+
+var duplicatePolicyNames = map[DuplicatePolicy]string{
+	DuplicatesKeep:             "keep",
+	DuplicatesAverage:          "average",
+	DuplicatesFirst:            "first",
+	DuplicatesMeasurementError: "measurement",
+	DuplicatesError:            "error",
+}
+
+func (d DuplicatePolicy) String() string {
+	if name, ok := duplicatePolicyNames[d]; ok {
+		return name
+	}
+	return fmt.Sprintf("DuplicatePolicy(%d)", int(d))
+}
+
+// ParseDuplicatePolicy returns the policy called name: keep, average, first,
+// measurement or error.
+func ParseDuplicatePolicy(name string) (DuplicatePolicy, error) {
+	for d, n := range duplicatePolicyNames {
+		if strings.EqualFold(name, n) {
+			return d, nil
+		}
+	}
+	return DuplicatesKeep, fmt.Errorf("unsupported duplicate policy: %s", name)
+}
+
+// findDuplicates groups the points that lie within tol of each other in
+// coordinate space. Only groups of two or more points are returned, ordered
+// by their first index.
+func findDuplicates(points []types.Point, tol float64, is3D bool) [][]int {
+	parent := make([]int, len(points))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	// hash the points into cells of size tol, so that only neighbouring
+	// cells have to be compared
+	cell := func(v float64) int64 {
+		if tol <= 0 {
+			return int64(math.Float64bits(v + 0))
+		}
+		return int64(math.Floor(v / tol))
+	}
+	type key struct {
+		x, y, z int64
+	}
+	cells := make(map[key][]int)
+	for i, p := range points {
+		k := key{cell(p.X), cell(p.Y), 0}
+		if is3D {
+			k.z = cell(p.Z)
+		}
+		cells[k] = append(cells[k], i)
+	}
+
+	dz := []int64{0}
+	if is3D && tol > 0 {
+		dz = []int64{-1, 0, 1}
+	}
+	d := []int64{0}
+	if tol > 0 {
+		d = []int64{-1, 0, 1}
+	}
+	for i, p := range points {
+		k := key{cell(p.X), cell(p.Y), 0}
+		if is3D {
+			k.z = cell(p.Z)
+		}
+		for _, ox := range d {
+			for _, oy := range d {
+				for _, oz := range dz {
+					for _, j := range cells[key{k.x + ox, k.y + oy, k.z + oz}] {
+						if j <= i || !coincident(p, points[j], tol, is3D) {
+							continue
+						}
+						if ri, rj := find(i), find(j); ri != rj {
+							if ri < rj {
+								parent[rj] = ri
+							} else {
+								parent[ri] = rj
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	groups := make(map[int][]int)
+	roots := make([]int, 0)
+	for i := range points {
+		r := find(i)
+		if _, ok := groups[r]; !ok {
+			roots = append(roots, r)
+		}
+		groups[r] = append(groups[r], i)
+	}
+	dups := make([][]int, 0)
+	for _, r := range roots {
+		if len(groups[r]) > 1 {
+			dups = append(dups, groups[r])
+		}
+	}
+	return dups
+}
+
+// coincident reports whether a and b are within tol of each other
+func coincident(a, b types.Point, tol float64, is3D bool) bool {
+	dx, dy, dz := a.X-b.X, a.Y-b.Y, 0.0
+	if is3D {
+		dz = a.Z - b.Z
+	}
+	return math.Sqrt(dx*dx+dy*dy+dz*dz) <= tol
+}
+
+// mergeDuplicates applies the duplicate policy to points, whose indices in
+// the points passed to Fit are idx. It returns the remaining points, their
+// indices and the measurement error variance of each point, which is nil if
+// no point has one. floor is the smallest error variance assigned to
+// duplicates under DuplicatesMeasurementError.
+func mergeDuplicates(points []types.Point, idx []int, dups [][]int, policy DuplicatePolicy, floor float64) ([]types.Point, []int, []float64) {
+	if len(dups) == 0 || policy == DuplicatesKeep {
+		return points, idx, errorVariances(points)
+	}
+
+	if policy == DuplicatesMeasurementError {
+		errVar := make([]float64, len(points))
+		for i, p := range points {
+			errVar[i] = p.ErrorVariance
+		}
+		for _, g := range dups {
+			values := make([]float64, len(g))
+			for i, j := range g {
+				values[i] = points[j].Value
+			}
+			v := math.Max(stat.Variance(values, nil), floor)
+			for _, j := range g {
+				errVar[j] += v
+			}
+		}
+		return points, idx, errVar
+	}
+
+	drop := make([]bool, len(points))
+	merged := make([]types.Point, len(points))
+	copy(merged, points)
+	for _, g := range dups {
+		if policy == DuplicatesAverage {
+			sum, errSum := 0.0, 0.0
+			for _, j := range g {
+				sum += points[j].Value
+				errSum += points[j].ErrorVariance
+			}
+			n := float64(len(g))
+			merged[g[0]].Value = sum / n
+			merged[g[0]].ErrorVariance = errSum / (n * n)
+		}
+		for _, j := range g[1:] {
+			drop[j] = true
+		}
+	}
+
+	outPoints := make([]types.Point, 0, len(points))
+	outIdx := make([]int, 0, len(points))
+	for i, p := range merged {
+		if !drop[i] {
+			outPoints = append(outPoints, p)
+			outIdx = append(outIdx, idx[i])
+		}
+	}
+	return outPoints, outIdx, errorVariances(outPoints)
+}
+
+// errorVariances returns the measurement error variances of points, or nil
+// if none of the points has one.
+func errorVariances(points []types.Point) []float64 {
+	var errVar []float64
+	for i, p := range points {
+		if p.ErrorVariance > 0 {
+			if errVar == nil {
+				errVar = make([]float64, len(points))
+			}
+			errVar[i] = p.ErrorVariance
+		}
+	}
+	return errVar
+}
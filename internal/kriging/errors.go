@@ -1,7 +1,11 @@
 // Package kriging implements ordinary kriging interpolation methods.
 package kriging
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
 
 // ErrInvalidPoints is returned when the input points for kriging are invalid.
 // This can occur when points are nil, empty, or contain invalid coordinates.
@@ -60,13 +64,31 @@ func (e ErrGridCreation) Error() string {
 // This can occur due to numerical instability, singular matrices, or other
 // computational issues at the given location.
 type ErrInterpolation struct {
-	// Point is a string representation of the location where interpolation failed
-	Point string
+	// Point is the location where interpolation failed
+	Point types.Point
+	// Code classifies the failure
+	Code types.EstimationError
+	// Size is the dimension of the kriging system, 0 if none was set up
+	Size int
 	// Reason contains a detailed description of why interpolation failed
 	Reason string
+	// Err is the underlying error, if any
+	Err error
 }
 
 // Error implements the error interface for ErrInterpolation.
 func (e ErrInterpolation) Error() string {
-	return fmt.Sprintf("interpolation failed at point %s: %s", e.Point, e.Reason)
+	loc := fmt.Sprintf("(%g, %g)", e.Point.X, e.Point.Y)
+	if e.Point.Is3D {
+		loc = fmt.Sprintf("(%g, %g, %g)", e.Point.X, e.Point.Y, e.Point.Z)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("interpolation failed at point %s: %s: %v", loc, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("interpolation failed at point %s: %s", loc, e.Reason)
+}
+
+// Unwrap returns the underlying error.
+func (e ErrInterpolation) Unwrap() error {
+	return e.Err
 }
@@ -1,6 +1,7 @@
 package kriging_test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,6 +9,7 @@ import (
 	"github.com/mmaelicke/go-geostat/internal/distance"
 	"github.com/mmaelicke/go-geostat/internal/empirical"
 	"github.com/mmaelicke/go-geostat/internal/estimator"
+	"github.com/mmaelicke/go-geostat/internal/grid"
 	"github.com/mmaelicke/go-geostat/internal/kriging"
 	"github.com/mmaelicke/go-geostat/internal/types"
 	"github.com/mmaelicke/go-geostat/internal/variogram"
@@ -56,14 +58,15 @@ func ExampleNew_withPancakeData() {
 	kr.Fit(points)
 
 	// Create a grid for interpolation
-	grid, err := kriging.DenseGrid(points, 10.0, 10.0, 0)
+	spec, err := grid.FromPoints(points, 10.0, 10.0, 0)
 	if err != nil {
 		fmt.Printf("Error creating grid: %v\n", err)
 		return
 	}
+	targets := spec.Points()
 
 	// Perform interpolation
-	estimations, err := kr.Interpolate(grid)
+	estimations, err := kr.Interpolate(targets)
 	if err != nil {
 		fmt.Printf("Error interpolating: %v\n", err)
 		return
@@ -103,11 +106,11 @@ func ExampleNew_withPancakeData() {
 
 	// Output:
 	// Interpolation Results:
-	// Number of points interpolated: 2500
-	// Mean estimated value: 187.05
+	// Number of points interpolated: 2601
+	// Mean estimated value: 186.43
 	// Value range: 91.27 - 243.37
-	// Mean estimation variance: 251.95
-	// Variance range: 171.12 - 549.08
+	// Mean estimation variance: 255.14
+	// Variance range: 171.12 - 650.41
 }
 
 func ExampleNew() {
@@ -193,3 +196,272 @@ func ExampleNew_with3D() {
 	// Estimated value at (0.5, 0.5, 0.5): 1.50
 	// Estimation variance: 0.03
 }
+
+func ExampleNew_withAnisotropy() {
+	model, _ := variogram.NewVariogram("spherical", types.BaseParams{
+		Range: 10,
+		Sill:  1.0,
+	})
+
+	// the spatial correlation is twice as long in north-south direction
+	dist := &distance.AnisotropicDistance{Azimuth: 0, Ratio: 0.5}
+
+	// use only the closest condition point
+	kr := kriging.New(model, 1, dist, false)
+
+	points := types.Points{
+		Points: []types.Point{
+			{X: 0, Y: 3, Value: 1.0},
+			{X: 2, Y: 0, Value: 2.0},
+		},
+	}
+	kr.Fit(points)
+
+	// both points are 3 and 2 units away, but anisotropic distances are 3 and 4
+	estimations, _ := kr.Interpolate(types.Points{Points: []types.Point{{X: 0, Y: 0}}})
+	fmt.Printf("Estimated value at (0, 0): %.2f\n", estimations[0].Field)
+
+	// Output:
+	// Estimated value at (0, 0): 1.00
+}
+
+func ExampleOrdinaryKriging_SetNeighborhood() {
+	model, _ := variogram.NewVariogram("spherical", types.BaseParams{
+		Range: 10,
+		Sill:  1.0,
+	})
+
+	kr := kriging.New(model, 4, nil, false)
+	kr.SetNeighborhood(kriging.Neighborhood{
+		MaxDistance:  5,
+		MinPoints:    2,
+		MaxPoints:    4,
+		Sectors:      4,
+		MaxPerSector: 1,
+	})
+
+	// two points east of the origin share one quadrant, one point lies far away
+	points := types.Points{
+		Points: []types.Point{
+			{X: 1, Y: 1, Value: 1.0},
+			{X: 2, Y: 1, Value: 5.0},
+			{X: -1, Y: -1, Value: 3.0},
+			{X: 20, Y: 20, Value: 9.0},
+		},
+	}
+	kr.Fit(points)
+
+	targets := types.Points{Points: []types.Point{{X: 0, Y: 0}, {X: 20, Y: 19}}}
+	estimations, _ := kr.Interpolate(targets)
+
+	// only (1, 1) and (-1, -1) are used at the origin
+	fmt.Printf("Estimated value at (0, 0): %.2f\n", estimations[0].Field)
+	// a single point in the search radius does not meet MinPoints
+	fmt.Printf("Estimated value at (20, 19): %.2f\n", estimations[1].Field)
+
+	// Output:
+	// Estimated value at (0, 0): 2.00
+	// Estimated value at (20, 19): NaN
+}
+
+func ExampleOrdinaryKriging_Interpolate_errors() {
+	model, _ := variogram.NewVariogram("spherical", types.BaseParams{
+		Range: 10,
+		Sill:  1.0,
+	})
+
+	kr := kriging.New(model, 4, nil, false)
+	kr.SetNeighborhood(kriging.Neighborhood{MaxDistance: 5, MinPoints: 2, MaxPoints: 4})
+	kr.Fit(types.Points{
+		Points: []types.Point{
+			{X: 0, Y: 0, Value: 1.0},
+			{X: 1, Y: 0, Value: 2.0},
+		},
+	})
+
+	targets := types.Points{Points: []types.Point{{X: 0.5, Y: 0}, {X: 20, Y: 20}}}
+	estimations, err := kr.Interpolate(targets)
+
+	// failed points keep their error code and reason
+	fmt.Println(estimations[1].ErrCode)
+	fmt.Println(estimations[1].Err)
+	fmt.Println(err)
+
+	// Output:
+	// no condition points
+	// interpolation failed at point (20, 20): not enough condition points in the search neighborhood
+	// 1 of 2 estimations failed: 1 no condition points
+}
+
+func ExampleOrdinaryKriging_SetDuplicates() {
+	model, _ := variogram.NewVariogram("spherical", types.BaseParams{
+		Range:  10,
+		Sill:   1.0,
+		Nugget: 0.1,
+	})
+
+	// the first two samples were taken at the same location
+	points := types.Points{
+		Points: []types.Point{
+			{X: 0, Y: 0, Value: 1.0},
+			{X: 0, Y: 0, Value: 3.0},
+			{X: 4, Y: 0, Value: 4.0},
+		},
+	}
+	targets := types.Points{Points: []types.Point{{X: 1, Y: 0}}}
+
+	// by default, the singular system is left to the solver fallback
+	kr := kriging.New(model, 4, nil, false)
+	kr.SetDiagnostics(true)
+	kr.Fit(points)
+	estimations, _ := kr.Interpolate(targets)
+	fmt.Printf("Duplicates: %v\n", kr.Duplicates())
+	fmt.Printf("keep: %.2f (%s)\n", estimations[0].Field, estimations[0].Diagnostics.Solver)
+
+	for _, policy := range []kriging.DuplicatePolicy{kriging.DuplicatesAverage, kriging.DuplicatesFirst, kriging.DuplicatesMeasurementError} {
+		kr.SetDuplicates(policy, 0)
+		kr.Fit(points)
+		estimations, _ = kr.Interpolate(targets)
+		fmt.Printf("%s: %.2f (%s)\n", policy, estimations[0].Field, estimations[0].Diagnostics.Solver)
+	}
+
+	// duplicates can also be rejected
+	kr.SetDuplicates(kriging.DuplicatesError, 0)
+	kr.Fit(points)
+	_, err := kr.Interpolate(targets)
+	fmt.Printf("error: %v\n", err)
+
+	// Output:
+	// Duplicates: [[0 1]]
+	// keep: 2.49 (regularized)
+	// average: 2.49 (direct)
+	// first: 1.74 (direct)
+	// measurement: 3.20 (direct)
+	// error: 1 duplicate locations, e.g. points [0 1]
+}
+
+func ExampleOrdinaryKriging_SetErrorMode() {
+	model, _ := variogram.NewVariogram("spherical", types.BaseParams{
+		Range: 10,
+		Sill:  1.0,
+	})
+
+	// the lab value at (0, 0) is uncertain
+	points := types.Points{
+		Points: []types.Point{
+			{X: 0, Y: 0, Value: 1.0, ErrorVariance: 0.5},
+			{X: 2, Y: 0, Value: 2.0},
+			{X: 0, Y: 2, Value: 2.0},
+		},
+	}
+	targets := types.Points{Points: []types.Point{{X: 0, Y: 0}}}
+
+	kr := kriging.New(model, 4, nil, false)
+	for _, mode := range []kriging.ErrorMode{kriging.Filtered, kriging.Exact} {
+		kr.SetErrorMode(mode)
+		kr.Fit(points)
+		estimations, _ := kr.Interpolate(targets)
+		fmt.Printf("%s: %.2f, variance %.2f\n", mode, estimations[0].Field, estimations[0].Variance)
+	}
+
+	// Output:
+	// filtered: 1.56, variance 0.22
+	// exact: 1.00, variance 0.00
+}
+
+func ExampleOrdinaryKriging_SetDrift() {
+	model, _ := variogram.NewVariogram("spherical", types.BaseParams{
+		Range: 5,
+		Sill:  1.0,
+	})
+
+	// the values follow twice the elevation
+	elev := func(e float64) map[string]float64 { return map[string]float64{"elev": e} }
+	points := types.Points{
+		Points: []types.Point{
+			{X: 0, Y: 0, Value: 2, Covariates: elev(1)},
+			{X: 10, Y: 0, Value: 4, Covariates: elev(2)},
+			{X: 0, Y: 10, Value: 6, Covariates: elev(3)},
+			{X: 10, Y: 10, Value: 8, Covariates: elev(4)},
+		},
+	}
+	targets := types.Points{Points: []types.Point{{X: 5, Y: 5, Covariates: elev(10)}, {X: 5, Y: 5}}}
+
+	kr := kriging.New(model, 4, nil, false)
+	kr.SetDrift("elev")
+	kr.Fit(points)
+	estimations, _ := kr.Interpolate(targets)
+
+	fmt.Printf("Estimated value at elevation 10: %.2f\n", estimations[0].Field)
+	fmt.Println(estimations[1].ErrCode)
+
+	// Output:
+	// Estimated value at elevation 10: 20.00
+	// missing covariate
+}
+
+func ExampleOrdinaryKriging_InterpolateFunc() {
+	model, _ := variogram.NewVariogram("spherical", types.BaseParams{
+		Range: 10,
+		Sill:  1.0,
+	})
+
+	kr := kriging.New(model, 10, nil, false)
+	kr.SetWorkers(2)
+	kr.Fit(types.Points{
+		Points: []types.Point{
+			{X: 0, Y: 0, Value: 1.0},
+			{X: 4, Y: 0, Value: 3.0},
+		},
+	})
+
+	targets := types.Points{Points: []types.Point{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 4, Y: 0}}}
+
+	// estimations are streamed in the order of the targets
+	err := kr.InterpolateFunc(context.Background(), targets, func(i int, e types.Estimation) error {
+		fmt.Printf("%d: %.2f\n", i, e.Field)
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error interpolating: %v\n", err)
+	}
+
+	// a cancelled context stops the interpolation
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = kr.InterpolateContext(ctx, targets)
+	fmt.Println(err)
+
+	// Output:
+	// 0: 1.00
+	// 1: 2.00
+	// 2: 3.00
+	// context canceled
+}
+
+func ExampleOrdinaryKriging_SetDiagnostics() {
+	model, _ := variogram.NewVariogram("spherical", types.BaseParams{
+		Range: 10,
+		Sill:  1.0,
+	})
+
+	kr := kriging.New(model, 10, nil, false)
+	kr.SetDiagnostics(true)
+	kr.Fit(types.Points{
+		Points: []types.Point{
+			{X: 0, Y: 0, Value: 1.0},
+			{X: 4, Y: 0, Value: 3.0},
+		},
+	})
+
+	estimations, _ := kr.Interpolate(types.Points{Points: []types.Point{{X: 1, Y: 0}}})
+	d := estimations[0].Diagnostics
+	fmt.Printf("Neighbors: %v\n", d.Neighbors)
+	fmt.Printf("Weights: %.2f, %.2f\n", d.Weights[0], d.Weights[1])
+	fmt.Printf("Nearest distance: %.2f\n", d.NearestDistance)
+
+	// Output:
+	// Neighbors: [0 1]
+	// Weights: 0.75, 0.25
+	// Nearest distance: 1.00
+}
@@ -0,0 +1,177 @@
+package kriging
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// InterpolateFunc estimates all locations of p on a bounded pool of workers
+// and passes each estimation to fn, in the order of p. At most twice the
+// number of workers estimations are held in memory at any time, independent of
+// the size of p. Returning an error from fn, or cancelling ctx, stops the
+// interpolation.
+//
+// Targets that cannot be estimated are passed to fn with NaN values, their
+// ErrCode and an ErrInterpolation. Once all targets are done, a
+// *types.EstimationErrors summarizing these failures is returned. Targets
+// outside of the mask are passed with NaN values and ErrMasked, but do not
+// count as failures.
+func (k *OrdinaryKriging) InterpolateFunc(ctx context.Context, p types.Locations, fn func(i int, e types.Estimation) error) error {
+	if err := k.fitted(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	n := p.Len()
+	if n == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := k.workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	// slots bounds the number of dispatched, but not yet emitted targets
+	slots := make(chan struct{}, 2*workers)
+	jobs := make(chan int)
+	results := make(chan krigResult, workers)
+
+	go func() {
+		defer close(jobs)
+		for i := 0; i < n; i++ {
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var est types.Estimation
+				var prof StepProfile
+				var err error
+				if target := p.At(i); k.mask != nil && !k.mask.Contains(target) {
+					est = types.Estimation{ErrCode: types.ErrMasked}
+				} else {
+					est, prof, err = k.krige(target)
+				}
+				select {
+				case results <- krigResult{Index: i, Estimation: est, Profile: prof, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int]krigResult, 2*workers)
+	next := 0
+	initSum := time.Duration(0)
+	matSum := time.Duration(0)
+	solvSum := time.Duration(0)
+	totalSum := time.Duration(0)
+	nOk := 0
+	failures := &types.EstimationErrors{}
+
+	defer func() {
+		if nOk == 0 {
+			nOk = 1
+		}
+		k.profile.KInitMeanTime = initSum / time.Duration(nOk)
+		k.profile.KMatMeanTime = matSum / time.Duration(nOk)
+		k.profile.KSolvMeanTime = solvSum / time.Duration(nOk)
+		k.profile.KTotalMeanTime = totalSum / time.Duration(nOk)
+	}()
+
+	for r := range results {
+		pending[r.Index] = r
+		// emit all results that are next in order
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			<-slots
+
+			est := r.Estimation
+			if r.Err != nil || est.ErrCode != types.ErrNone {
+				est = types.Estimation{
+					Field:       math.NaN(),
+					Variance:    math.NaN(),
+					ErrCode:     est.ErrCode,
+					Err:         r.Err,
+					Diagnostics: est.Diagnostics,
+				}
+			} else {
+				initSum += r.Profile.InitTime
+				matSum += r.Profile.MatTime
+				solvSum += r.Profile.SolvTime
+				totalSum += r.Profile.TotalTime
+				nOk++
+			}
+			failures.Add(est)
+			if err := fn(next, est); err != nil {
+				return err
+			}
+			next++
+		}
+	}
+
+	if next < n {
+		return ctx.Err()
+	}
+	return failures.Err()
+}
+
+// InterpolateTo streams the estimations of all locations of p to sink, in the
+// order of p, and flushes the sink once all locations have been estimated.
+// Locations that cannot be estimated are written as NaN and reported by a
+// *types.EstimationErrors after the flush. Any other error, like ctx.Err()
+// on cancellation or a failing Write, stops the estimation and is returned
+// without flushing: the sink then holds an incomplete grid, which the caller
+// has to discard.
+func (k *OrdinaryKriging) InterpolateTo(ctx context.Context, p types.Locations, sink types.EstimationSink) error {
+	err := k.InterpolateFunc(ctx, p, func(i int, e types.Estimation) error {
+		return sink.Write(p.At(i), e)
+	})
+	var failures *types.EstimationErrors
+	if err != nil && !errors.As(err, &failures) {
+		return err
+	}
+	if ferr := sink.Flush(); ferr != nil {
+		return ferr
+	}
+	return err
+}
@@ -0,0 +1,197 @@
+package kriging_test
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/mmaelicke/go-geostat/internal/distance"
+	"github.com/mmaelicke/go-geostat/internal/kriging"
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"github.com/mmaelicke/go-geostat/internal/variogram"
+)
+
+// model returns a spherical model of range 10 and sill 1
+func model(t *testing.T, nugget float64) types.SpatialFunction {
+	t.Helper()
+	m, err := variogram.NewVariogram("spherical", types.BaseParams{Range: 10, Sill: 1, Nugget: nugget})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestRegularizedCoincidentSamples(t *testing.T) {
+	kr := kriging.New(model(t, 0.1), 4, nil, false)
+	kr.SetDiagnostics(true)
+	kr.Fit(types.Points{Points: []types.Point{
+		{X: 0, Y: 0, Value: 1},
+		{X: 0, Y: 0, Value: 3},
+		{X: 4, Y: 0, Value: 4},
+	}})
+	estimations, err := kr.Interpolate(types.Points{Points: []types.Point{{X: 1, Y: 0}, {X: 3, Y: 1}}})
+	if err != nil {
+		t.Fatalf("Interpolate() error = %v", err)
+	}
+	for i, e := range estimations {
+		d := e.Diagnostics
+		if d.Solver != kriging.SolverRegularized {
+			t.Errorf("target %d solved by %s, want %s", i, d.Solver, kriging.SolverRegularized)
+		}
+		sum := 0.0
+		for _, w := range d.Weights {
+			if math.IsNaN(w) || math.IsInf(w, 0) {
+				t.Errorf("target %d has weights %v", i, d.Weights)
+			}
+			sum += w
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("target %d weights sum to %v, want 1", i, sum)
+		}
+		if !(e.Variance >= 0) {
+			t.Errorf("target %d has variance %v", i, e.Variance)
+		}
+	}
+}
+
+func TestErrorModeVarianceAtDatum(t *testing.T) {
+	points := types.Points{Points: []types.Point{
+		{X: 0, Y: 0, Value: 1, ErrorVariance: 0.5},
+		{X: 2, Y: 0, Value: 2},
+		{X: 0, Y: 2, Value: 2},
+	}}
+	// the noisy datum, the error-free datum and a location between the data
+	targets := types.Points{Points: []types.Point{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 1, Y: 1}}}
+	estimate := func(mode kriging.ErrorMode) []types.Estimation {
+		kr := kriging.New(model(t, 0), 4, nil, false)
+		kr.SetErrorMode(mode)
+		kr.Fit(points)
+		estimations, err := kr.Interpolate(targets)
+		if err != nil {
+			t.Fatalf("%s: Interpolate() error = %v", mode, err)
+		}
+		return estimations
+	}
+	filtered, exact := estimate(kriging.Filtered), estimate(kriging.Exact)
+
+	// filtered estimates smooth the noisy datum, with a variance below its error
+	if f := filtered[0]; math.Abs(f.Field-1) < 0.1 || !(f.Variance > 0 && f.Variance < 0.5) {
+		t.Errorf("filtered at the noisy datum = %.4f, variance %.4f", f.Field, f.Variance)
+	}
+	// exact estimates reproduce it, without variance
+	if e := exact[0]; math.Abs(e.Field-1) > 1e-9 || math.Abs(e.Variance) > 1e-9 {
+		t.Errorf("exact at the noisy datum = %.4f, variance %.4f, want 1 and 0", e.Field, e.Variance)
+	}
+	// both modes honour error-free data and agree away from the data
+	for i := 1; i < len(targets.Points); i++ {
+		f, e := filtered[i], exact[i]
+		if math.Abs(f.Field-e.Field) > 1e-9 || math.Abs(f.Variance-e.Variance) > 1e-9 {
+			t.Errorf("target %d: filtered %.4f (%.4f), exact %.4f (%.4f)", i, f.Field, f.Variance, e.Field, e.Variance)
+		}
+	}
+	if v := filtered[1].Variance; math.Abs(v) > 1e-9 {
+		t.Errorf("variance at the error-free datum = %v, want 0", v)
+	}
+}
+
+func TestNeighborhood(t *testing.T) {
+	// around the origin, at distances 1, 2, 3, 1.6, 2.5, 4 and 6
+	flat := types.Points{Points: []types.Point{
+		{X: 1, Y: 0, Group: "a"},
+		{X: 2, Y: 0, Group: "a"},
+		{X: 3, Y: 0, Group: "a"},
+		{X: 0, Y: 1.6, Group: "b"},
+		{X: -2.5, Y: 0, Group: "b"},
+		{X: 0, Y: -4, Group: "c"},
+		{X: 6, Y: 0, Group: "c"},
+	}}
+	// around the origin in octants 0, 0, 1, 6, 7 and 4, closest first 0, 4,
+	// 5, 2, 1 and 3
+	solid := types.Points{Is3D: true, Points: []types.Point{
+		{X: 1, Y: 1, Z: 0.5},
+		{X: 2, Y: 1, Z: 1},
+		{X: -1, Y: 1, Z: 1.5},
+		{X: 1, Y: -1, Z: -2.2},
+		{X: -1, Y: -1, Z: -1},
+		{X: 1, Y: 1, Z: -1.2},
+	}}
+	// the major axis points east, distances to the north and south double
+	ellipse := &distance.AnisotropicDistance{Azimuth: 90, Ratio: 0.5}
+
+	tests := []struct {
+		name   string
+		points types.Points
+		dist   types.Distance
+		nh     kriging.Neighborhood
+		// want are the selected points, closest first, nil if the target
+		// cannot be estimated
+		want []int
+	}{
+		{"closest", flat, nil, kriging.Neighborhood{MaxPoints: 4}, []int{0, 3, 1, 4}},
+		{"radius", flat, nil, kriging.Neighborhood{MaxDistance: 2.2, MaxPoints: 4}, []int{0, 3, 1}},
+		{"ellipse", flat, ellipse, kriging.Neighborhood{MaxDistance: 2.2, MaxPoints: 4}, []int{0, 1}},
+		{"max per group", flat, nil, kriging.Neighborhood{MaxPoints: 4, MaxPerGroup: 1}, []int{0, 3, 5}},
+		{"max per group in radius", flat, nil, kriging.Neighborhood{MaxDistance: 2.6, MaxPoints: 4, MaxPerGroup: 1}, []int{0, 3}},
+		{"max per group in ellipse", flat, ellipse, kriging.Neighborhood{MaxDistance: 3.5, MaxPoints: 4, MaxPerGroup: 1}, []int{0, 4}},
+		{"quadrants", flat, nil, kriging.Neighborhood{MaxPoints: 4, Sectors: 4, MaxPerSector: 1}, []int{0, 3, 4, 5}},
+		{"quadrants in radius", flat, nil, kriging.Neighborhood{MaxDistance: 3.5, MaxPoints: 4, Sectors: 4, MaxPerSector: 1}, []int{0, 3, 4}},
+		{"quadrants in ellipse", flat, ellipse, kriging.Neighborhood{MaxDistance: 3.5, MaxPoints: 4, Sectors: 4, MaxPerSector: 1}, []int{0, 4, 3}},
+		{"quadrants and groups", flat, nil, kriging.Neighborhood{MaxPoints: 4, Sectors: 4, MaxPerSector: 2, MaxPerGroup: 1}, []int{0, 3, 5}},
+		{"minimum in radius", flat, nil, kriging.Neighborhood{MaxDistance: 2.2, MinPoints: 3, MaxPoints: 4}, []int{0, 3, 1}},
+		{"minimum not met in radius", flat, nil, kriging.Neighborhood{MaxDistance: 1.2, MinPoints: 2, MaxPoints: 4}, nil},
+		{"minimum not met in quadrants", flat, nil, kriging.Neighborhood{MaxDistance: 2.2, MinPoints: 3, MaxPoints: 4, Sectors: 4, MaxPerSector: 1}, nil},
+		{"minimum not met per group", flat, nil, kriging.Neighborhood{MinPoints: 4, MaxPoints: 4, MaxPerGroup: 1}, nil},
+		{"octants", solid, nil, kriging.Neighborhood{MaxPoints: 6, Sectors: 8, MaxPerSector: 1}, []int{0, 4, 5, 2, 3}},
+		{"octants in radius", solid, nil, kriging.Neighborhood{MaxDistance: 2.1, MaxPoints: 6, Sectors: 8, MaxPerSector: 1}, []int{0, 4, 5, 2}},
+		{"quadrants ignore depth", solid, nil, kriging.Neighborhood{MaxPoints: 6, Sectors: 4, MaxPerSector: 1}, []int{0, 4, 2, 3}},
+		{"minimum in 3D radius", solid, nil, kriging.Neighborhood{MaxDistance: 2.5, MinPoints: 5, MaxPoints: 6}, []int{0, 4, 5, 2, 1}},
+		{"minimum not met in octants", solid, nil, kriging.Neighborhood{MaxDistance: 2.5, MinPoints: 5, MaxPoints: 6, Sectors: 8, MaxPerSector: 1}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := types.Points{Is3D: tt.points.Is3D}
+			for i, p := range tt.points.Points {
+				p.Value, p.Is3D = float64(i), tt.points.Is3D
+				points.Points = append(points.Points, p)
+			}
+			kr := kriging.New(model(t, 0), 0, tt.dist, false)
+			kr.SetNeighborhood(tt.nh)
+			kr.SetDiagnostics(true)
+			kr.Fit(points)
+			target := types.Points{Is3D: points.Is3D, Points: []types.Point{{Is3D: points.Is3D}}}
+			estimations, _ := kr.Interpolate(target)
+
+			e := estimations[0]
+			if tt.want == nil {
+				if e.ErrCode != types.ErrNoConditionPoints {
+					t.Errorf("got %v with neighbors %v, want %v", e.ErrCode, e.Diagnostics.Neighbors, types.ErrNoConditionPoints)
+				}
+				return
+			}
+			if e.ErrCode != types.ErrNone {
+				t.Fatalf("got %v, want neighbors %v", e.ErrCode, tt.want)
+			}
+			if !slices.Equal(e.Diagnostics.Neighbors, tt.want) {
+				t.Errorf("got neighbors %v, want %v", e.Diagnostics.Neighbors, tt.want)
+			}
+		})
+	}
+}
+
+func TestDenseGridOrder(t *testing.T) {
+	points := types.Points{Points: []types.Point{{X: 0, Y: 0}, {X: 2, Y: 1}}}
+	nodes, err := kriging.DenseGrid(points, 1, 1, 0)
+	if err != nil {
+		t.Fatalf("DenseGrid() error = %v", err)
+	}
+	// x varies slowest, y runs from south to north
+	want := [][2]float64{{0, 0}, {0, 1}, {1, 0}, {1, 1}, {2, 0}, {2, 1}}
+	if len(nodes.Points) != len(want) {
+		t.Fatalf("got %d nodes, want %d", len(nodes.Points), len(want))
+	}
+	for i, w := range want {
+		if p := nodes.Points[i]; p.X != w[0] || p.Y != w[1] {
+			t.Errorf("node %d = (%v, %v), want %v", i, p.X, p.Y, w)
+		}
+	}
+}
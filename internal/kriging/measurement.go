@@ -0,0 +1,23 @@
+package kriging
+
+// ErrorMode selects how measurement errors of the condition points enter the
+// estimate. The error variances are read from Point.ErrorVariance and added
+// to the covariance on the diagonal of the kriging matrix.
+type ErrorMode int
+
+const (
+	// Filtered estimates the error-free signal. Estimates are smoothed and do
+	// not honour noisy data at their locations.
+	Filtered ErrorMode = iota
+	// Exact reproduces the measurements. Estimates honour noisy data at their
+	// locations with a variance of zero there, like error-free data. Away
+	// from the data, estimates equal those of Filtered.
+	Exact
+)
+
+func (m ErrorMode) String() string {
+	if m == Exact {
+		return "exact"
+	}
+	return "filtered"
+}
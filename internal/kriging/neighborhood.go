@@ -0,0 +1,151 @@
+package kriging
+
+import (
+	"math"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// Neighborhood configures the search for condition points around a target.
+//
+// Distances are measured with the distance of the kriging instance, thus an
+// AnisotropicDistance turns the search radius into a search ellipse(oid) and
+// aligns the sectors with the anisotropy axes.
+type Neighborhood struct {
+	// MaxDistance is the search radius, +Inf for no limit.
+	MaxDistance float64
+	// MinPoints is the minimum number of condition points. Targets with
+	// fewer neighbors are not estimated.
+	MinPoints int
+	// MaxPoints is the maximum number of condition points, <= 0 for no limit.
+	MaxPoints int
+	// Sectors splits the search into 4 quadrants or 8 octants around the
+	// target. Any other value disables sector search.
+	Sectors int
+	// MaxPerSector limits the number of condition points per sector.
+	MaxPerSector int
+	// MaxPerGroup limits the number of condition points sharing the same
+	// Point.Group, e.g. samples from one drillhole.
+	MaxPerGroup int
+}
+
+// DefaultNeighborhood returns an unlimited search for the maxPoints closest
+// condition points.
+func DefaultNeighborhood(maxPoints int) Neighborhood {
+	return Neighborhood{
+		MaxDistance: math.Inf(1),
+		MinPoints:   1,
+		MaxPoints:   maxPoints,
+	}
+}
+
+func (n Neighborhood) useSectors() bool {
+	return (n.Sectors == 4 || n.Sectors == 8) && n.MaxPerSector > 0
+}
+
+func (n Neighborhood) isRestricted() bool {
+	return n.useSectors() || n.MaxPerGroup > 0
+}
+
+// sector returns the quadrant or octant of offset within the search space.
+// In 2D, octants are 45 degree wedges; in 3D they are split by the sign of
+// each axis, and quadrants ignore the vertical axis.
+func sector(offset [3]float64, sectors int, is3D bool) int {
+	if is3D && sectors == 8 {
+		s := 0
+		for d := 0; d < 3; d++ {
+			if offset[d] < 0 {
+				s |= 1 << d
+			}
+		}
+		return s
+	}
+	angle := math.Atan2(offset[1], offset[0])
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
+	s := int(angle / (2 * math.Pi / float64(sectors)))
+	if s >= sectors {
+		s = sectors - 1
+	}
+	return s
+}
+
+// neighbors searches the condition points for target p. It returns the
+// selected neighbors closest first, or ErrNoConditionPoints if the
+// neighborhood minimum cannot be met.
+func (k *OrdinaryKriging) neighbors(p types.Point) ([]neighbor, types.EstimationError) {
+	nh := k.params.Neighborhood
+	total := k.index.len()
+
+	// sector and group limits may reject close points, so the k-nearest query
+	// is widened until enough points are accepted or all have been seen
+	n := nh.MaxPoints
+	if nh.isRestricted() && n > 0 {
+		n *= 4
+	}
+
+	var selected []neighbor
+	for {
+		indices, dists := k.index.nearest(&p, n, nh.MaxDistance)
+		selected = k.selectNeighbors(p, indices, dists)
+		exhausted := n <= 0 || len(indices) < n || n >= total
+		if !nh.isRestricted() || len(selected) >= nh.MaxPoints || exhausted {
+			break
+		}
+		n *= 2
+	}
+
+	if len(selected) == 0 || len(selected) < nh.MinPoints {
+		return nil, types.ErrNoConditionPoints
+	}
+	return selected, types.ErrNone
+}
+
+// selectNeighbors applies the sector and group limits to the candidates,
+// which are sorted by distance.
+func (k *OrdinaryKriging) selectNeighbors(p types.Point, indices []int, dists []float64) []neighbor {
+	nh := k.params.Neighborhood
+	limit := len(indices)
+	if nh.MaxPoints > 0 && nh.MaxPoints < limit {
+		limit = nh.MaxPoints
+	}
+	selected := make([]neighbor, 0, limit)
+
+	var perSector []int
+	var target searchPoint
+	if nh.useSectors() {
+		perSector = make([]int, nh.Sectors)
+		target = k.index.searchPoint(&p)
+	}
+	var perGroup map[string]int
+	if nh.MaxPerGroup > 0 {
+		perGroup = make(map[string]int)
+	}
+
+	for i, idx := range indices {
+		if len(selected) == limit {
+			break
+		}
+		c := &k.condition.Points[idx]
+		s := -1
+		if perSector != nil {
+			s = sector(k.index.offset(target, idx), nh.Sectors, k.condition.Is3D)
+			if perSector[s] >= nh.MaxPerSector {
+				continue
+			}
+		}
+		if perGroup != nil && c.Group != "" && perGroup[c.Group] >= nh.MaxPerGroup {
+			continue
+		}
+
+		if s >= 0 {
+			perSector[s]++
+		}
+		if perGroup != nil && c.Group != "" {
+			perGroup[c.Group]++
+		}
+		selected = append(selected, neighbor{p: c, d: dists[i], idx: idx})
+	}
+	return selected
+}
@@ -1,10 +1,11 @@
 package kriging
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
-	"sort"
-	"sync"
+	"runtime"
 	"time"
 
 	"github.com/mmaelicke/go-geostat/internal/distance"
@@ -13,10 +14,9 @@ import (
 )
 
 type Params struct {
-	MaxDistance float64
-	MaxPoints   int
-	InRange     bool
-	dist        types.Distance
+	Neighborhood
+	InRange bool
+	dist    types.Distance
 }
 
 type StepProfile struct {
@@ -45,47 +45,165 @@ type OrdinaryKriging struct {
 	params    Params
 	profile   types.Profile
 	dm        *mat.Dense
-	//kd        *kdtree.Tree
-	isFitted bool
+	index     *spatialIndex
+	workers   int
+	// origIdx maps condition points to their index in the points passed to Fit
+	origIdx     []int
+	diagnostics bool
+	solver      Solver
+	duplicates  DuplicatePolicy
+	dupTol      float64
+	dups        [][]int
+	// errVar is the measurement error variance of each condition point, nil if none
+	errVar    []float64
+	errorMode ErrorMode
+	mask      types.Mask
+	drift     []string
+	isFitted  bool
+	// fitErr is the reason the last Fit failed
+	fitErr error
 }
 
+// New creates an ordinary kriging interpolator using the maxPoints closest
+// condition points. If inRange is set, the search is limited to the range
+// of sf. Use SetNeighborhood for finer control of the search.
 func New(sf types.SpatialFunction, maxPoints int, dist types.Distance, inRange bool) *OrdinaryKriging {
 	if dist == nil {
 		dist = &distance.EuclideanDistance{}
 	}
+	nh := DefaultNeighborhood(maxPoints)
+	if inRange {
+		nh.MaxDistance = sf.Range()
+	}
 	return &OrdinaryKriging{
 		sf: sf,
 		params: Params{
-			MaxDistance: math.Inf(1),
-			MaxPoints:   maxPoints,
-			InRange:     inRange,
-			dist:        dist,
+			Neighborhood: nh,
+			InRange:      inRange,
+			dist:         dist,
 		},
+		workers:  runtime.NumCPU(),
+		solver:   DefaultSolver(),
 		isFitted: false,
 	}
 }
 
+// SetMaxDistance limits the neighbour search to condition points within d of
+// the target. Use math.Inf(1) to disable the limit.
+func (k *OrdinaryKriging) SetMaxDistance(d float64) {
+	k.params.MaxDistance = d
+}
+
+// SetWorkers sets the number of goroutines kriging targets concurrently.
+// Values below one fall back to the number of CPUs.
+func (k *OrdinaryKriging) SetWorkers(n int) {
+	if n < 1 {
+		n = runtime.NumCPU()
+	}
+	k.workers = n
+}
+
+// SetDiagnostics enables the Diagnostics of each estimation. Neighbor
+// indices refer to the points passed to Fit.
+func (k *OrdinaryKriging) SetDiagnostics(on bool) {
+	k.diagnostics = on
+}
+
+// SetSolver replaces the solver of the kriging systems.
+func (k *OrdinaryKriging) SetSolver(s Solver) {
+	k.solver = s
+}
+
+// SetDuplicates sets how Fit handles condition points within tol of each
+// other. A tol of 0 only matches identical coordinates.
+func (k *OrdinaryKriging) SetDuplicates(policy DuplicatePolicy, tol float64) {
+	k.duplicates = policy
+	k.dupTol = tol
+}
+
+// SetErrorMode sets whether points with a measurement error are filtered
+// or honoured exactly. It has no effect on points without ErrorVariance.
+func (k *OrdinaryKriging) SetErrorMode(mode ErrorMode) {
+	k.errorMode = mode
+}
+
+// SetMask restricts estimation to the locations contained in m. Other
+// locations are estimated as NaN with ErrMasked. A nil mask estimates all
+// locations.
+func (k *OrdinaryKriging) SetMask(m types.Mask) {
+	k.mask = m
+}
+
+// Duplicates returns the groups of duplicate condition points found by Fit,
+// as indices into the points passed to Fit.
+func (k *OrdinaryKriging) Duplicates() [][]int {
+	return k.dups
+}
+
+// SetNeighborhood replaces the search neighborhood.
+func (k *OrdinaryKriging) SetNeighborhood(nh Neighborhood) {
+	if nh.MaxDistance == 0 {
+		nh.MaxDistance = math.Inf(1)
+	}
+	k.params.Neighborhood = nh
+}
+
+// Neighborhood returns the search neighborhood.
+func (k *OrdinaryKriging) Neighborhood() Neighborhood {
+	return k.params.Neighborhood
+}
+
 func (k *OrdinaryKriging) SetDM(dm *mat.Dense) {
 	k.dm = dm
 	k.isFitted = true
+	k.fitErr = nil
+}
+
+// fitted returns why the interpolator cannot estimate yet, or nil
+func (k *OrdinaryKriging) fitted() error {
+	if k.fitErr != nil {
+		return k.fitErr
+	}
+	if !k.isFitted {
+		return fmt.Errorf("kriging model not fitted")
+	}
+	return nil
 }
 
 func (k *OrdinaryKriging) Fit(condition types.Points) {
 	// Filter out NaN values from condition points
 	validPoints := make([]types.Point, 0, len(condition.Points))
-	for _, p := range condition.Points {
-		if !math.IsNaN(p.Value) {
+	k.origIdx = make([]int, 0, len(condition.Points))
+	for i, p := range condition.Points {
+		if !math.IsNaN(p.Value) && k.hasCovariates(&p) {
 			validPoints = append(validPoints, p)
+			k.origIdx = append(k.origIdx, i)
 		}
 	}
 
+	nugget := k.sf.Nugget()
+	dups := findDuplicates(validPoints, k.dupTol, condition.Is3D)
+	k.dups = make([][]int, len(dups))
+	for i, g := range dups {
+		k.dups[i] = make([]int, len(g))
+		for j, idx := range g {
+			k.dups[i][j] = k.origIdx[idx]
+		}
+	}
+	k.isFitted, k.fitErr = false, nil
+	if len(dups) > 0 && k.duplicates == DuplicatesError {
+		k.fitErr = fmt.Errorf("%d duplicate locations, e.g. points %v", len(dups), k.dups[0])
+		return
+	}
+	floor := math.Max(k.solver.Ridge*(k.sf.Sill()+nugget), 1e-10)
+	validPoints, k.origIdx, k.errVar = mergeDuplicates(validPoints, k.origIdx, dups, k.duplicates, floor)
+
 	k.condition = types.Points{
 		Points: validPoints,
 		Is3D:   condition.Is3D,
 	}
 	n := len(validPoints)
 	dist := k.params.dist
-	nugget := k.sf.Nugget()
 	prof := k.sf.Profile()
 	k.params.dist.Set3D(condition.Is3D)
 	if nugget == 0.0 {
@@ -98,7 +216,13 @@ func (k *OrdinaryKriging) Fit(condition types.Points) {
 	for i := range validPoints {
 		for j := range validPoints {
 			if i == j {
-				dm.Set(i, j, nugget)
+				// measurement error adds to the covariance, thus it is
+				// subtracted from the semi-variance
+				if k.errVar != nil {
+					dm.Set(i, j, nugget-k.errVar[i])
+				} else {
+					dm.Set(i, j, nugget)
+				}
 			} else {
 				v := k.sf.Evaluate(dist.Compute(&validPoints[i], &validPoints[j]))
 				dm.Set(i, j, v)
@@ -106,6 +230,7 @@ func (k *OrdinaryKriging) Fit(condition types.Points) {
 		}
 	}
 	k.dm = dm
+	k.index = newSpatialIndex(validPoints, dist, condition.Is3D)
 	prof.FitTime = time.Since(start)
 	k.profile = prof
 	k.isFitted = true
@@ -115,123 +240,76 @@ func (k *OrdinaryKriging) Profile() types.Profile {
 	return k.profile
 }
 
+// Interpolate estimates all points of p. If some points cannot be estimated,
+// the estimations are returned together with a *types.EstimationErrors.
 func (k *OrdinaryKriging) Interpolate(p types.Points) ([]types.Estimation, error) {
-	if !k.isFitted {
-		return []types.Estimation{}, fmt.Errorf("kriging model not fitted")
-	}
-
-	results := make(chan krigResult, len(p.Points))
-
-	wg := sync.WaitGroup{}
-	for i, c := range p.Points {
-		wg.Add(1)
-		go func(i int, c types.Point) {
-			defer wg.Done()
+	return k.InterpolateContext(context.Background(), p)
+}
 
-			est, prof, err := k.krige(c)
-			results <- krigResult{
-				Index:      i,
-				Estimation: est,
-				Profile:    prof,
-				Err:        err,
-			}
-		}(i, c)
+// InterpolateContext estimates all points of p like Interpolate, but stops
+// early and returns the context error once ctx is cancelled.
+func (k *OrdinaryKriging) InterpolateContext(ctx context.Context, p types.Points) ([]types.Estimation, error) {
+	if err := k.fitted(); err != nil {
+		return []types.Estimation{}, err
 	}
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
 
 	estimations := make([]types.Estimation, len(p.Points))
-	initSum := time.Duration(0)
-	matSum := time.Duration(0)
-	solvSum := time.Duration(0)
-	totalSum := time.Duration(0)
-	n := 0
-
-	for r := range results {
-		if r.Err != nil || r.Estimation.ErrCode != types.ErrNone {
-			estimations[r.Index] = types.Estimation{
-				Field:    math.NaN(),
-				Variance: math.NaN(),
-			}
-		} else {
-			estimations[r.Index] = r.Estimation
-			initSum += r.Profile.InitTime
-			matSum += r.Profile.MatTime
-			solvSum += r.Profile.SolvTime
-			totalSum += r.Profile.TotalTime
-			n++
-		}
-	}
-	if n == 0 {
-		n = 1
+	err := k.InterpolateFunc(ctx, p, func(i int, e types.Estimation) error {
+		estimations[i] = e
+		return nil
+	})
+	var failures *types.EstimationErrors
+	if err != nil && !errors.As(err, &failures) {
+		return nil, err
 	}
-	k.profile.KInitMeanTime = initSum / time.Duration(n)
-	k.profile.KMatMeanTime = matSum / time.Duration(n)
-	k.profile.KSolvMeanTime = solvSum / time.Duration(n)
-	k.profile.KTotalMeanTime = totalSum / time.Duration(n)
-	return estimations, nil
+	return estimations, err
 }
 
 func (k *OrdinaryKriging) krige(p types.Point) (types.Estimation, StepProfile, error) {
-	if !k.isFitted {
-		return types.Estimation{}, StepProfile{}, fmt.Errorf("kriging model not fitted")
+	if err := k.fitted(); err != nil {
+		return types.Estimation{}, StepProfile{}, err
 	}
 	prof := StepProfile{}
-	maxp := k.params.MaxPoints
 
 	start := time.Now()
 	startTotal := start
 
-	allNeighbors := make([]neighbor, len(k.condition.Points))
-	for i, c := range k.condition.Points {
-		d := k.params.dist.Compute(&c, &p)
-		allNeighbors[i] = neighbor{
-			p:   &k.condition.Points[i],
-			d:   d,
-			idx: i,
+	neighbors, code := k.neighbors(p)
+	if code != types.ErrNone {
+		return types.Estimation{ErrCode: code}, StepProfile{}, ErrInterpolation{
+			Point:  p,
+			Code:   code,
+			Reason: "not enough condition points in the search neighborhood",
 		}
 	}
-
-	sort.Slice(allNeighbors, func(i, j int) bool {
-		return allNeighbors[i].d < allNeighbors[j].d
-	})
-
-	// Check if we have enough neighbors
-	if len(allNeighbors) == 0 {
-		return types.Estimation{ErrCode: types.ErrNoConditionPoints}, StepProfile{}, nil
-	}
-
-	// Adjust maxp if we don't have enough neighbors
-	if maxp > len(allNeighbors) {
-		maxp = len(allNeighbors)
-	}
-
-	neighbors := allNeighbors[:maxp]
-
-	// Only check range if we have more points than maxp
-	if k.params.InRange && len(allNeighbors) > maxp && allNeighbors[maxp].d < k.sf.Range() {
-		return types.Estimation{ErrCode: types.ErrNoConditionPoints}, StepProfile{}, nil
+	maxp := len(neighbors)
+	if !k.hasCovariates(&p) {
+		return types.Estimation{ErrCode: types.ErrMissingCovariate}, StepProfile{}, ErrInterpolation{
+			Point:  p,
+			Code:   types.ErrMissingCovariate,
+			Reason: "target lacks a drift covariate",
+		}
 	}
 
 	prof.InitTime = time.Since(start)
 
+	// the system holds one row per neighbor, the unbiasedness constraint and
+	// one constraint per drift covariate
 	start = time.Now()
-	aData := make([]float64, (maxp+1)*(maxp+1))
-	bData := make([]float64, maxp+1)
-
-	for i := 0; i < maxp+1; i++ {
-		for j := 0; j < maxp+1; j++ {
-			if i == maxp || j == maxp {
-				if i == maxp && j == maxp {
-					aData[i*(maxp+1)+j] = 0
-				} else {
-					aData[i*(maxp+1)+j] = 1
-				}
-			} else {
-				aData[i*(maxp+1)+j] = k.dm.At(neighbors[i].idx, neighbors[j].idx)
-			}
+	size := maxp + 1 + len(k.drift)
+	aData := make([]float64, size*size)
+	bData := make([]float64, size)
+
+	for i := 0; i < maxp; i++ {
+		for j := 0; j < maxp; j++ {
+			aData[i*size+j] = k.dm.At(neighbors[i].idx, neighbors[j].idx)
+		}
+		aData[i*size+maxp] = 1
+		aData[maxp*size+i] = 1
+		for d, name := range k.drift {
+			v := neighbors[i].p.Covariates[name]
+			aData[i*size+maxp+1+d] = v
+			aData[(maxp+1+d)*size+i] = v
 		}
 	}
 
@@ -240,17 +318,41 @@ func (k *OrdinaryKriging) krige(p types.Point) (types.Estimation, StepProfile, e
 		bData[i] = v
 	}
 	bData[maxp] = 1
+	for d, name := range k.drift {
+		bData[maxp+1+d] = p.Covariates[name]
+	}
+
+	// an exact estimate at a noisy datum reproduces its measurement: the datum
+	// gets all the weight, and its error variance, subtracted on the diagonal,
+	// is added back so that the variance is zero
+	targetErr := 0.0
+	if k.errorMode == Exact && k.errVar != nil {
+		for i := range neighbors {
+			if neighbors[i].d == 0 {
+				if targetErr == 0 {
+					targetErr = k.errVar[neighbors[i].idx]
+				}
+				bData[i] = k.dm.At(neighbors[i].idx, neighbors[i].idx)
+			}
+		}
+	}
 	prof.MatTime = time.Since(start)
 
 	start = time.Now()
-	A := mat.NewDense(maxp+1, maxp+1, aData)
-	b := mat.NewVecDense(maxp+1, bData)
+	A := mat.NewDense(size, size, aData)
+	b := mat.NewVecDense(size, bData)
 
-	var L mat.VecDense
-	err := L.SolveVec(A, b)
+	sol, err := k.solver.solve(A, b, maxp, k.sf.Sill()+k.sf.Nugget())
 	if err != nil {
-		return types.Estimation{ErrCode: types.ErrSingularMatrix}, StepProfile{}, fmt.Errorf("error solving linear system: %v", err)
+		return types.Estimation{ErrCode: types.ErrSingularMatrix}, StepProfile{}, ErrInterpolation{
+			Point:  p,
+			Code:   types.ErrSingularMatrix,
+			Size:   size,
+			Reason: "error solving linear system",
+			Err:    err,
+		}
 	}
+	L := sol.weights
 	prof.SolvTime = time.Since(start)
 
 	field := 0.0
@@ -258,18 +360,51 @@ func (k *OrdinaryKriging) krige(p types.Point) (types.Estimation, StepProfile, e
 		field += L.AtVec(i) * neighbors[i].p.Value
 	}
 
-	// Calculate error variance for Ordinary Kriging
+	// Calculate error variance, which includes the Lagrange multipliers of
+	// the unbiasedness and drift constraints
 	variance := 0.0
-	for i := range neighbors {
+	for i := range bData {
 		variance += L.AtVec(i) * bData[i]
 	}
-	variance += L.AtVec(maxp)
+	variance += targetErr
 
 	estimation := types.Estimation{
 		Field:    field,
 		Variance: variance,
 		ErrCode:  types.ErrNone,
 	}
+	if k.diagnostics {
+		estimation.Diagnostics = k.diagnose(neighbors, sol, variance)
+	}
 	prof.TotalTime = time.Since(startTotal)
 	return estimation, prof, nil
 }
+
+// diagnose collects the diagnostics of a solved kriging system.
+func (k *OrdinaryKriging) diagnose(neighbors []neighbor, sol solution, variance float64) *types.Diagnostics {
+	n := len(neighbors)
+	L := sol.weights
+	d := &types.Diagnostics{
+		Solver:          sol.path,
+		ConditionNumber: sol.condition,
+		Neighbors:       make([]int, n),
+		Weights:         make([]float64, n),
+		Lagrange:        L.AtVec(n),
+		NumNeighbors:    n,
+		NearestDistance: neighbors[0].d,
+	}
+	for i, nb := range neighbors {
+		d.Neighbors[i] = k.origIdx[nb.idx]
+		d.Weights[i] = L.AtVec(i)
+		if d.Weights[i] < 0 {
+			d.NegativeWeightSum += d.Weights[i]
+		}
+	}
+
+	// for point support, the block variance is the total sill
+	bv := k.sf.Sill() + k.sf.Nugget()
+	mu := math.Abs(d.Lagrange)
+	d.SlopeOfRegression = (bv - variance + mu) / (bv - variance + 2*mu)
+	d.KrigingEfficiency = (bv - variance) / bv
+	return d
+}
@@ -0,0 +1,155 @@
+package kriging
+
+import (
+	"math"
+
+	"github.com/mmaelicke/go-geostat/internal/distance"
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"gonum.org/v1/gonum/spatial/kdtree"
+)
+
+// searchPoint is a condition point located in search space, that is after
+// any anisotropy transform of the configured distance has been applied.
+type searchPoint struct {
+	c      [3]float64
+	dims   int
+	idx    int
+	metric types.Distance
+}
+
+func (p searchPoint) Compare(c kdtree.Comparable, d kdtree.Dim) float64 {
+	return p.c[d] - c.(searchPoint).c[d]
+}
+
+func (p searchPoint) Dims() int {
+	return p.dims
+}
+
+// Distance returns the squared metric distance. The tree prunes by the squared
+// per-axis offset, which is a lower bound for any Minkowski metric.
+func (p searchPoint) Distance(c kdtree.Comparable) float64 {
+	q := c.(searchPoint)
+	a := types.Point{X: p.c[0], Y: p.c[1], Z: p.c[2]}
+	b := types.Point{X: q.c[0], Y: q.c[1], Z: q.c[2]}
+	d := p.metric.Compute(&a, &b)
+	return d * d
+}
+
+type searchPoints []searchPoint
+
+func (p searchPoints) Index(i int) kdtree.Comparable { return p[i] }
+func (p searchPoints) Len() int                      { return len(p) }
+func (p searchPoints) Slice(start, end int) kdtree.Interface {
+	return p[start:end]
+}
+func (p searchPoints) Pivot(d kdtree.Dim) int {
+	pl := plane{searchPoints: p, dim: d}
+	return kdtree.Partition(pl, kdtree.MedianOfRandoms(pl, 100))
+}
+
+// plane sorts search points along a single dimension for pivoting.
+type plane struct {
+	searchPoints
+	dim kdtree.Dim
+}
+
+func (p plane) Less(i, j int) bool {
+	return p.searchPoints[i].c[p.dim] < p.searchPoints[j].c[p.dim]
+}
+func (p plane) Swap(i, j int) {
+	p.searchPoints[i], p.searchPoints[j] = p.searchPoints[j], p.searchPoints[i]
+}
+func (p plane) Slice(start, end int) kdtree.SortSlicer {
+	p.searchPoints = p.searchPoints[start:end]
+	return p
+}
+
+// spatialIndex is a k-d tree over the condition points, honouring the
+// distance metric and anisotropy of the kriging instance.
+type spatialIndex struct {
+	tree   *kdtree.Tree
+	points searchPoints
+	dist   types.Distance
+	metric types.Distance
+	dims   int
+}
+
+func newSpatialIndex(points []types.Point, dist types.Distance, is3D bool) *spatialIndex {
+	s := &spatialIndex{dist: dist, metric: dist, dims: 2}
+	if t, ok := dist.(distance.Transformer); ok {
+		s.metric = t.Base()
+	}
+	if is3D {
+		s.dims = 3
+	}
+
+	sp := make(searchPoints, len(points))
+	for i := range points {
+		sp[i] = s.searchPoint(&points[i])
+		sp[i].idx = i
+	}
+	// the tree reorders its input, keep the points by condition index
+	s.points = make(searchPoints, len(sp))
+	copy(s.points, sp)
+	s.tree = kdtree.New(sp, false)
+	return s
+}
+
+func (s *spatialIndex) len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.points)
+}
+
+// offset returns the search space vector from target to condition point idx.
+func (s *spatialIndex) offset(target searchPoint, idx int) [3]float64 {
+	c := s.points[idx].c
+	return [3]float64{c[0] - target.c[0], c[1] - target.c[1], c[2] - target.c[2]}
+}
+
+func (s *spatialIndex) searchPoint(p *types.Point) searchPoint {
+	t := *p
+	if tr, ok := s.dist.(distance.Transformer); ok {
+		t = tr.Transform(p)
+	}
+	return searchPoint{
+		c:      [3]float64{t.X, t.Y, t.Z},
+		dims:   s.dims,
+		idx:    -1,
+		metric: s.metric,
+	}
+}
+
+// nearest returns the indices and distances of at most n condition points
+// within radius of p, closest first. Pass n <= 0 for no count limit and an
+// infinite radius for no distance limit.
+func (s *spatialIndex) nearest(p *types.Point, n int, radius float64) ([]int, []float64) {
+	if s == nil || s.tree == nil || s.tree.Len() == 0 {
+		return nil, nil
+	}
+
+	q := s.searchPoint(p)
+	var found kdtree.Heap
+	if n > 0 {
+		keeper := kdtree.NewNKeeper(n)
+		keeper.Heap[0].Dist = radius * radius
+		s.tree.NearestSet(keeper, q)
+		found = keeper.Heap
+	} else {
+		keeper := kdtree.NewDistKeeper(radius * radius)
+		s.tree.NearestSet(keeper, q)
+		found = keeper.Heap
+	}
+
+	indices := make([]int, 0, len(found))
+	dists := make([]float64, 0, len(found))
+	for _, c := range found {
+		if c.Comparable == nil {
+			continue
+		}
+		indices = append(indices, c.Comparable.(searchPoint).idx)
+		dists = append(dists, math.Sqrt(c.Dist))
+	}
+	return indices, dists
+}
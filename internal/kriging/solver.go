@@ -0,0 +1,107 @@
+package kriging
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Solver paths recorded in the diagnostics of an estimation.
+const (
+	SolverDirect        = "direct"
+	SolverRegularized   = "regularized"
+	SolverPseudoInverse = "pseudo-inverse"
+)
+
+// Solver controls how kriging systems are solved. Systems with a condition
+// number above MaxCondition are regularized by subtracting Ridge times the
+// total sill from the semivariance diagonal, which adds it to the diagonal of
+// the covariances sill - γ like a small nugget. If that is not enough, they
+// are solved with the SVD based pseudo-inverse.
+type Solver struct {
+	// MaxCondition is the largest condition number solved directly
+	MaxCondition float64
+	// Ridge is the regularization, relative to the total sill
+	Ridge float64
+	// Fallback enables the regularized and pseudo-inverse solvers. Without
+	// it, ill-conditioned systems fail with ErrSingularMatrix.
+	Fallback bool
+}
+
+// DefaultSolver returns the solver used by New.
+func DefaultSolver() Solver {
+	return Solver{
+		MaxCondition: 1e12,
+		Ridge:        1e-6,
+		Fallback:     true,
+	}
+}
+
+// solution of a kriging system
+type solution struct {
+	weights   *mat.VecDense
+	path      string
+	condition float64
+}
+
+// solve solves A x = b for a kriging system with n condition points. The
+// Lagrange row and column are never regularized.
+func (s Solver) solve(A *mat.Dense, b *mat.VecDense, n int, sill float64) (solution, error) {
+	size, _ := A.Dims()
+	maxCond := s.MaxCondition
+	if maxCond <= 0 {
+		maxCond = DefaultSolver().MaxCondition
+	}
+
+	var lu mat.LU
+	lu.Factorize(A)
+	cond := lu.Cond()
+	if cond <= maxCond {
+		var x mat.VecDense
+		if err := lu.SolveVecTo(&x, false, b); err == nil {
+			return solution{weights: &x, path: SolverDirect, condition: cond}, nil
+		}
+	}
+	if !s.Fallback {
+		return solution{}, ErrSingularMatrix{
+			Size:   size,
+			Reason: fmt.Sprintf("condition number %.3g exceeds %.3g", cond, maxCond),
+		}
+	}
+
+	if s.Ridge > 0 {
+		var R mat.Dense
+		R.CloneFrom(A)
+		// Tikhonov regularization of the covariances
+		ridge := s.Ridge * sill
+		for i := 0; i < n; i++ {
+			R.Set(i, i, R.At(i, i)-ridge)
+		}
+		var rlu mat.LU
+		rlu.Factorize(&R)
+		if rcond := rlu.Cond(); rcond <= maxCond {
+			var x mat.VecDense
+			if err := rlu.SolveVecTo(&x, false, b); err == nil {
+				return solution{weights: &x, path: SolverRegularized, condition: cond}, nil
+			}
+		}
+	}
+
+	var svd mat.SVD
+	if !svd.Factorize(A, mat.SVDThin) {
+		return solution{}, ErrSingularMatrix{Size: size, Reason: "singular value decomposition failed"}
+	}
+	rank := svd.Rank(1 / maxCond)
+	if rank < 1 {
+		return solution{}, ErrSingularMatrix{Size: size, Reason: "matrix has rank 0"}
+	}
+	var x mat.VecDense
+	svd.SolveVecTo(&x, b, rank)
+	for i := 0; i < size; i++ {
+		if math.IsNaN(x.AtVec(i)) {
+			return solution{}, ErrSingularMatrix{Size: size, Reason: "pseudo-inverse solution is not finite"}
+		}
+	}
+	return solution{weights: &x, path: SolverPseudoInverse, condition: cond}, nil
+}
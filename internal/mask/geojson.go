@@ -0,0 +1,106 @@
+package mask
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// geoJSON holds the members of any GeoJSON object needed to find polygons
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Geometries  []geoJSON       `json:"geometries"`
+	Features    []geoJSON       `json:"features"`
+}
+
+// ReadGeoJSON reads all Polygon and MultiPolygon geometries of a GeoJSON
+// geometry, Feature or FeatureCollection. Other geometries are ignored.
+func ReadGeoJSON(r io.Reader) (Polygons, error) {
+	var obj geoJSON
+	if err := json.NewDecoder(r).Decode(&obj); err != nil {
+		return nil, fmt.Errorf("failed to decode GeoJSON: %w", err)
+	}
+	polys := Polygons{}
+	if err := obj.collect(&polys); err != nil {
+		return nil, err
+	}
+	if len(polys) == 0 {
+		return nil, fmt.Errorf("no polygons found in GeoJSON")
+	}
+	return polys, nil
+}
+
+// ReadGeoJSONFile opens path and reads it with ReadGeoJSON.
+func ReadGeoJSONFile(path string) (Polygons, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadGeoJSON(f)
+}
+
+func (g *geoJSON) collect(polys *Polygons) error {
+	switch g.Type {
+	case "FeatureCollection":
+		for i := range g.Features {
+			if err := g.Features[i].collect(polys); err != nil {
+				return err
+			}
+		}
+	case "Feature":
+		if g.Geometry != nil {
+			return g.Geometry.collect(polys)
+		}
+	case "GeometryCollection":
+		for i := range g.Geometries {
+			if err := g.Geometries[i].collect(polys); err != nil {
+				return err
+			}
+		}
+	case "Polygon":
+		var coords [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+			return fmt.Errorf("invalid Polygon coordinates: %w", err)
+		}
+		poly, err := polygonFromCoords(coords)
+		if err != nil {
+			return err
+		}
+		*polys = append(*polys, poly)
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+			return fmt.Errorf("invalid MultiPolygon coordinates: %w", err)
+		}
+		for _, c := range coords {
+			poly, err := polygonFromCoords(c)
+			if err != nil {
+				return err
+			}
+			*polys = append(*polys, poly)
+		}
+	}
+	return nil
+}
+
+func polygonFromCoords(coords [][][]float64) (Polygon, error) {
+	poly := make(Polygon, len(coords))
+	for i, c := range coords {
+		if len(c) < 3 {
+			return nil, fmt.Errorf("ring with %d positions", len(c))
+		}
+		ring := make(Ring, len(c))
+		for j, pos := range c {
+			if len(pos) < 2 {
+				return nil, fmt.Errorf("position with %d coordinates", len(pos))
+			}
+			ring[j] = Vertex{X: pos[0], Y: pos[1]}
+		}
+		poly[i] = ring
+	}
+	return poly, nil
+}
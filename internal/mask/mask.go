@@ -0,0 +1,100 @@
+// Package mask restricts estimation to a prediction domain, given either by
+// polygons or by the valid cells of a raster. All masks implement types.Mask.
+package mask
+
+import (
+	"math"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// Vertex is a corner of a polygon ring.
+type Vertex struct {
+	X, Y float64
+}
+
+// Ring is a closed sequence of vertices. The last vertex may or may not
+// repeat the first one.
+type Ring []Vertex
+
+// Polygon is an outer ring followed by any number of holes.
+type Polygon []Ring
+
+// Polygons is a set of polygons, e.g. read from a MULTIPOLYGON. It contains
+// a location if any of its polygons does.
+type Polygons []Polygon
+
+// Contains reports whether p lies inside the ring, using the even-odd rule.
+func (r Ring) Contains(p types.Point) bool {
+	inside := false
+	n := len(r)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := r[i], r[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) &&
+			p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Contains reports whether p lies inside the outer ring and outside of all
+// holes. Z coordinates are ignored.
+func (poly Polygon) Contains(p types.Point) bool {
+	if len(poly) == 0 || !poly[0].Contains(p) {
+		return false
+	}
+	for _, hole := range poly[1:] {
+		if hole.Contains(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains reports whether any of the polygons contains p.
+func (ps Polygons) Contains(p types.Point) bool {
+	for _, poly := range ps {
+		if poly.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Raster masks all locations outside of a grid or in its NaN cells, e.g. the
+// NODATA cells of an ESRI ASCII grid.
+type Raster struct {
+	spec  grid.Spec
+	valid []bool
+}
+
+// NewRaster returns a mask of the cells of spec, where values holds one value
+// per cell in the order of spec.At. Cells holding NaN are masked.
+func NewRaster(spec grid.Spec, values []float64) *Raster {
+	valid := make([]bool, len(values))
+	for i, v := range values {
+		valid[i] = !math.IsNaN(v)
+	}
+	return &Raster{spec: spec, valid: valid}
+}
+
+// Contains reports whether p lies in a valid cell of the raster.
+func (r *Raster) Contains(p types.Point) bool {
+	i, ok := r.spec.Locate(p)
+	return ok && i < len(r.valid) && r.valid[i]
+}
+
+// All contains the locations contained in every one of its masks.
+type All []types.Mask
+
+// Contains reports whether all masks contain p.
+func (a All) Contains(p types.Point) bool {
+	for _, m := range a {
+		if !m.Contains(p) {
+			return false
+		}
+	}
+	return true
+}
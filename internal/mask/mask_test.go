@@ -0,0 +1,81 @@
+package mask
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+func TestParseWKTPolygonWithHole(t *testing.T) {
+	polys, err := ParseWKT("POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (4 4, 6 4, 6 6, 4 6, 4 4))")
+	if err != nil {
+		t.Fatalf("ParseWKT() error = %v", err)
+	}
+	tests := []struct {
+		p    types.Point
+		want bool
+	}{
+		{types.Point{X: 1, Y: 1}, true},
+		{types.Point{X: 5, Y: 5}, false},
+		{types.Point{X: 11, Y: 5}, false},
+	}
+	for _, tt := range tests {
+		if got := polys.Contains(tt.p); got != tt.want {
+			t.Errorf("Contains(%v, %v) = %v, want %v", tt.p.X, tt.p.Y, got, tt.want)
+		}
+	}
+}
+
+func TestParseWKTMultiPolygonZ(t *testing.T) {
+	polys, err := ParseWKT("MULTIPOLYGON Z (((0 0 1, 1 0 1, 1 1 1, 0 0 1)), ((5 5 0, 6 5 0, 6 6 0, 5 6 0)))")
+	if err != nil {
+		t.Fatalf("ParseWKT() error = %v", err)
+	}
+	if len(polys) != 2 {
+		t.Fatalf("got %d polygons, want 2", len(polys))
+	}
+	if !polys.Contains(types.Point{X: 5.5, Y: 5.5}) {
+		t.Error("second polygon should contain (5.5, 5.5)")
+	}
+	if _, err := ParseWKT("LINESTRING (0 0, 1 1)"); err == nil {
+		t.Error("expected an error for a LINESTRING")
+	}
+	for _, wkt := range []string{"POLYGON EMPTY", "MULTIPOLYGON Z EMPTY"} {
+		if _, err := ParseWKT(wkt); err == nil || !strings.Contains(err.Error(), "empty geometry") {
+			t.Errorf("ParseWKT(%q) error = %v, want empty geometry", wkt, err)
+		}
+	}
+}
+
+func TestReadGeoJSONFeatureCollection(t *testing.T) {
+	input := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "properties": {}, "geometry": {"type": "Point", "coordinates": [0, 0]}},
+		{"type": "Feature", "properties": {}, "geometry": {"type": "Polygon",
+			"coordinates": [[[0, 0], [10, 0], [10, 10], [0, 10], [0, 0]]]}}
+	]}`
+	polys, err := ReadGeoJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadGeoJSON() error = %v", err)
+	}
+	if len(polys) != 1 || !polys.Contains(types.Point{X: 5, Y: 5}) {
+		t.Errorf("expected one polygon containing (5, 5), got %v", polys)
+	}
+}
+
+func TestRaster(t *testing.T) {
+	spec := grid.Spec{DX: 1, DY: 1, NX: 2, NY: 2, Registration: grid.CellCorner}
+	// the north-east cell is NODATA
+	m := NewRaster(spec, []float64{1, math.NaN(), 1, 1})
+	if !m.Contains(types.Point{X: 0.5, Y: 1.5}) {
+		t.Error("north-west cell should be valid")
+	}
+	if m.Contains(types.Point{X: 1.5, Y: 1.5}) {
+		t.Error("north-east cell should be masked")
+	}
+	if m.Contains(types.Point{X: 3, Y: 0}) {
+		t.Error("points outside of the raster should be masked")
+	}
+}
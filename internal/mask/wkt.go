@@ -0,0 +1,145 @@
+package mask
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseWKT parses a POLYGON or MULTIPOLYGON in well-known text. Z and M
+// coordinates are accepted and ignored. Empty geometries are an error.
+func ParseWKT(s string) (Polygons, error) {
+	p := &wktParser{s: s}
+	kind := strings.ToUpper(p.word())
+	// skip dimension qualifiers like Z, M or ZM
+	for {
+		q := strings.ToUpper(p.peekWord())
+		if q != "Z" && q != "M" && q != "ZM" {
+			break
+		}
+		p.word()
+	}
+	if kind != "POLYGON" && kind != "MULTIPOLYGON" {
+		return nil, fmt.Errorf("unsupported WKT geometry %q, need POLYGON or MULTIPOLYGON", kind)
+	}
+	// an empty mask would exclude every location
+	if strings.EqualFold(p.peekWord(), "EMPTY") {
+		return nil, fmt.Errorf("invalid WKT: empty geometry")
+	}
+
+	var polys Polygons
+	var err error
+	if kind == "POLYGON" {
+		var poly Polygon
+		poly, err = p.polygon()
+		polys = Polygons{poly}
+	} else {
+		err = p.list(func() error {
+			poly, err := p.polygon()
+			polys = append(polys, poly)
+			return err
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid WKT: %w", err)
+	}
+	return polys, nil
+}
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// peekWord returns the next word without consuming it
+func (p *wktParser) peekWord() string {
+	pos := p.pos
+	w := p.word()
+	p.pos = pos
+	return w
+}
+
+func (p *wktParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && unicode.IsLetter(rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func (p *wktParser) expect(c byte) error {
+	p.skipSpace()
+	if p.pos >= len(p.s) || p.s[p.pos] != c {
+		return fmt.Errorf("expected %q at position %d", c, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+// list parses a parenthesized, comma separated list, calling item for
+// every element.
+func (p *wktParser) list(item func() error) error {
+	if err := p.expect('('); err != nil {
+		return err
+	}
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+		p.skipSpace()
+		if p.pos < len(p.s) && p.s[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		return p.expect(')')
+	}
+}
+
+func (p *wktParser) polygon() (Polygon, error) {
+	var poly Polygon
+	err := p.list(func() error {
+		ring, err := p.ring()
+		poly = append(poly, ring)
+		return err
+	})
+	return poly, err
+}
+
+func (p *wktParser) ring() (Ring, error) {
+	var ring Ring
+	err := p.list(func() error {
+		fields := make([]float64, 0, 4)
+		for {
+			p.skipSpace()
+			start := p.pos
+			for p.pos < len(p.s) && strings.IndexByte("+-.0123456789eE", p.s[p.pos]) >= 0 {
+				p.pos++
+			}
+			if start == p.pos {
+				break
+			}
+			v, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+			if err != nil {
+				return err
+			}
+			fields = append(fields, v)
+		}
+		if len(fields) < 2 {
+			return fmt.Errorf("expected coordinates at position %d", p.pos)
+		}
+		ring = append(ring, Vertex{X: fields[0], Y: fields[1]})
+		return nil
+	})
+	if err == nil && len(ring) < 3 {
+		err = fmt.Errorf("ring with %d vertices", len(ring))
+	}
+	return ring, err
+}
@@ -46,7 +46,9 @@ The package includes several optimizations:
 
   - Neighbor selection: Only the closest points are used for kriging
   - Parallel processing: Multiple realizations are simulated concurrently
-  - Memory efficiency: Points are processed sequentially
+  - Memory efficiency: Points are processed sequentially, and SimulateTo
+    writes every realization to its sink once complete, simulating at most
+    SetWorkers realizations at a time on a lazily generated grid
 
 # Implementation Details
 
@@ -8,7 +8,7 @@ import (
 	"github.com/mmaelicke/go-geostat/internal/distance"
 	"github.com/mmaelicke/go-geostat/internal/empirical"
 	"github.com/mmaelicke/go-geostat/internal/estimator"
-	"github.com/mmaelicke/go-geostat/internal/kriging"
+	"github.com/mmaelicke/go-geostat/internal/grid"
 	"github.com/mmaelicke/go-geostat/internal/sgs"
 	"github.com/mmaelicke/go-geostat/io/csv"
 )
@@ -54,14 +54,15 @@ func Example_withPancakeData() {
 	simulator.Fit(points)
 
 	// Create a grid for simulation
-	grid, err := kriging.DenseGrid(points, 10.0, 10.0, 0)
+	spec, err := grid.FromPoints(points, 10.0, 10.0, 0)
 	if err != nil {
 		fmt.Printf("Error creating grid: %v\n", err)
 		return
 	}
+	targets := spec.Points()
 
 	// Generate 5 realizations
-	simulations, err := simulator.Simulate(grid, 5)
+	simulations, err := simulator.Simulate(targets, 5)
 	if err != nil {
 		fmt.Printf("Error simulating: %v\n", err)
 		return
@@ -76,6 +77,6 @@ func Example_withPancakeData() {
 	// Output:
 	// Simulation results:
 	// Number of realizations: 5
-	// Points per realization: 2500
+	// Points per realization: 2601
 	// Grid spacing: 10.0 x 10.0
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"runtime"
 	"sort"
 	"sync"
 	"time"
@@ -22,7 +23,9 @@ type SGS struct {
 	isFitted        bool
 	maxPoints       int
 	useNeighbors    bool
+	mask            types.Mask
 	progress        *progressTracker
+	workers         int
 }
 
 func New(sf types.SpatialFunction, maxPoints int, dist types.Distance, showProgress bool) *SGS {
@@ -44,14 +47,32 @@ func New(sf types.SpatialFunction, maxPoints int, dist types.Distance, showProgr
 		maxPoints:       maxPoints,
 		useNeighbors:    true,
 		progress:        pt,
+		workers:         runtime.NumCPU(),
 	}
 }
 
+// SetWorkers sets the number of realizations SimulateTo runs concurrently,
+// which bounds its memory use. Values below one fall back to the number of
+// CPUs.
+func (s *SGS) SetWorkers(n int) {
+	if n < 1 {
+		n = runtime.NumCPU()
+	}
+	s.workers = n
+}
+
 func (s *SGS) Fit(p types.Points) {
 	s.condition = p
 	s.isFitted = true
 }
 
+// SetMask restricts the simulation to the locations contained in m. Other
+// locations are neither simulated nor used as conditioning data, and hold
+// NaN with ErrMasked in every realization.
+func (s *SGS) SetMask(m types.Mask) {
+	s.mask = m
+}
+
 func (s *SGS) Interpolate(p types.Points) ([]types.Estimation, error) {
 	// we want the spatial interpolator interface, thus Interpolate is a simulation with n=1
 	estimations, err := s.Simulate(p, 1)
@@ -99,6 +120,70 @@ func (s *SGS) Simulate(p types.Points, n int) ([][]types.Estimation, error) {
 	return simulations, nil
 }
 
+// SimulateTo runs n realizations at the locations like Simulate, but writes
+// each realization to the sink returned by newSink as soon as it is complete,
+// instead of keeping all realizations in memory. At most SetWorkers
+// realizations are simulated at a time, and the locations are only
+// materialized for the realizations in progress, so memory grows with the
+// number of workers, not with n. Realizations are written one at a time.
+func (s *SGS) SimulateTo(locs types.Locations, n int, newSink func(sim int) (types.EstimationSink, error)) error {
+	if !s.isFitted {
+		return fmt.Errorf("the SGS needs first condition points")
+	}
+	if s.progress != nil {
+		defer s.progress.close()
+	}
+
+	errors := make([]error, n)
+	writeMux := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	jobs := make(chan int)
+
+	for range min(max(s.workers, 1), n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				simulation, err := s.sequential(locs, i)
+				if err != nil {
+					errors[i] = err
+					continue
+				}
+
+				writeMux.Lock()
+				errors[i] = write(locs, simulation, newSink, i)
+				writeMux.Unlock()
+			}
+		}()
+	}
+	for i := range n {
+		jobs <- i
+	}
+	close(jobs)
+
+	wg.Wait()
+	for i, err := range errors {
+		if err != nil {
+			return fmt.Errorf("simulation %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// write writes a realization to the sink of simulation sim
+func write(locs types.Locations, simulation []types.Estimation, newSink func(sim int) (types.EstimationSink, error), sim int) error {
+	sink, err := newSink(sim)
+	if err != nil {
+		return err
+	}
+	for j, e := range simulation {
+		if err := sink.Write(locs.At(j), e); err != nil {
+			return err
+		}
+	}
+	return sink.Flush()
+}
+
 type neighbor struct {
 	point types.Point
 	dist  float64
@@ -106,10 +191,13 @@ type neighbor struct {
 }
 
 // findClosestNeighbors pre-calculates the closest neighbors for each simulation point
-func (s *SGS) findClosestNeighbors(simPoints types.Points) [][]neighbor {
-	closestNeighbors := make([][]neighbor, len(simPoints.Points))
+func (s *SGS) findClosestNeighbors(simPoints []types.Point) [][]neighbor {
+	closestNeighbors := make([][]neighbor, len(simPoints))
 
-	for i, point := range simPoints.Points {
+	for i, point := range simPoints {
+		if s.mask != nil && !s.mask.Contains(point) {
+			continue
+		}
 		// Calculate distances to all condition points
 		neighbors := make([]neighbor, len(s.condition.Points))
 		for j, cond := range s.condition.Points {
@@ -170,13 +258,16 @@ func (s *SGS) getConditionPoints(point types.Point, neighbors []neighbor, points
 	return newCondition
 }
 
-func (s *SGS) sequential(p types.Points, routineId int) ([]types.Estimation, error) {
-	n := len(p.Points)
+func (s *SGS) sequential(locs types.Locations, routineId int) ([]types.Estimation, error) {
+	n := locs.Len()
+	is3D := locs.Dims() == 3
 	pMask := make([]bool, n)
 
-	// create a copy of the points
+	// the simulated values are stored in a copy of the locations
 	points := make([]types.Point, n)
-	copy(points, p.Points)
+	for i := range points {
+		points[i] = locs.At(i)
+	}
 
 	estimations := make([]types.Estimation, n)
 
@@ -190,12 +281,18 @@ func (s *SGS) sequential(p types.Points, routineId int) ([]types.Estimation, err
 	// Pre-calculate neighbors if optimization is enabled
 	var closestNeighbors [][]neighbor
 	if s.useNeighbors {
-		closestNeighbors = s.findClosestNeighbors(p)
+		closestNeighbors = s.findClosestNeighbors(points)
 	}
 
 	interpolator := s.newInterpolator()
 
 	for it, idx := range rand_idx {
+		if s.mask != nil && !s.mask.Contains(points[idx]) {
+			points[idx].Value = math.NaN()
+			estimations[idx] = types.Estimation{Field: math.NaN(), Variance: math.NaN(), ErrCode: types.ErrMasked}
+			continue
+		}
+
 		// Get condition points for current location
 		var neighbors []neighbor
 		if s.useNeighbors {
@@ -207,14 +304,14 @@ func (s *SGS) sequential(p types.Points, routineId int) ([]types.Estimation, err
 		fitStart := time.Now()
 
 		// Fit using the selected condition points
-		interpolator.Fit(types.Points{Points: newCondition, Is3D: p.Is3D})
+		interpolator.Fit(types.Points{Points: newCondition, Is3D: is3D})
 
 		fitTime := time.Since(fitStart)
 		totalFitTime += fitTime
 
 		loc := types.Points{
 			Points: []types.Point{points[idx]},
-			Is3D:   p.Is3D,
+			Is3D:   is3D,
 		}
 
 		// Time the Interpolate operation
@@ -232,21 +329,17 @@ func (s *SGS) sequential(p types.Points, routineId int) ([]types.Estimation, err
 			})
 		}
 
-		if err != nil {
-			// If kriging fails, set NaN and continue
-			points[idx].Value = math.NaN()
-			estimations[idx] = types.Estimation{
-				Field: math.NaN(),
+		// If kriging fails, set NaN, keep the reason and continue
+		if err != nil || est[0].ErrCode != types.ErrNone || math.IsNaN(est[0].Field) {
+			failed := types.Estimation{Field: math.NaN(), Variance: math.NaN(), Err: err}
+			if len(est) > 0 {
+				failed.ErrCode = est[0].ErrCode
+				if est[0].Err != nil {
+					failed.Err = est[0].Err
+				}
 			}
-			continue
-		}
-
-		// Check if the estimation was successful
-		if est[0].ErrCode != types.ErrNone || math.IsNaN(est[0].Field) {
 			points[idx].Value = math.NaN()
-			estimations[idx] = types.Estimation{
-				Field: math.NaN(),
-			}
+			estimations[idx] = failed
 			continue
 		}
 
@@ -0,0 +1,55 @@
+package sgs
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/mmaelicke/go-geostat/internal/distance"
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"github.com/mmaelicke/go-geostat/internal/variogram"
+)
+
+// countSink counts the estimations written to it
+type countSink struct {
+	n       *int
+	flushed *bool
+}
+
+func (c countSink) Write(p types.Point, e types.Estimation) error {
+	*c.n++
+	return nil
+}
+
+func (c countSink) Flush() error {
+	*c.flushed = true
+	return nil
+}
+
+func TestSimulateTo(t *testing.T) {
+	model, err := variogram.NewVariogram("spherical", types.BaseParams{Range: 10, Sill: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(model, 8, &distance.EuclideanDistance{}, false)
+	s.SetWorkers(2)
+	s.Fit(types.Points{Points: []types.Point{{X: 0, Y: 0, Value: 1}, {X: 5, Y: 5, Value: 2}, {X: 9, Y: 1, Value: 0}}})
+
+	spec := grid.Spec{DX: 1, DY: 1, NX: 6, NY: 4}
+	var mu sync.Mutex
+	counts := make([]int, 5)
+	flushed := make([]bool, 5)
+	err = s.SimulateTo(spec, 5, func(sim int) (types.EstimationSink, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return countSink{&counts[sim], &flushed[sim]}, nil
+	})
+	if err != nil {
+		t.Fatalf("SimulateTo() error = %v", err)
+	}
+	for i := range counts {
+		if counts[i] != spec.Len() || !flushed[i] {
+			t.Errorf("realization %d: %d values, flushed %v", i, counts[i], flushed[i])
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Package timeaxis parses timestamps of space-time data and maps them onto a
+// numeric axis, so that time can be treated like a coordinate.
+package timeaxis
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// Unix and UnixMilli are layout names for epoch seconds and milliseconds.
+// Both accept fractions.
+const (
+	Unix      = "unix"
+	UnixMilli = "unixms"
+)
+
+// DefaultLayouts are tried in order if a Parser has no layouts: ISO 8601 with
+// and without time zone, with T or space as separator, and plain dates.
+var DefaultLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Parser parses timestamps by trying its layouts in order. Layouts are Go
+// time layouts or one of Unix and UnixMilli.
+type Parser struct {
+	Layouts []string
+	// Location is used for timestamps without time zone. It defaults to UTC.
+	Location *time.Location
+}
+
+// NewParser returns a parser trying layouts, or DefaultLayouts if none are
+// given.
+func NewParser(layouts ...string) Parser {
+	if len(layouts) == 0 {
+		layouts = DefaultLayouts
+	}
+	return Parser{Layouts: layouts}
+}
+
+// Parse returns the time of s in the first layout that matches.
+func (p Parser) Parse(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	layouts := p.Layouts
+	if len(layouts) == 0 {
+		layouts = DefaultLayouts
+	}
+	loc := p.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range layouts {
+		switch strings.ToLower(layout) {
+		case Unix:
+			if t, ok := epoch(s, 1e9); ok {
+				return t, nil
+			}
+		case UnixMilli:
+			if t, ok := epoch(s, 1e6); ok {
+				return t, nil
+			}
+		default:
+			if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+				return t, nil
+			}
+		}
+	}
+	if len(layouts) == 1 {
+		return time.Time{}, fmt.Errorf("cannot parse %q as %q", s, layouts[0])
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q with any of %d layouts", s, len(layouts))
+}
+
+// epoch parses s as a number of units of nanos nanoseconds since 1970
+func epoch(s string, nanos float64) (time.Time, bool) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return time.Time{}, false
+	}
+	sec, frac := math.Modf(v * nanos / 1e9)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC(), true
+}
+
+// ParseUnit parses the unit of a time axis: ms, s, min, h, d, w, y (365.25
+// days), their long names, or any Go duration like 6h.
+func ParseUnit(s string) (time.Duration, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "ms", "millisecond", "milliseconds":
+		return time.Millisecond, nil
+	case "s", "sec", "second", "seconds":
+		return time.Second, nil
+	case "min", "minute", "minutes":
+		return time.Minute, nil
+	case "h", "hour", "hours":
+		return time.Hour, nil
+	case "d", "day", "days":
+		return 24 * time.Hour, nil
+	case "w", "week", "weeks":
+		return 7 * 24 * time.Hour, nil
+	case "y", "year", "years":
+		return 8766 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time unit %q, need ms, s, min, h, d, w, y or a duration", s)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("time unit %q must be positive", s)
+	}
+	return d, nil
+}
+
+// Axis maps times onto numbers: the time since Origin in multiples of Unit.
+type Axis struct {
+	Origin time.Time
+	Unit   time.Duration
+}
+
+// Value returns the position of t on the axis.
+func (a Axis) Value(t time.Time) float64 {
+	// split into seconds and nanoseconds, as t.Sub saturates after 292 years
+	sec := float64(t.Unix() - a.Origin.Unix())
+	nsec := float64(t.Nanosecond() - a.Origin.Nanosecond())
+	return (sec*1e9 + nsec) / float64(a.Unit)
+}
+
+// Time returns the time at position v of the axis.
+func (a Axis) Time(v float64) time.Time {
+	sec, frac := math.Modf(v * float64(a.Unit) / 1e9)
+	return a.Origin.Add(time.Duration(sec) * time.Second).Add(time.Duration(math.Round(frac * 1e9)))
+}
+
+// String describes the axis like CF time units, e.g. "3600 seconds since
+// 2020-01-01T00:00:00Z".
+func (a Axis) String() string {
+	return fmt.Sprintf("%g seconds since %s", a.Unit.Seconds(), a.Origin.Format(time.RFC3339Nano))
+}
+
+// Earliest returns the earliest time of the points, and false if none of
+// them has a time.
+func Earliest(points []types.Point) (time.Time, bool) {
+	var first time.Time
+	found := false
+	for _, p := range points {
+		if p.HasTime && (!found || p.Time.Before(first)) {
+			first, found = p.Time, true
+		}
+	}
+	return first, found
+}
+
+// SpaceTime returns a copy of the 2D points with their time on the axis as Z
+// coordinate, so that variograms and kriging treat time as third dimension.
+// The unit sets the ratio of time to space distances. It fails for 3D points
+// and for points without time.
+func (a Axis) SpaceTime(points types.Points) (types.Points, error) {
+	if points.Is3D {
+		return types.Points{}, fmt.Errorf("space-time needs 2D points, the z coordinate is taken by time")
+	}
+	if a.Unit <= 0 {
+		return types.Points{}, fmt.Errorf("time unit must be positive")
+	}
+	out := types.Points{Points: make([]types.Point, len(points.Points)), Is3D: true}
+	for i, p := range points.Points {
+		if !p.HasTime {
+			return types.Points{}, fmt.Errorf("point %d at (%g, %g) has no time", i, p.X, p.Y)
+		}
+		p.Z = a.Value(p.Time)
+		p.Is3D = true
+		out.Points[i] = p
+	}
+	return out, nil
+}
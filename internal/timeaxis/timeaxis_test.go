@@ -0,0 +1,83 @@
+package timeaxis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+func TestParse(t *testing.T) {
+	want := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	berlin := time.FixedZone("CET", 3600)
+	tests := []struct {
+		in      string
+		layouts []string
+		loc     *time.Location
+		want    time.Time
+	}{
+		{"2024-03-01T12:30:00Z", nil, nil, want},
+		{"2024-03-01T13:30:00+01:00", nil, nil, want},
+		{"2024-03-01 13:30", nil, berlin, want},
+		{"2024-03-01", nil, nil, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"1709296200", []string{Unix}, nil, want},
+		{"1709296200000", []string{UnixMilli}, nil, want},
+		{"01.03.2024 12:30", []string{"2006-01-02", "02.01.2006 15:04"}, nil, want},
+	}
+	for _, tt := range tests {
+		p := NewParser(tt.layouts...)
+		p.Location = tt.loc
+		got, err := p.Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := NewParser().Parse("yesterday"); err == nil {
+		t.Error("Parse(yesterday) should fail")
+	}
+}
+
+func TestAxis(t *testing.T) {
+	unit, err := ParseUnit("d")
+	if err != nil {
+		t.Fatalf("ParseUnit() error = %v", err)
+	}
+	origin := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := Axis{Origin: origin, Unit: unit}
+
+	later := origin.Add(36 * time.Hour)
+	if v := a.Value(later); v != 1.5 {
+		t.Errorf("Value() = %v, want 1.5", v)
+	}
+	if got := a.Time(1.5); !got.Equal(later) {
+		t.Errorf("Time(1.5) = %v, want %v", got, later)
+	}
+
+	points := types.Points{Points: []types.Point{
+		{X: 1, Y: 2, Time: later, HasTime: true},
+		{X: 3, Y: 4, Time: origin, HasTime: true},
+	}}
+	if first, ok := Earliest(points.Points); !ok || !first.Equal(origin) {
+		t.Errorf("Earliest() = %v, want %v", first, origin)
+	}
+	st, err := a.SpaceTime(points)
+	if err != nil {
+		t.Fatalf("SpaceTime() error = %v", err)
+	}
+	if !st.Is3D || st.Points[0].Z != 1.5 || points.Points[0].Z != 0 {
+		t.Errorf("SpaceTime() should set Z on a copy, got %+v", st.Points[0])
+	}
+
+	points.Points[1].HasTime = false
+	if _, err := a.SpaceTime(points); err == nil {
+		t.Error("SpaceTime() should fail for points without time")
+	}
+	if _, err := ParseUnit("fortnight"); err == nil {
+		t.Error("ParseUnit(fortnight) should fail")
+	}
+}
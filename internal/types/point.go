@@ -13,6 +13,48 @@ type Point struct {
 	Time    time.Time
 	HasTime bool
 	Is3D    bool
+	// Attributes holds all named variables measured at this location.
+	// Value is one of them, selected with Points.Variable.
+	Attributes map[string]float64
+	// Covariates holds named auxiliary variables, e.g. for drift terms.
+	Covariates map[string]float64
+	// Group identifies points sharing a source, e.g. a drillhole.
+	Group string
+	// ErrorVariance is the known measurement error variance of Value, e.g.
+	// the squared analytical uncertainty of a lab value.
+	ErrorVariance float64
+}
+
+// Attribute returns the named attribute of the point and whether the point
+// carries it at all.
+func (p *Point) Attribute(name string) (float64, bool) {
+	v, ok := p.Attributes[name]
+	return v, ok
+}
+
+// Covariate returns the named covariate of the point and whether the point
+// carries it at all.
+func (p *Point) Covariate(name string) (float64, bool) {
+	v, ok := p.Covariates[name]
+	return v, ok
+}
+
+// Variable returns a copy of the points with Value set to the named attribute.
+// Points that do not carry the attribute, or carry NaN, are dropped.
+func (p Points) Variable(name string) Points {
+	selected := make([]Point, 0, len(p.Points))
+	for _, c := range p.Points {
+		v, ok := c.Attribute(name)
+		if !ok || math.IsNaN(v) {
+			continue
+		}
+		c.Value = v
+		selected = append(selected, c)
+	}
+	return Points{
+		Points: selected,
+		Is3D:   p.Is3D,
+	}
 }
 
 // implement the Comparable interface from gonum
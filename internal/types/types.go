@@ -1,6 +1,9 @@
 package types
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -77,10 +80,178 @@ const (
 	ErrNone EstimationError = iota
 	ErrNoConditionPoints
 	ErrSingularMatrix
+	// ErrMasked marks targets outside of the prediction domain, which are
+	// skipped on purpose
+	ErrMasked
+	// ErrMissingCovariate marks targets without the covariates of a drift
+	ErrMissingCovariate
 )
 
+func (e EstimationError) String() string {
+	switch e {
+	case ErrNone:
+		return "none"
+	case ErrNoConditionPoints:
+		return "no condition points"
+	case ErrSingularMatrix:
+		return "singular matrix"
+	case ErrMasked:
+		return "masked"
+	case ErrMissingCovariate:
+		return "missing covariate"
+	default:
+		return fmt.Sprintf("estimation error %d", uint8(e))
+	}
+}
+
 type Estimation struct {
 	Field    float64
 	Variance float64
 	ErrCode  EstimationError
+	// Err describes why the estimation failed, if ErrCode is not ErrNone
+	Err error
+	// Diagnostics is only set if the interpolator was asked for them
+	Diagnostics *Diagnostics
+}
+
+// Diagnostics describes the kriging system solved for a single target.
+type Diagnostics struct {
+	// Neighbors are the indices of the condition points used, closest first
+	Neighbors []int
+	// Weights are the kriging weights of the Neighbors
+	Weights []float64
+	// Lagrange is the Lagrange multiplier of the unbiasedness constraint
+	Lagrange     float64
+	NumNeighbors int
+	// NearestDistance is the distance to the closest condition point
+	NearestDistance float64
+	// NegativeWeightSum is the sum of all negative weights
+	NegativeWeightSum float64
+	// SlopeOfRegression of true on estimated values; 1 means no conditional bias
+	SlopeOfRegression float64
+	// KrigingEfficiency is 1 - variance / sill; low values indicate poor estimates
+	KrigingEfficiency float64
+	// Solver is the path used to solve the kriging system, e.g. direct or pseudo-inverse
+	Solver string
+	// ConditionNumber of the kriging matrix
+	ConditionNumber float64
+}
+
+// Mask restricts estimation to a prediction domain. Locations the mask does
+// not contain are skipped and estimated as NaN with ErrMasked.
+type Mask interface {
+	Contains(p Point) bool
+}
+
+// Locations is an ordered set of target locations. Implementations may
+// generate the locations lazily, so that they never have to be held in memory.
+type Locations interface {
+	Len() int
+	At(i int) Point
+	Dims() int
+}
+
+// Len returns the number of points.
+func (p Points) Len() int {
+	return len(p.Points)
+}
+
+// At returns the i-th point.
+func (p Points) At(i int) Point {
+	return p.Points[i]
+}
+
+// Dims returns 3 for 3D points and 2 otherwise.
+func (p Points) Dims() int {
+	if p.Is3D {
+		return 3
+	}
+	return 2
+}
+
+// EstimationSink consumes estimations one at a time, in the order of their
+// target locations. Flush is called once all estimations have been written;
+// it does not close any underlying writer.
+type EstimationSink interface {
+	Write(p Point, e Estimation) error
+	Flush() error
+}
+
+// MultiSink writes every estimation to all of its sinks.
+type MultiSink []EstimationSink
+
+func (m MultiSink) Write(p Point, e Estimation) error {
+	for _, s := range m {
+		if err := s.Write(p, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m MultiSink) Flush() error {
+	for _, s := range m {
+		if err := s.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxReportedErrors limits the errors kept by EstimationErrors
+const maxReportedErrors = 10
+
+// EstimationErrors is returned by interpolators alongside the estimations if
+// some targets could not be estimated. The failed estimations hold NaN, their
+// ErrCode and Err.
+type EstimationErrors struct {
+	Total  int
+	Failed int
+	// Masked counts the skipped targets, which are not part of Total
+	Masked int
+	ByCode map[EstimationError]int
+	// Errors holds the errors of the first failed targets
+	Errors []error
+}
+
+// Add records the outcome of a single estimation.
+func (e *EstimationErrors) Add(est Estimation) {
+	if est.ErrCode == ErrMasked {
+		e.Masked++
+		return
+	}
+	e.Total++
+	if est.ErrCode == ErrNone && est.Err == nil {
+		return
+	}
+	e.Failed++
+	if e.ByCode == nil {
+		e.ByCode = make(map[EstimationError]int)
+	}
+	e.ByCode[est.ErrCode]++
+	if est.Err != nil && len(e.Errors) < maxReportedErrors {
+		e.Errors = append(e.Errors, est.Err)
+	}
+}
+
+// Err returns e if any estimation failed, and nil otherwise.
+func (e *EstimationErrors) Err() error {
+	if e.Failed == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *EstimationErrors) Error() string {
+	codes := make([]EstimationError, 0, len(e.ByCode))
+	for code := range e.ByCode {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	reasons := make([]string, len(codes))
+	for i, code := range codes {
+		reasons[i] = fmt.Sprintf("%d %s", e.ByCode[code], code)
+	}
+	return fmt.Sprintf("%d of %d estimations failed: %s", e.Failed, e.Total, strings.Join(reasons, ", "))
 }
@@ -0,0 +1,140 @@
+package asc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+)
+
+// ReadAscFromReader reads an ESRI ASCII grid. It returns the grid spec and
+// the cell values in the order of grid.Spec.At, with NODATA cells as NaN.
+// Both XLLCORNER and XLLCENTER headers are understood, as well as DX and DY
+// instead of CELLSIZE for rectangular cells.
+func ReadAscFromReader(r io.Reader) (grid.Spec, []float64, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	scanner.Split(bufio.ScanWords)
+
+	spec, nodata, first, err := readHeader(scanner)
+	if err != nil {
+		return grid.Spec{}, nil, err
+	}
+
+	values := make([]float64, 0, spec.Len())
+	parse := func(s string) error {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse value %d: %w", len(values), err)
+		}
+		if v == nodata {
+			v = math.NaN()
+		}
+		values = append(values, v)
+		return nil
+	}
+	if first != "" {
+		if err := parse(first); err != nil {
+			return grid.Spec{}, nil, err
+		}
+	}
+	for scanner.Scan() {
+		if err := parse(scanner.Text()); err != nil {
+			return grid.Spec{}, nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return grid.Spec{}, nil, fmt.Errorf("failed to read grid: %w", err)
+	}
+	if len(values) != spec.Len() {
+		return grid.Spec{}, nil, fmt.Errorf("expected %d values, got %d", spec.Len(), len(values))
+	}
+	return spec, values, nil
+}
+
+// ReadAscSpec reads only the header of the ESRI ASCII grid at path and
+// returns its grid spec, e.g. to check its alignment with a template.
+func ReadAscSpec(path string) (grid.Spec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return grid.Spec{}, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Split(bufio.ScanWords)
+	spec, _, _, err := readHeader(scanner)
+	return spec, err
+}
+
+// readHeader reads the header from scanner, which splits words. It returns
+// the first data value if it had to be read to find the end of the header.
+func readHeader(scanner *bufio.Scanner) (spec grid.Spec, nodata float64, first string, err error) {
+	nodata = -9999.0
+	var hasX, hasY, hasSize bool
+
+	for scanner.Scan() {
+		key := strings.ToLower(scanner.Text())
+		if _, err := strconv.ParseFloat(key, 64); err == nil {
+			// the header is done once the first value is read
+			first = key
+			break
+		}
+		if !scanner.Scan() {
+			return spec, nodata, "", fmt.Errorf("missing value for header %s", key)
+		}
+		v, err := strconv.ParseFloat(scanner.Text(), 64)
+		if err != nil {
+			return spec, nodata, "", fmt.Errorf("failed to parse header %s: %w", key, err)
+		}
+
+		switch key {
+		case "ncols":
+			spec.NX = int(v)
+		case "nrows":
+			spec.NY = int(v)
+		case "xllcorner", "xllcenter":
+			spec.X0 = v
+			hasX = true
+			if key == "xllcorner" {
+				spec.Registration = grid.CellCorner
+			}
+		case "yllcorner", "yllcenter":
+			spec.Y0 = v
+			hasY = true
+		case "cellsize":
+			spec.DX, spec.DY = v, v
+			hasSize = true
+		case "dx":
+			spec.DX = v
+			hasSize = true
+		case "dy":
+			spec.DY = v
+		case "nodata_value":
+			nodata = v
+		default:
+			return spec, nodata, "", fmt.Errorf("unknown header %s", key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return spec, nodata, "", fmt.Errorf("failed to read grid: %w", err)
+	}
+	if !hasX || !hasY || !hasSize {
+		return spec, nodata, "", fmt.Errorf("incomplete header: need NCOLS, NROWS, XLL, YLL and CELLSIZE")
+	}
+	return spec, nodata, first, spec.Validate()
+}
+
+// ReadAsc opens path and reads it with ReadAscFromReader.
+func ReadAsc(path string) (grid.Spec, []float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return grid.Spec{}, nil, err
+	}
+	defer f.Close()
+	return ReadAscFromReader(f)
+}
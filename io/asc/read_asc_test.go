@@ -0,0 +1,42 @@
+package asc
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+)
+
+func TestReadAscFromReader(t *testing.T) {
+	input := `NCOLS 3
+NROWS 2
+XLLCENTER 0.5
+YLLCENTER 0.5
+CELLSIZE 1
+NODATA_VALUE -1
+1 2 3
+4 -1 6
+`
+	spec, values, err := ReadAscFromReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadAscFromReader() error = %v", err)
+	}
+	if spec.NX != 3 || spec.NY != 2 || spec.Registration != grid.CellCenter {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+	// the first value is the north-west cell
+	if p := spec.At(0); p.X != 0.5 || p.Y != 1.5 || values[0] != 1 {
+		t.Errorf("At(0) = (%v, %v) with value %v", p.X, p.Y, values[0])
+	}
+	if !math.IsNaN(values[4]) {
+		t.Errorf("NODATA should be read as NaN, got %v", values[4])
+	}
+}
+
+func TestReadAscFromReaderTruncated(t *testing.T) {
+	input := "NCOLS 2\nNROWS 2\nXLLCORNER 0\nYLLCORNER 0\nCELLSIZE 1\n1 2 3\n"
+	if _, _, err := ReadAscFromReader(strings.NewReader(input)); err == nil {
+		t.Error("expected an error for missing values")
+	}
+}
@@ -1,86 +1,182 @@
 package asc
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"math"
 	"os"
-	"sort"
+	"strconv"
 
+	"github.com/mmaelicke/go-geostat/internal/grid"
 	"github.com/mmaelicke/go-geostat/internal/types"
 )
 
+// Header holds the ESRI ASCII grid header fields and the formatting of the
+// values.
+type Header struct {
+	NCols, NRows int
+	// XLL and YLL are the outer lower left corner, or the centre of the lower
+	// left cell if Center is set
+	XLL, YLL float64
+	Center   bool
+	// DX and DY are the cell sizes. Rectangular cells are written as DX and DY
+	// headers instead of CELLSIZE.
+	DX, DY float64
+	NoData float64
+	// Precision is the number of decimals written, -1 for the shortest
+	// representation that reads back exactly
+	Precision int
+}
+
+// HeaderFromSpec returns the header of a raster with the cells of s. Cell
+// centre registered grids are written with XLLCENTER and YLLCENTER headers.
+func HeaderFromSpec(s grid.Spec) Header {
+	h := Header{
+		NCols:     s.NX,
+		NRows:     s.NY,
+		DX:        s.DX,
+		DY:        s.DY,
+		NoData:    -9999,
+		Precision: 6,
+	}
+	if s.Registration == grid.CellCenter {
+		h.XLL, h.YLL, _ = s.Center()
+		h.Center = true
+	} else {
+		h.XLL, h.YLL, _ = s.Corner()
+	}
+	return h
+}
+
+// Spec returns the grid described by the header.
+func (h Header) Spec() grid.Spec {
+	s := grid.Spec{X0: h.XLL, Y0: h.YLL, DX: h.DX, DY: h.DY, NX: h.NCols, NY: h.NRows}
+	if !h.Center {
+		s.Registration = grid.CellCorner
+	}
+	return s
+}
+
+// noData returns NODATA_VALUE as written in the header. NaN cells are
+// written as the same string, so that readers match them whatever the
+// precision of the values.
+func (h Header) noData() string {
+	return strconv.FormatFloat(h.NoData, 'f', -1, 64)
+}
+
+func (h Header) format(v float64) string {
+	if math.IsNaN(v) {
+		return h.noData()
+	}
+	return strconv.FormatFloat(v, 'f', h.Precision, 64)
+}
+
+// write writes the header lines to w
+func (h Header) write(w io.Writer) error {
+	ref := "CORNER"
+	if h.Center {
+		ref = "CENTER"
+	}
+	fmt.Fprintf(w, "NCOLS %d\n", h.NCols)
+	fmt.Fprintf(w, "NROWS %d\n", h.NRows)
+	fmt.Fprintf(w, "XLL%s %s\n", ref, strconv.FormatFloat(h.XLL, 'f', -1, 64))
+	fmt.Fprintf(w, "YLL%s %s\n", ref, strconv.FormatFloat(h.YLL, 'f', -1, 64))
+	if h.DX == h.DY {
+		fmt.Fprintf(w, "CELLSIZE %s\n", strconv.FormatFloat(h.DX, 'f', -1, 64))
+	} else {
+		fmt.Fprintf(w, "DX %s\n", strconv.FormatFloat(h.DX, 'f', -1, 64))
+		fmt.Fprintf(w, "DY %s\n", strconv.FormatFloat(h.DY, 'f', -1, 64))
+	}
+	_, err := fmt.Fprintf(w, "NODATA_VALUE %s\n", h.noData())
+	return err
+}
+
+// Field selects the kriging estimate of an estimation.
+func Field(e types.Estimation) float64 { return e.Field }
+
+// Variance selects the kriging variance of an estimation.
+func Variance(e types.Estimation) float64 { return e.Variance }
+
+// KrigAscSink writes one value per estimation into an ESRI ASCII grid as soon
+// as it is produced. Estimations have to arrive in row-major order starting
+// in the north-west corner, as generated by grid.Spec.
+// It implements types.EstimationSink.
+type KrigAscSink struct {
+	w      *bufio.Writer
+	header Header
+	value  func(types.Estimation) float64
+	n      int
+}
+
+// NewKrigAscSink writes the header to w and returns a sink writing the value
+// selected by value, e.g. Field or Variance, for each estimation.
+func NewKrigAscSink(w io.Writer, header Header, value func(types.Estimation) float64) (*KrigAscSink, error) {
+	s := &KrigAscSink{
+		w:      bufio.NewWriter(w),
+		header: header,
+		value:  value,
+	}
+	if header.NCols < 1 || header.NRows < 1 {
+		return nil, fmt.Errorf("grid must have at least one cell, got %d x %d", header.NCols, header.NRows)
+	}
+	return s, header.write(s.w)
+}
+
+func (s *KrigAscSink) Write(p types.Point, e types.Estimation) error {
+	if s.n >= s.header.NCols*s.header.NRows {
+		return fmt.Errorf("more values than the %d x %d grid holds", s.header.NCols, s.header.NRows)
+	}
+	sep := " "
+	if s.n%s.header.NCols == s.header.NCols-1 {
+		sep = "\n"
+	}
+	s.n++
+
+	_, err := s.w.WriteString(s.header.format(s.value(e)) + sep)
+	return err
+}
+
+func (s *KrigAscSink) Flush() error {
+	if s.n != s.header.NCols*s.header.NRows {
+		return fmt.Errorf("grid incomplete: got %d of %d values", s.n, s.header.NCols*s.header.NRows)
+	}
+	return s.w.Flush()
+}
+
+// WriteKrigAscToWriter writes values at the nodes of a regular grid in any
+// order. The grid is derived from the node coordinates; nodes missing from
+// gridList are written as NODATA. Use NewKrigAscSink with the grid spec to
+// write grids without holding them in memory.
 func WriteKrigAscToWriter(w io.Writer, gridList types.Points, values []float64) error {
 	if gridList.Is3D {
 		return fmt.Errorf("3D grids are not supported")
 	}
-	// Create copies of the input data
-	grid := make([]types.Point, len(gridList.Points))
-	copy(grid, gridList.Points)
-	valuesCopy := make([]float64, len(values))
-	copy(valuesCopy, values)
+	if len(gridList.Points) == 0 {
+		return fmt.Errorf("no grid nodes to write")
+	}
+	spec := grid.FromNodes(gridList.Points)
 
-	type gridValue struct {
-		Point types.Point
-		Value float64
+	cells := make([]float64, spec.Len())
+	for i := range cells {
+		cells[i] = math.NaN()
 	}
-	combined := make([]gridValue, len(grid))
-	for i := range grid {
-		combined[i] = gridValue{Point: grid[i], Value: valuesCopy[i]}
+	for i, p := range gridList.Points {
+		if j, ok := spec.Locate(p); ok {
+			cells[j] = values[i]
+		}
 	}
 
-	sort.Slice(combined, func(i, j int) bool {
-		if combined[i].Point.Y != combined[j].Point.Y {
-			return combined[i].Point.Y > combined[j].Point.Y
-		}
-		return combined[i].Point.X < combined[j].Point.X
-	})
-
-	// After sorting, extract sorted grid and values
-	for i := range grid {
-		grid[i] = combined[i].Point
-		valuesCopy[i] = combined[i].Value
-	}
-
-	llx := grid[0].X
-	lly := grid[len(grid)-1].Y
-
-	xVals := make(map[float64]struct{})
-	yVals := make(map[float64]struct{})
-	for _, p := range grid {
-		xVals[p.X] = struct{}{}
-		yVals[p.Y] = struct{}{}
-	}
-	nx := len(xVals)
-	ny := len(yVals)
-	dx := (grid[len(grid)-1].X - grid[0].X) / float64(nx-1)
-
-	w.Write([]byte(fmt.Sprintf("NCOLS %d\n", nx)))
-	w.Write([]byte(fmt.Sprintf("NROWS %d\n", ny)))
-	w.Write([]byte(fmt.Sprintf("XLLCORNER %f\n", llx)))
-	w.Write([]byte(fmt.Sprintf("YLLCORNER %f\n", lly)))
-	w.Write([]byte(fmt.Sprintf("CELLSIZE %f\n", dx)))
-	w.Write([]byte("NODATA_VALUE -9999\n"))
-	for j := 0; j < ny; j++ {
-		for i := 0; i < nx; i++ {
-			idx := j*nx + i
-			if math.IsNaN(valuesCopy[idx]) {
-				if i == nx-1 {
-					w.Write([]byte("-9999\n"))
-				} else {
-					w.Write([]byte("-9999 "))
-				}
-			} else {
-				if i == nx-1 {
-					w.Write([]byte(fmt.Sprintf("%f\n", valuesCopy[idx])))
-				} else {
-					w.Write([]byte(fmt.Sprintf("%f ", valuesCopy[idx])))
-				}
-			}
+	sink, err := NewKrigAscSink(w, HeaderFromSpec(spec), Field)
+	if err != nil {
+		return err
+	}
+	for i, v := range cells {
+		if err := sink.Write(spec.At(i), types.Estimation{Field: v}); err != nil {
+			return err
 		}
 	}
-
-	return nil
+	return sink.Flush()
 }
 
 func WriteKrigAsc(path string, gridList types.Points, values []float64) error {
@@ -0,0 +1,105 @@
+package asc
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+func TestKrigAscSinkRectangularCells(t *testing.T) {
+	spec := grid.Spec{X0: 0.5, Y0: 1, DX: 1, DY: 2, NX: 2, NY: 2}
+	header := HeaderFromSpec(spec)
+	header.NoData = -1
+	header.Precision = 2
+
+	var buf bytes.Buffer
+	sink, err := NewKrigAscSink(&buf, header, Field)
+	if err != nil {
+		t.Fatalf("NewKrigAscSink() error = %v", err)
+	}
+	for i, v := range []float64{1, 2, math.NaN(), 4} {
+		if err := sink.Write(spec.At(i), types.Estimation{Field: v}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"XLLCENTER 0.5\n", "DX 1\n", "DY 2\n", "NODATA_VALUE -1\n", "-1 4.00\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output misses %q:\n%s", want, out)
+		}
+	}
+
+	// the written grid reads back with the same cells
+	read, values, err := ReadAscFromReader(&buf)
+	if err != nil {
+		t.Fatalf("ReadAscFromReader() error = %v", err)
+	}
+	if err := spec.Aligned(read); err != nil {
+		t.Errorf("Aligned() error = %v", err)
+	}
+	if !math.IsNaN(values[2]) {
+		t.Errorf("NODATA should read back as NaN, got %v", values[2])
+	}
+}
+
+func TestKrigAscSinkNoDataPrecision(t *testing.T) {
+	spec := grid.Spec{X0: 0.5, Y0: 0.5, DX: 1, DY: 1, NX: 3, NY: 1}
+	header := HeaderFromSpec(spec)
+	header.NoData = -9999.25
+	header.Precision = 0
+
+	var buf bytes.Buffer
+	sink, err := NewKrigAscSink(&buf, header, Field)
+	if err != nil {
+		t.Fatalf("NewKrigAscSink() error = %v", err)
+	}
+	for i, v := range []float64{math.NaN(), -9999.4, 2.5} {
+		if err := sink.Write(spec.At(i), types.Estimation{Field: v}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	// NaN cells are the exact NODATA_VALUE string, not rounded to Precision
+	out := buf.String()
+	for _, want := range []string{"NODATA_VALUE -9999.25\n", "\n-9999.25 -9999 2\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output misses %q:\n%s", want, out)
+		}
+	}
+	_, values, err := ReadAscFromReader(&buf)
+	if err != nil {
+		t.Fatalf("ReadAscFromReader() error = %v", err)
+	}
+	if !math.IsNaN(values[0]) || values[1] != -9999 || values[2] != 2 {
+		t.Errorf("read back %v, want NaN, -9999 and 2", values)
+	}
+}
+
+func TestWriteKrigAscSingleColumn(t *testing.T) {
+	nodes := types.Points{Points: []types.Point{{X: 5, Y: 0}, {X: 5, Y: 10}, {X: 5, Y: 20}}}
+	var buf bytes.Buffer
+	if err := WriteKrigAscToWriter(&buf, nodes, []float64{1, 2, 3}); err != nil {
+		t.Fatalf("WriteKrigAscToWriter() error = %v", err)
+	}
+	spec, values, err := ReadAscFromReader(&buf)
+	if err != nil {
+		t.Fatalf("ReadAscFromReader() error = %v", err)
+	}
+	if spec.NX != 1 || spec.NY != 3 || spec.DX != 10 {
+		t.Errorf("unexpected grid %+v", spec)
+	}
+	// rows are written from north to south
+	if values[0] != 3 || values[2] != 1 {
+		t.Errorf("unexpected values %v", values)
+	}
+}
@@ -34,6 +34,29 @@ Basic Usage:
 	// Sample random subset of points
 	sample := data.Sample(100)
 
+Several value columns, and covariates, can be read at once. Each variable is
+stored in the point attributes and selected by name:
+
+	data, err := csv.ReadColumns("data/meuse.txt", csv.Columns{
+		Values:     []string{"copper", "zinc"},
+		Covariates: []string{"elev"},
+	}, "", false)
+
+	copper := data.Variable("copper")
+
+Options control how the text is parsed: the delimiter, decimal commas,
+missing-value sentinels and whether there is a header row. Columns may be
+selected by name or by 1-based index, and gzip compressed input is detected
+automatically. ReadFile and ReadFromReader also return a Report, which counts
+the rows and locates the skipped ones:
+
+	data, report, err := csv.ReadFile("samples.csv.gz", csv.Columns{
+		X: "2", Y: "3", Values: []string{"5"},
+	}, csv.Options{DecimalComma: true, Missing: []string{"NA", "-9999"}})
+	if len(report.Skipped) > 0 {
+		log.Println(report)
+	}
+
 The PointData type implements the types.SpatialSample interface, providing methods
 for accessing and sampling the data:
 
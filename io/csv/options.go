@@ -0,0 +1,185 @@
+package csv
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options control how delimited text is split and parsed. The zero value
+// reads comma separated files with a header row.
+type Options struct {
+	// Delimiter separates the fields. It defaults to ',', or to ';' with
+	// DecimalComma. A space splits on runs of spaces and tabs, as in
+	// whitespace aligned tables.
+	Delimiter rune
+	// DecimalComma parses numbers like 3,14 as used in many European locales.
+	DecimalComma bool
+	// Comment starts lines that are skipped. It defaults to "#".
+	Comment string
+	// Missing lists sentinels read as missing values, e.g. NA or -9999.
+	// Empty fields are always missing.
+	Missing []string
+	// NoHeader reads the first row as data. Columns have to be selected by
+	// their 1-based index then.
+	NoHeader bool
+	// TimeLayouts are tried in order to parse the time column: Go time
+	// layouts, or timeaxis.Unix and timeaxis.UnixMilli for epoch seconds and
+	// milliseconds. They default to timeaxis.DefaultLayouts, ISO 8601 with
+	// and without time zone.
+	TimeLayouts []string
+	// Location is the time zone of times without zone. It defaults to UTC.
+	Location *time.Location
+	// Strict fails on the first invalid field, instead of skipping its row.
+	// Rows with missing values are still skipped.
+	Strict bool
+}
+
+// errMissing marks fields that are empty or one of the missing sentinels
+var errMissing = errors.New("missing")
+
+// withDefaults returns the options with all defaults filled in
+func (o Options) withDefaults() (Options, error) {
+	if o.Delimiter == 0 {
+		o.Delimiter = ','
+		if o.DecimalComma {
+			o.Delimiter = ';'
+		}
+	}
+	if o.DecimalComma && o.Delimiter == ',' {
+		return o, fmt.Errorf("a decimal comma needs a delimiter other than ','")
+	}
+	if o.Comment == "" {
+		o.Comment = "#"
+	}
+	return o, nil
+}
+
+// missing reports whether field is empty or a missing sentinel
+func (o Options) missing(field string) bool {
+	if field == "" {
+		return true
+	}
+	for _, m := range o.Missing {
+		if field == m {
+			return true
+		}
+	}
+	return false
+}
+
+// number parses a numeric field. It returns errMissing for missing values.
+func (o Options) number(field string) (float64, error) {
+	field = strings.TrimSpace(field)
+	if o.missing(field) {
+		return 0, errMissing
+	}
+	if o.DecimalComma {
+		field = strings.Replace(field, ",", ".", 1)
+	}
+	v, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", field)
+	}
+	return v, nil
+}
+
+// SkippedRow locates a row that was not read and tells why.
+type SkippedRow struct {
+	Line   int
+	Reason string
+}
+
+// Report summarizes a read: the number of data rows, the number of points
+// read from them and the rows that were skipped.
+type Report struct {
+	Rows    int
+	Points  int
+	Skipped []SkippedRow
+}
+
+// maxListed is the number of skipped rows listed by Report.String
+const maxListed = 10
+
+// String returns a summary line, followed by the first skipped rows.
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "read %d points from %d rows, skipped %d", r.Points, r.Rows, len(r.Skipped))
+	for i, s := range r.Skipped {
+		if i == maxListed {
+			fmt.Fprintf(&b, "\n  and %d more", len(r.Skipped)-maxListed)
+			break
+		}
+		fmt.Fprintf(&b, "\n  line %d: %s", s.Line, s.Reason)
+	}
+	return b.String()
+}
+
+// rows iterates over the records of delimited text together with their line
+// numbers
+type rows interface {
+	next() ([]string, int, error)
+}
+
+// quotedRows splits quoted delimited text with encoding/csv
+type quotedRows struct {
+	r *csv.Reader
+}
+
+func (q quotedRows) next() ([]string, int, error) {
+	record, err := q.r.Read()
+	if err != nil {
+		return nil, 0, err
+	}
+	line, _ := q.r.FieldPos(0)
+	return record, line, nil
+}
+
+// fieldRows splits lines on runs of whitespace
+type fieldRows struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (f *fieldRows) next() ([]string, int, error) {
+	for f.scanner.Scan() {
+		f.line++
+		if fields := strings.Fields(f.scanner.Text()); len(fields) > 0 {
+			return fields, f.line, nil
+		}
+	}
+	if err := f.scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	return nil, 0, io.EOF
+}
+
+// newRows returns the rows of r, which is decompressed first if it starts
+// with the gzip magic number
+func newRows(r io.Reader, opts Options) (rows, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress: %w", err)
+		}
+		br = bufio.NewReader(gz)
+	}
+
+	if opts.Delimiter == ' ' {
+		scanner := bufio.NewScanner(br)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		return &fieldRows{scanner: scanner}, nil
+	}
+	cr := csv.NewReader(br)
+	cr.Comma = opts.Delimiter
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	return quotedRows{cr}, nil
+}
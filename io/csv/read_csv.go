@@ -2,14 +2,17 @@ package csv
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/rand"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/mmaelicke/go-geostat/internal/timeaxis"
 	"github.com/mmaelicke/go-geostat/internal/types"
 )
 
@@ -17,6 +20,29 @@ import (
 type PointData struct {
 	Points []types.Point
 	Is3D   bool
+	// Variables lists the value columns read into each point's Attributes
+	Variables []string
+	// Covariates lists the columns read into each point's Covariates
+	Covariates []string
+}
+
+// Columns maps the point fields to CSV header names. Empty coordinate names
+// fall back to the defaults x, y, z and time; an empty Values list falls back
+// to a single value column.
+type Columns struct {
+	X, Y, Z, T string
+	// Values are read into Point.Attributes. The first one is also Point.Value.
+	Values []string
+	// Covariates are read into Point.Covariates.
+	Covariates []string
+	// Group is an optional column of group ids, e.g. drillhole names.
+	Group string
+	// ErrorVariance is an optional column of measurement error variances,
+	// read into Point.ErrorVariance.
+	ErrorVariance string
+	// Date is an optional column of dates. If set, the time column holds the
+	// time of day, and both are joined by a space before parsing.
+	Date string
 }
 
 func (p PointData) Length() int {
@@ -63,134 +89,308 @@ func (p PointData) Sample(size int) types.Points {
 	}
 }
 
+// Variable returns all points with Value set to the named variable. Points
+// where the variable is missing are dropped.
+func (p PointData) Variable(name string) types.Points {
+	return p.Read().Variable(name)
+}
+
 func ReadCSVFromReader(reader io.Reader, xCol, yCol, zCol, tCol, valueCol, timeFormat string, errorOnParse bool) (PointData, error) {
-	csvReader := csv.NewReader(reader)
+	cols := Columns{X: xCol, Y: yCol, Z: zCol, T: tCol}
+	if valueCol != "" {
+		cols.Values = []string{valueCol}
+	}
+	return ReadColumnsFromReader(reader, cols, timeFormat, errorOnParse)
+}
 
-	header, err := csvReader.Read()
-	if err != nil {
-		return PointData{}, fmt.Errorf("failed to read header: %w", err)
+// ReadColumnsFromReader reads points carrying several value and covariate
+// columns at once. A row is skipped if none of its value columns can be
+// parsed; single unparseable values are stored as NaN.
+func ReadColumnsFromReader(reader io.Reader, cols Columns, timeFormat string, errorOnParse bool) (PointData, error) {
+	opts := Options{Strict: errorOnParse}
+	if timeFormat != "" {
+		opts.TimeLayouts = []string{timeFormat}
 	}
+	data, _, err := ReadFromReader(reader, cols, opts)
+	return data, err
+}
+
+// layout holds the field index of each column, or -1 for unused columns
+type layout struct {
+	x, y, z, t, date, group, errVar int
+	values, covariates              []int
+	width                           int
+	times                           timeaxis.Parser
+}
 
-	if xCol == "" {
-		xCol = "x"
+// resolve returns the index of the column called name in header. Names are
+// matched exactly, then case-insensitively; a name that matches no column but
+// is a number selects the column by its 1-based index.
+func resolve(header []string, name string) int {
+	for i, col := range header {
+		if strings.TrimSpace(col) == name {
+			return i
+		}
 	}
-	if yCol == "" {
-		yCol = "y"
+	for i, col := range header {
+		if strings.EqualFold(strings.TrimSpace(col), name) {
+			return i
+		}
 	}
-	if zCol == "" {
-		zCol = "z"
+	if n, err := strconv.Atoi(name); err == nil && n >= 1 && n <= len(header) {
+		return n - 1
 	}
-	if tCol == "" {
-		tCol = "time"
+	return -1
+}
+
+// newLayout resolves the columns in header
+func newLayout(header []string, cols Columns) (layout, error) {
+	l := layout{
+		x:          resolve(header, cols.X),
+		y:          resolve(header, cols.Y),
+		z:          resolve(header, cols.Z),
+		t:          resolve(header, cols.T),
+		date:       -1,
+		group:      -1,
+		errVar:     -1,
+		values:     make([]int, len(cols.Values)),
+		covariates: make([]int, len(cols.Covariates)),
 	}
-	if valueCol == "" {
-		valueCol = "value"
+	if l.x == -1 || l.y == -1 {
+		return l, fmt.Errorf("missing required columns. You need to specify at least x, y and value columns")
+	}
+	for v, name := range cols.Values {
+		if l.values[v] = resolve(header, name); l.values[v] == -1 {
+			return l, fmt.Errorf("missing value column %q", name)
+		}
 	}
-	if timeFormat == "" {
-		timeFormat = "2006-01-02 15:04:05"
+	for c, name := range cols.Covariates {
+		if l.covariates[c] = resolve(header, name); l.covariates[c] == -1 {
+			return l, fmt.Errorf("missing covariate column %q", name)
+		}
+	}
+	if cols.Date != "" {
+		if l.date = resolve(header, cols.Date); l.date == -1 {
+			return l, fmt.Errorf("missing date column %q", cols.Date)
+		}
 	}
+	if cols.Group != "" {
+		if l.group = resolve(header, cols.Group); l.group == -1 {
+			return l, fmt.Errorf("missing group column %q", cols.Group)
+		}
+	}
+	if cols.ErrorVariance != "" {
+		if l.errVar = resolve(header, cols.ErrorVariance); l.errVar == -1 {
+			return l, fmt.Errorf("missing error variance column %q", cols.ErrorVariance)
+		}
+	}
+	for _, idx := range append([]int{l.x, l.y, l.z, l.t, l.date, l.group, l.errVar}, append(l.values, l.covariates...)...) {
+		l.width = max(l.width, idx+1)
+	}
+	return l, nil
+}
 
-	xIdx := -1
-	yIdx := -1
-	zIdx := -1
-	tIdx := -1
-	valueIdx := -1
+// point parses one record. The error names the reason to skip the record.
+func (l layout) point(record []string, cols Columns, opts Options) (types.Point, error) {
+	point := types.Point{}
+	if len(record) < l.width {
+		return point, fmt.Errorf("has %d fields, want at least %d", len(record), l.width)
+	}
 
-	for i, col := range header {
-		switch col {
-		case xCol:
-			xIdx = i
-		case yCol:
-			yIdx = i
-		case zCol:
-			zIdx = i
-		case tCol:
-			tIdx = i
-		case valueCol:
-			valueIdx = i
+	var err error
+	if point.X, err = opts.number(record[l.x]); err != nil {
+		return point, fmt.Errorf("x: %w", err)
+	}
+	if point.Y, err = opts.number(record[l.y]); err != nil {
+		return point, fmt.Errorf("y: %w", err)
+	}
+
+	point.Attributes = make(map[string]float64, len(l.values))
+	parsed := 0
+	for v, idx := range l.values {
+		value, err := opts.number(record[idx])
+		if err != nil {
+			if opts.Strict && err != errMissing {
+				return point, fmt.Errorf("%s: %w", cols.Values[v], err)
+			}
+			value = math.NaN()
+		} else {
+			parsed++
 		}
+		point.Attributes[cols.Values[v]] = value
+	}
+	if parsed == 0 {
+		return point, fmt.Errorf("all values %w", errMissing)
 	}
+	point.Value = point.Attributes[cols.Values[0]]
 
-	if xIdx == -1 || yIdx == -1 || valueIdx == -1 {
-		return PointData{}, fmt.Errorf("missing required columns. You need to specify at least x, y and value columns")
+	if len(l.covariates) > 0 {
+		point.Covariates = make(map[string]float64, len(l.covariates))
+	}
+	for c, idx := range l.covariates {
+		cov, err := opts.number(record[idx])
+		if err != nil {
+			return point, fmt.Errorf("%s: %w", cols.Covariates[c], err)
+		}
+		point.Covariates[cols.Covariates[c]] = cov
 	}
 
-	data := PointData{
-		Points: make([]types.Point, 0),
-		Is3D:   zIdx != -1,
+	if l.z != -1 {
+		if point.Z, err = opts.number(record[l.z]); err != nil {
+			return point, fmt.Errorf("z: %w", err)
+		}
+		point.Is3D = true
 	}
 
-	for {
-		record, err := csvReader.Read()
-		if err == io.EOF {
-			break
+	if l.t != -1 || l.date != -1 {
+		t, err := l.time(record, opts)
+		if err != nil {
+			return point, fmt.Errorf("time: %w", err)
+		}
+		point.Time = t
+		point.HasTime = true
+	}
+
+	if l.group != -1 {
+		point.Group = record[l.group]
+	}
+
+	if l.errVar != -1 {
+		ev, err := opts.number(record[l.errVar])
+		if err == nil && (ev < 0 || math.IsNaN(ev)) {
+			err = fmt.Errorf("negative error variance %v", ev)
 		}
 		if err != nil {
-			return PointData{}, fmt.Errorf("failed to read record: %w", err)
+			return point, fmt.Errorf("%s: %w", cols.ErrorVariance, err)
 		}
+		point.ErrorVariance = ev
+	}
+	return point, nil
+}
 
-		if strings.HasPrefix(record[0], "#") {
+// time parses the time column, joined to the date column if there is one
+func (l layout) time(record []string, opts Options) (time.Time, error) {
+	var parts []string
+	for _, idx := range []int{l.date, l.t} {
+		if idx == -1 {
 			continue
 		}
+		field := strings.TrimSpace(record[idx])
+		if opts.missing(field) {
+			return time.Time{}, errMissing
+		}
+		parts = append(parts, field)
+	}
+	return l.times.Parse(strings.Join(parts, " "))
+}
 
-		point := types.Point{}
+// ReadFromReader reads points from delimited text, which may be gzip
+// compressed. Columns are selected by header name or by 1-based index. A row
+// is skipped if a coordinate, covariate or time is missing or invalid, or if
+// none of its value columns can be parsed; single missing values are stored
+// as NaN. The report counts the rows and locates the skipped ones.
+func ReadFromReader(reader io.Reader, cols Columns, opts Options) (PointData, Report, error) {
+	var report Report
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return PointData{}, report, err
+	}
+	records, err := newRows(reader, opts)
+	if err != nil {
+		return PointData{}, report, err
+	}
 
-		// Parse coordinates
-		if x, err := strconv.ParseFloat(record[xIdx], 64); err == nil {
-			point.X = x
-		} else {
-			if errorOnParse {
-				return PointData{}, fmt.Errorf("failed to parse x: %w", err)
+	// next returns the next record that is not a comment
+	next := func() ([]string, int, error) {
+		for {
+			record, line, err := records.next()
+			if err != nil {
+				return nil, line, err
+			}
+			if !strings.HasPrefix(strings.TrimSpace(record[0]), opts.Comment) {
+				return record, line, nil
 			}
-			continue
 		}
+	}
 
-		if y, err := strconv.ParseFloat(record[yIdx], 64); err == nil {
-			point.Y = y
-		} else {
-			if errorOnParse {
-				return PointData{}, fmt.Errorf("failed to parse y: %w", err)
-			}
-			continue
+	header, _, err := next()
+	if err != nil {
+		return PointData{}, report, fmt.Errorf("failed to read header: %w", err)
+	}
+	var first []string
+	if opts.NoHeader {
+		first = header
+		header = make([]string, len(first))
+		for i := range header {
+			header[i] = strconv.Itoa(i + 1)
 		}
+	}
 
-		if value, err := strconv.ParseFloat(record[valueIdx], 64); err == nil {
-			point.Value = value
+	if cols.X == "" {
+		cols.X = "x"
+	}
+	if cols.Y == "" {
+		cols.Y = "y"
+	}
+	if cols.Z == "" {
+		cols.Z = "z"
+	}
+	if cols.T == "" {
+		cols.T = "time"
+	}
+	if len(cols.Values) == 0 {
+		cols.Values = []string{"value"}
+	}
+
+	l, err := newLayout(header, cols)
+	if err != nil {
+		return PointData{}, report, err
+	}
+	l.times = timeaxis.Parser{Layouts: opts.TimeLayouts, Location: opts.Location}
+
+	data := PointData{
+		Points:     make([]types.Point, 0),
+		Is3D:       l.z != -1,
+		Variables:  cols.Values,
+		Covariates: cols.Covariates,
+	}
+
+	for {
+		var record []string
+		var line int
+		if first != nil {
+			record, first = first, nil
+			line = 1
 		} else {
-			if errorOnParse {
-				return PointData{}, fmt.Errorf("failed to parse value: %w", err)
-			}
+			record, line, err = next()
+		}
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && !opts.Strict {
+			report.Rows++
+			report.Skipped = append(report.Skipped, SkippedRow{Line: parseErr.Line, Reason: parseErr.Err.Error()})
 			continue
 		}
-
-		if data.Is3D {
-			if z, err := strconv.ParseFloat(record[zIdx], 64); err == nil {
-				point.Z = z
-				point.Is3D = true
-			} else {
-				if errorOnParse {
-					return PointData{}, fmt.Errorf("failed to parse z: %w", err)
-				}
-				continue
-			}
+		if err != nil {
+			return PointData{}, report, fmt.Errorf("failed to read record: %w", err)
 		}
 
-		if tIdx != -1 {
-			if t, err := time.Parse(timeFormat, record[tIdx]); err == nil {
-				point.Time = t
-				point.HasTime = true
-			} else {
-				if errorOnParse {
-					return PointData{}, fmt.Errorf("failed to parse time: %w", err)
-				}
-				continue
+		report.Rows++
+		point, err := l.point(record, cols, opts)
+		if err != nil {
+			if opts.Strict && !errors.Is(err, errMissing) {
+				return PointData{}, report, fmt.Errorf("line %d: %w", line, err)
 			}
+			report.Skipped = append(report.Skipped, SkippedRow{Line: line, Reason: err.Error()})
+			continue
 		}
-
 		data.Points = append(data.Points, point)
 	}
 
-	return data, nil
+	report.Points = len(data.Points)
+	return data, report, nil
 }
 
 func ReadCSV(path, xCol, yCol, zCol, tCol, valueCol, timeFormat string, errorOnParse bool) (PointData, error) {
@@ -201,3 +401,23 @@ func ReadCSV(path, xCol, yCol, zCol, tCol, valueCol, timeFormat string, errorOnP
 	defer file.Close()
 	return ReadCSVFromReader(file, xCol, yCol, zCol, tCol, valueCol, timeFormat, errorOnParse)
 }
+
+// ReadFile opens path and reads it with ReadFromReader.
+func ReadFile(path string, cols Columns, opts Options) (PointData, Report, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return PointData{}, Report{}, err
+	}
+	defer file.Close()
+	return ReadFromReader(file, cols, opts)
+}
+
+// ReadColumns opens path and reads it with ReadColumnsFromReader.
+func ReadColumns(path string, cols Columns, timeFormat string, errorOnParse bool) (PointData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return PointData{}, err
+	}
+	defer file.Close()
+	return ReadColumnsFromReader(file, cols, timeFormat, errorOnParse)
+}
@@ -1,10 +1,14 @@
 package csv
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestReadCopperData(t *testing.T) {
@@ -55,3 +59,140 @@ func TestReadCopperData(t *testing.T) {
 		t.Error("Data should not be in 3D mode")
 	}
 }
+
+func TestReadColumnsMultipleVariables(t *testing.T) {
+	input := `x,y,a,b,elev
+0,0,1,2,10
+1,0,2,NA,11
+0,1,NA,NA,12
+1,1,4,5,13
+`
+	data, err := ReadColumnsFromReader(strings.NewReader(input), Columns{
+		Values:     []string{"a", "b"},
+		Covariates: []string{"elev"},
+	}, "", false)
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+
+	// the row without any parseable value is skipped
+	if len(data.Points) != 3 {
+		t.Fatalf("Expected 3 points, got %d", len(data.Points))
+	}
+	if data.Points[1].Value != 2 {
+		t.Errorf("Value should be the first variable, got %f", data.Points[1].Value)
+	}
+	if elev, ok := data.Points[2].Covariate("elev"); !ok || elev != 13 {
+		t.Errorf("Expected covariate elev=13, got %f", elev)
+	}
+
+	a := data.Variable("a")
+	if len(a.Points) != 3 {
+		t.Errorf("Expected 3 points for a, got %d", len(a.Points))
+	}
+	b := data.Variable("b")
+	if len(b.Points) != 2 {
+		t.Errorf("Expected 2 points for b, got %d", len(b.Points))
+	}
+	if b.Points[1].Value != 5 {
+		t.Errorf("Expected value 5 for b, got %f", b.Points[1].Value)
+	}
+}
+
+func TestReadColumnsMissingColumn(t *testing.T) {
+	input := "x,y,a\n0,0,1\n"
+	_, err := ReadColumnsFromReader(strings.NewReader(input), Columns{Values: []string{"a", "b"}}, "", false)
+	if err == nil {
+		t.Error("Expected an error for the missing column b")
+	}
+}
+
+func TestReadColumnsErrorVariance(t *testing.T) {
+	input := `x,y,value,sd2
+0,0,1,0.5
+1,0,2,-1
+0,1,3,0
+`
+	data, err := ReadColumnsFromReader(strings.NewReader(input), Columns{ErrorVariance: "sd2"}, "", false)
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+
+	// the row with a negative error variance is skipped
+	if len(data.Points) != 2 {
+		t.Fatalf("Expected 2 points, got %d", len(data.Points))
+	}
+	if data.Points[0].ErrorVariance != 0.5 {
+		t.Errorf("Expected error variance 0.5, got %f", data.Points[0].ErrorVariance)
+	}
+}
+
+func TestReadOptionsReport(t *testing.T) {
+	input := `# exported from a spreadsheet
+X;Y;Value;elev
+0;0;1,5;10
+1;0;-9999;11
+NA;1;3;12
+1;1;4,25;-9999
+2;1;5
+`
+	data, report, err := ReadFromReader(strings.NewReader(input), Columns{
+		Covariates: []string{"elev"},
+	}, Options{DecimalComma: true, Missing: []string{"NA", "-9999"}})
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+
+	// header names are matched case-insensitively
+	if len(data.Points) != 1 || data.Points[0].Value != 1.5 {
+		t.Fatalf("Expected the single point with value 1.5, got %v", data.Points)
+	}
+	if report.Rows != 5 || report.Points != 1 || len(report.Skipped) != 4 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	lines := []int{4, 5, 6, 7}
+	for i, s := range report.Skipped {
+		if s.Line != lines[i] {
+			t.Errorf("Skipped row %d on line %d, want %d (%s)", i, s.Line, lines[i], s.Reason)
+		}
+	}
+	if !strings.Contains(report.String(), "line 5: x: missing") {
+		t.Errorf("Report should locate the missing x, got\n%s", report)
+	}
+}
+
+func TestReadGzipByIndex(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("1  2   3\n\n4\t5 6\n"))
+	gz.Close()
+
+	data, _, err := ReadFromReader(&buf, Columns{X: "2", Y: "1", Values: []string{"3"}},
+		Options{Delimiter: ' ', NoHeader: true})
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if len(data.Points) != 2 {
+		t.Fatalf("Expected 2 points, got %d", len(data.Points))
+	}
+	if p := data.Points[1]; p.X != 5 || p.Y != 4 || p.Value != 6 {
+		t.Errorf("Unexpected point %+v", p)
+	}
+}
+func TestReadDateAndTimeColumns(t *testing.T) {
+	input := `x,y,value,date,clock
+0,0,1,01.03.2024,12:30
+1,0,2,02.03.2024,
+`
+	data, report, err := ReadFromReader(strings.NewReader(input), Columns{T: "clock", Date: "date"},
+		Options{TimeLayouts: []string{"02.01.2006 15:04"}})
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if len(data.Points) != 1 || len(report.Skipped) != 1 {
+		t.Fatalf("Expected 1 point and 1 skipped row, got %d and %v", len(data.Points), report.Skipped)
+	}
+	if p := data.Points[0]; !p.HasTime || p.Time.Format(time.RFC3339) != "2024-03-01T12:30:00Z" {
+		t.Errorf("Unexpected time %v", p.Time)
+	}
+}
@@ -5,30 +5,119 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/mmaelicke/go-geostat/internal/types"
 )
 
-func WriteKrigCSVToWriter(w io.Writer, gridList types.Points, estimation []types.Estimation) error {
-	csvw := csv.NewWriter(w)
+// KrigCSVSink writes kriging estimations as CSV rows as soon as they are
+// produced. It implements types.EstimationSink.
+type KrigCSVSink struct {
+	csvw        *csv.Writer
+	is3D        bool
+	diagnostics bool
+	wroteHeader bool
+}
+
+// NewKrigCSVSink returns a sink writing x, y, (z,) value and variance rows to w.
+func NewKrigCSVSink(w io.Writer, is3D bool) *KrigCSVSink {
+	return &KrigCSVSink{
+		csvw: csv.NewWriter(w),
+		is3D: is3D,
+	}
+}
+
+// SetDiagnostics adds the kriging diagnostics as extra columns. Neighbor
+// indices and weights are written as semicolon separated lists.
+// It has to be called before the first estimation is written.
+func (s *KrigCSVSink) SetDiagnostics(on bool) {
+	s.diagnostics = on
+}
 
+func (s *KrigCSVSink) writeHeader() error {
 	header := []string{"x", "y"}
-	if gridList.Is3D {
+	if s.is3D {
 		header = append(header, "z")
 	}
 	header = append(header, "value", "variance")
-	csvw.Write(header)
+	if s.diagnostics {
+		header = append(header, "n_neighbors", "nearest_distance", "lagrange", "negative_weight_sum",
+			"slope_of_regression", "kriging_efficiency", "solver", "condition_number", "neighbors", "weights")
+	}
+	s.wroteHeader = true
+	return s.csvw.Write(header)
+}
+
+func (s *KrigCSVSink) Write(p types.Point, e types.Estimation) error {
+	if !s.wroteHeader {
+		if err := s.writeHeader(); err != nil {
+			return err
+		}
+	}
+	row := []string{fmt.Sprintf("%f", p.X), fmt.Sprintf("%f", p.Y)}
+	if s.is3D {
+		row = append(row, fmt.Sprintf("%f", p.Z))
+	}
+	row = append(row, fmt.Sprintf("%f", e.Field), fmt.Sprintf("%f", e.Variance))
+	if s.diagnostics {
+		row = append(row, diagnosticsRow(e.Diagnostics)...)
+	}
+	return s.csvw.Write(row)
+}
+
+// diagnosticsRow formats the diagnostics columns, which are empty for
+// estimations without diagnostics
+func diagnosticsRow(d *types.Diagnostics) []string {
+	if d == nil {
+		return make([]string, 10)
+	}
+	neighbors := make([]string, len(d.Neighbors))
+	for i, idx := range d.Neighbors {
+		neighbors[i] = strconv.Itoa(idx)
+	}
+	weights := make([]string, len(d.Weights))
+	for i, w := range d.Weights {
+		weights[i] = fmt.Sprintf("%f", w)
+	}
+	return []string{
+		fmt.Sprintf("%d", d.NumNeighbors),
+		fmt.Sprintf("%f", d.NearestDistance),
+		fmt.Sprintf("%f", d.Lagrange),
+		fmt.Sprintf("%f", d.NegativeWeightSum),
+		fmt.Sprintf("%f", d.SlopeOfRegression),
+		fmt.Sprintf("%f", d.KrigingEfficiency),
+		d.Solver,
+		fmt.Sprintf("%g", d.ConditionNumber),
+		strings.Join(neighbors, ";"),
+		strings.Join(weights, ";"),
+	}
+}
 
+func (s *KrigCSVSink) Flush() error {
+	if !s.wroteHeader {
+		if err := s.writeHeader(); err != nil {
+			return err
+		}
+	}
+	s.csvw.Flush()
+	return s.csvw.Error()
+}
+
+func WriteKrigCSVToWriter(w io.Writer, gridList types.Points, estimation []types.Estimation) error {
+	sink := NewKrigCSVSink(w, gridList.Is3D)
+	for _, e := range estimation {
+		if e.Diagnostics != nil {
+			sink.SetDiagnostics(true)
+			break
+		}
+	}
 	for i, p := range gridList.Points {
-		row := []string{fmt.Sprintf("%f", p.X), fmt.Sprintf("%f", p.Y)}
-		if gridList.Is3D {
-			row = append(row, fmt.Sprintf("%f", p.Z))
+		if err := sink.Write(p, estimation[i]); err != nil {
+			return err
 		}
-		row = append(row, fmt.Sprintf("%f", estimation[i].Field), fmt.Sprintf("%f", estimation[i].Variance))
-		csvw.Write(row)
 	}
-	csvw.Flush()
-	return csvw.Error()
+	return sink.Flush()
 }
 
 func WriteKrigCSV(path string, gridList types.Points, estimation []types.Estimation) error {
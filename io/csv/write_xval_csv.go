@@ -0,0 +1,60 @@
+package csv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mmaelicke/go-geostat/internal/crossval"
+)
+
+// WriteXvalCSVToWriter writes per-point cross-validation results, preceded by
+// the summary statistics as comment lines.
+func WriteXvalCSVToWriter(w io.Writer, results []crossval.Result, summary crossval.Summary, is3D bool) error {
+	metadata := fmt.Sprintf("# n: %d, failed: %d, ME: %f, MAE: %f, RMSE: %f, MSDR: %f, correlation: %f\n",
+		summary.N, summary.Failed, summary.ME, summary.MAE, summary.RMSE, summary.MSDR, summary.Correlation)
+	if _, err := w.Write([]byte(metadata)); err != nil {
+		return err
+	}
+
+	csvw := csv.NewWriter(w)
+	header := []string{"index", "fold", "x", "y"}
+	if is3D {
+		header = append(header, "z")
+	}
+	header = append(header, "observed", "predicted", "variance", "error", "std_error")
+	csvw.Write(header)
+
+	for _, r := range results {
+		row := []string{
+			fmt.Sprintf("%d", r.Index),
+			fmt.Sprintf("%d", r.Fold),
+			fmt.Sprintf("%f", r.Point.X),
+			fmt.Sprintf("%f", r.Point.Y),
+		}
+		if is3D {
+			row = append(row, fmt.Sprintf("%f", r.Point.Z))
+		}
+		row = append(row,
+			fmt.Sprintf("%f", r.Observed),
+			fmt.Sprintf("%f", r.Predicted),
+			fmt.Sprintf("%f", r.Variance),
+			fmt.Sprintf("%f", r.Error),
+			fmt.Sprintf("%f", r.StdError),
+		)
+		csvw.Write(row)
+	}
+	csvw.Flush()
+	return csvw.Error()
+}
+
+func WriteXvalCSV(path string, results []crossval.Result, summary crossval.Summary, is3D bool) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer f.Close()
+
+	return WriteXvalCSVToWriter(f, results, summary, is3D)
+}
@@ -0,0 +1,137 @@
+package geojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmaelicke/go-geostat/internal/crossval"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+func TestReadPointsFromReader(t *testing.T) {
+	input := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [1, 2]}, "properties": {"zinc": 10, "lead": "5"}},
+		{"type": "Feature", "geometry": {"type": "MultiPoint", "coordinates": [[3, 4], [5, 6]]}, "properties": {"zinc": null, "lead": 7}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [7, 8]}, "properties": {"zinc": null}},
+		{"type": "Feature", "geometry": null, "properties": {"zinc": 1}}
+	]}`
+	points, err := ReadPointsFromReader(strings.NewReader(input), Properties{Values: []string{"zinc", "lead"}})
+	if err != nil {
+		t.Fatalf("ReadPointsFromReader() error = %v", err)
+	}
+	if len(points.Points) != 3 || points.Is3D {
+		t.Fatalf("got %d points, 3D %v, want 3 2D points", len(points.Points), points.Is3D)
+	}
+	if p := points.Points[0]; p.X != 1 || p.Y != 2 || p.Value != 10 || p.Attributes["lead"] != 5 {
+		t.Errorf("unexpected first point %+v", p)
+	}
+	if p := points.Points[2]; p.X != 5 || !math.IsNaN(p.Value) || p.Attributes["lead"] != 7 {
+		t.Errorf("unexpected last point %+v", p)
+	}
+}
+
+func TestReadPointsFromReader3D(t *testing.T) {
+	input := `{"type": "Feature", "geometry": {"type": "Point", "coordinates": [1, 2, 3]}, "properties": {"value": 4}}`
+	points, err := ReadPointsFromReader(strings.NewReader(input), Properties{})
+	if err != nil {
+		t.Fatalf("ReadPointsFromReader() error = %v", err)
+	}
+	if !points.Is3D || points.Points[0].Z != 3 {
+		t.Errorf("expected a 3D point, got %+v", points)
+	}
+
+	mixed := `{"type": "Feature", "geometry": {"type": "MultiPoint", "coordinates": [[1, 2, 3], [1, 2]]}, "properties": {"value": 4}}`
+	if _, err := ReadPointsFromReader(strings.NewReader(mixed), Properties{}); err == nil {
+		t.Error("expected an error for mixed 2D and 3D positions")
+	}
+}
+
+func TestKrigGeoJSONSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewKrigGeoJSONSink(&buf, false)
+	sink.Write(types.Point{X: 1, Y: 2}, types.Estimation{Field: 3, Variance: 0.5})
+	sink.Write(types.Point{X: 4, Y: 5}, types.Estimation{Field: math.NaN(), Variance: math.NaN(), ErrCode: types.ErrMasked})
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var fc struct {
+		Type     string
+		Features []struct {
+			Geometry   struct{ Coordinates []float64 }
+			Properties map[string]any
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &fc); err != nil {
+		t.Fatalf("invalid GeoJSON: %v\n%s", err, buf.String())
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 2 {
+		t.Fatalf("unexpected collection %+v", fc)
+	}
+	if v := fc.Features[0].Properties["value"]; v != 3.0 {
+		t.Errorf("value = %v, want 3", v)
+	}
+	if props := fc.Features[1].Properties; props["value"] != nil || props["error"] != "masked" {
+		t.Errorf("unexpected properties of a masked estimation: %v", props)
+	}
+}
+
+func TestWriteXvalGeoJSONToWriter(t *testing.T) {
+	nan := math.NaN()
+	results := []crossval.Result{
+		{Index: 0, Point: types.Point{X: 1, Y: 2}, Observed: 3, Predicted: 2.5, Variance: 0.25, Error: -0.5, StdError: -1},
+		{Index: 1, Fold: 1, Point: types.Point{X: 4, Y: 5}, Observed: nan, Predicted: nan, Variance: nan, Error: nan, StdError: nan},
+	}
+	summary := crossval.Summarize(results)
+	var buf bytes.Buffer
+	if err := WriteXvalGeoJSONToWriter(&buf, results, summary, false); err != nil {
+		t.Fatalf("WriteXvalGeoJSONToWriter() error = %v", err)
+	}
+
+	var fc struct {
+		Summary  map[string]any
+		Features []struct {
+			Geometry   struct{ Coordinates []float64 }
+			Properties map[string]any
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &fc); err != nil {
+		t.Fatalf("invalid GeoJSON: %v\n%s", err, buf.String())
+	}
+	if len(fc.Features) != 2 || fc.Summary["n"] != 1.0 || fc.Summary["failed"] != 1.0 {
+		t.Fatalf("unexpected collection %+v", fc)
+	}
+	if props := fc.Features[0].Properties; props["observed"] != 3.0 || props["predicted"] != 2.5 || props["std_error"] != -1.0 {
+		t.Errorf("unexpected properties %v", props)
+	}
+	// NaN values of the failed row are null
+	for _, name := range []string{"observed", "predicted", "variance", "error", "std_error"} {
+		if v, ok := fc.Features[1].Properties[name]; !ok || v != nil {
+			t.Errorf("%s = %v, want null", name, v)
+		}
+	}
+}
+
+func TestReadPointsDateAndTime(t *testing.T) {
+	input := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [1, 2]}, "properties": {"value": 1, "day": "2024-03-01", "at": "14:30"}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [3, 4]}, "properties": {"value": 2, "day": "2024-03-02", "at": null}}
+	]}`
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	points, err := ReadPointsFromReader(strings.NewReader(input), Properties{Time: "at", Date: "day", Location: zone})
+	if err != nil {
+		t.Fatalf("ReadPointsFromReader() error = %v", err)
+	}
+	// the feature without a time of day is skipped
+	if len(points.Points) != 1 {
+		t.Fatalf("expected 1 point, got %+v", points)
+	}
+	want := time.Date(2024, 3, 1, 14, 30, 0, 0, zone)
+	if p := points.Points[0]; !p.HasTime || !p.Time.Equal(want) {
+		t.Errorf("time = %v, want %v", p.Time, want)
+	}
+}
@@ -0,0 +1,229 @@
+// Package geojson reads point data from and writes estimations and
+// cross-validation results to GeoJSON, for use in web mapping workflows.
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmaelicke/go-geostat/internal/timeaxis"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// Properties maps the point fields to feature properties. An empty Values
+// list falls back to a single value property.
+type Properties struct {
+	// Values are read into Point.Attributes. The first one is also Point.Value.
+	Values []string
+	// Covariates are read into Point.Covariates.
+	Covariates []string
+	// Group is an optional property of group ids, e.g. drillhole names.
+	Group string
+	// ErrorVariance is an optional property of measurement error variances.
+	ErrorVariance string
+	// Time is an optional property of timestamps, parsed by trying
+	// TimeLayouts in order. They default to timeaxis.DefaultLayouts, ISO 8601
+	// with and without time zone; times without zone are in Location, which
+	// defaults to UTC.
+	Time        string
+	TimeLayouts []string
+	Location    *time.Location
+	// Date is an optional property of dates. If set, the time property holds
+	// the time of day, and both are joined by a space before parsing.
+	Date string
+}
+
+// object holds the members of any GeoJSON object needed to find points
+type object struct {
+	Type        string                     `json:"type"`
+	Coordinates json.RawMessage            `json:"coordinates"`
+	Geometry    *object                    `json:"geometry"`
+	Features    []object                   `json:"features"`
+	Properties  map[string]json.RawMessage `json:"properties"`
+}
+
+// ReadPointsFromReader reads the Point and MultiPoint features of a GeoJSON
+// FeatureCollection or Feature. Points are 3D if their positions have three
+// coordinates. A feature is skipped if none of its value properties is a
+// number, or if it lacks a covariate; single missing values are stored as
+// NaN. Numbers given as strings are accepted.
+func ReadPointsFromReader(r io.Reader, props Properties) (types.Points, error) {
+	var obj object
+	if err := json.NewDecoder(r).Decode(&obj); err != nil {
+		return types.Points{}, fmt.Errorf("failed to decode GeoJSON: %w", err)
+	}
+	if len(props.Values) == 0 {
+		props.Values = []string{"value"}
+	}
+
+	times := timeaxis.Parser{Layouts: props.TimeLayouts, Location: props.Location}
+
+	var features []object
+	switch obj.Type {
+	case "FeatureCollection":
+		features = obj.Features
+	case "Feature":
+		features = []object{obj}
+	default:
+		return types.Points{}, fmt.Errorf("expected a FeatureCollection or Feature, got %q", obj.Type)
+	}
+
+	points := types.Points{Points: make([]types.Point, 0, len(features))}
+	dims := 0
+	for i, f := range features {
+		if f.Geometry == nil {
+			continue
+		}
+		positions, err := f.Geometry.positions()
+		if err != nil {
+			return types.Points{}, fmt.Errorf("feature %d: %w", i, err)
+		}
+		point, ok := props.point(f.Properties, times)
+		if !ok {
+			continue
+		}
+		for _, pos := range positions {
+			if len(pos) < 2 {
+				return types.Points{}, fmt.Errorf("feature %d: position with %d coordinates", i, len(pos))
+			}
+			n := min(len(pos), 3)
+			if dims == 0 {
+				dims = n
+			} else if n != dims {
+				return types.Points{}, fmt.Errorf("feature %d: mixed 2D and 3D positions", i)
+			}
+			p := point
+			p.X, p.Y = pos[0], pos[1]
+			if n == 3 {
+				p.Z = pos[2]
+				p.Is3D = true
+			}
+			points.Points = append(points.Points, p)
+		}
+	}
+	points.Is3D = dims == 3
+	return points, nil
+}
+
+// ReadPoints opens path and reads it with ReadPointsFromReader.
+func ReadPoints(path string, props Properties) (types.Points, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return types.Points{}, err
+	}
+	defer f.Close()
+	return ReadPointsFromReader(f, props)
+}
+
+// positions returns the positions of a Point or MultiPoint geometry
+func (g *object) positions() ([][]float64, error) {
+	switch g.Type {
+	case "Point":
+		var pos []float64
+		if err := json.Unmarshal(g.Coordinates, &pos); err != nil {
+			return nil, fmt.Errorf("invalid Point coordinates: %w", err)
+		}
+		return [][]float64{pos}, nil
+	case "MultiPoint":
+		var pos [][]float64
+		if err := json.Unmarshal(g.Coordinates, &pos); err != nil {
+			return nil, fmt.Errorf("invalid MultiPoint coordinates: %w", err)
+		}
+		return pos, nil
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %q, need Point or MultiPoint", g.Type)
+	}
+}
+
+// point returns a point carrying the selected properties, and false if the
+// feature has to be skipped
+func (props Properties) point(properties map[string]json.RawMessage, times timeaxis.Parser) (types.Point, bool) {
+	point := types.Point{Attributes: make(map[string]float64, len(props.Values))}
+	parsed := 0
+	for _, name := range props.Values {
+		v, ok := number(properties[name])
+		if !ok {
+			v = math.NaN()
+		} else {
+			parsed++
+		}
+		point.Attributes[name] = v
+	}
+	if parsed == 0 {
+		return point, false
+	}
+	point.Value = point.Attributes[props.Values[0]]
+
+	if len(props.Covariates) > 0 {
+		point.Covariates = make(map[string]float64, len(props.Covariates))
+	}
+	for _, name := range props.Covariates {
+		v, ok := number(properties[name])
+		if !ok {
+			return point, false
+		}
+		point.Covariates[name] = v
+	}
+
+	if props.Group != "" {
+		point.Group = text(properties[props.Group])
+	}
+	if props.ErrorVariance != "" {
+		v, ok := number(properties[props.ErrorVariance])
+		if !ok || v < 0 {
+			return point, false
+		}
+		point.ErrorVariance = v
+	}
+	if props.Time != "" || props.Date != "" {
+		var parts []string
+		for _, name := range []string{props.Date, props.Time} {
+			if name == "" {
+				continue
+			}
+			part := text(properties[name])
+			if part == "" {
+				return point, false
+			}
+			parts = append(parts, part)
+		}
+		t, err := times.Parse(strings.Join(parts, " "))
+		if err != nil {
+			return point, false
+		}
+		point.Time = t
+		point.HasTime = true
+	}
+	return point, true
+}
+
+// number returns the value of a number or numeric string property
+func number(raw json.RawMessage) (float64, bool) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, false
+	}
+	var v float64
+	if err := json.Unmarshal(raw, &v); err == nil {
+		return v, true
+	}
+	v, err := strconv.ParseFloat(text(raw), 64)
+	return v, err == nil && !math.IsNaN(v)
+}
+
+// text returns a string property, or the JSON text of any other property
+func text(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
@@ -0,0 +1,168 @@
+package geojson
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/mmaelicke/go-geostat/internal/crossval"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+type geometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type feature struct {
+	Type       string   `json:"type"`
+	Geometry   geometry `json:"geometry"`
+	Properties any      `json:"properties"`
+}
+
+// pointFeature returns a Point feature at p
+func pointFeature(p types.Point, is3D bool, properties any) feature {
+	coords := []float64{p.X, p.Y}
+	if is3D {
+		coords = append(coords, p.Z)
+	}
+	return feature{
+		Type:       "Feature",
+		Geometry:   geometry{Type: "Point", Coordinates: coords},
+		Properties: properties,
+	}
+}
+
+// numberOrNull returns nil for values JSON cannot represent, which encode
+// as null
+func numberOrNull(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+type krigProperties struct {
+	Value    *float64 `json:"value"`
+	Variance *float64 `json:"variance"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// KrigGeoJSONSink writes kriging estimations as Point features of a
+// FeatureCollection as soon as they are produced. Failed estimations have a
+// null value and variance and name the failure in the error property. It
+// implements types.EstimationSink.
+type KrigGeoJSONSink struct {
+	w       *bufio.Writer
+	is3D    bool
+	started bool
+}
+
+// NewKrigGeoJSONSink returns a sink writing a FeatureCollection to w.
+func NewKrigGeoJSONSink(w io.Writer, is3D bool) *KrigGeoJSONSink {
+	return &KrigGeoJSONSink{w: bufio.NewWriter(w), is3D: is3D}
+}
+
+func (s *KrigGeoJSONSink) Write(p types.Point, e types.Estimation) error {
+	props := krigProperties{Value: numberOrNull(e.Field), Variance: numberOrNull(e.Variance)}
+	if e.ErrCode != types.ErrNone {
+		props.Error = e.ErrCode.String()
+	}
+	b, err := json.Marshal(pointFeature(p, s.is3D, props))
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if !s.started {
+		sep = `{"type":"FeatureCollection","features":[` + "\n"
+		s.started = true
+	}
+	if _, err := s.w.WriteString(sep); err != nil {
+		return err
+	}
+	_, err = s.w.Write(b)
+	return err
+}
+
+func (s *KrigGeoJSONSink) Flush() error {
+	end := "\n]}\n"
+	if !s.started {
+		end = `{"type":"FeatureCollection","features":[]}` + "\n"
+	}
+	if _, err := s.w.WriteString(end); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+type xvalProperties struct {
+	Index     int      `json:"index"`
+	Fold      int      `json:"fold"`
+	Observed  *float64 `json:"observed"`
+	Predicted *float64 `json:"predicted"`
+	Variance  *float64 `json:"variance"`
+	Error     *float64 `json:"error"`
+	StdError  *float64 `json:"std_error"`
+}
+
+type xvalSummary struct {
+	N           int      `json:"n"`
+	Failed      int      `json:"failed"`
+	ME          *float64 `json:"me"`
+	MAE         *float64 `json:"mae"`
+	RMSE        *float64 `json:"rmse"`
+	MSDR        *float64 `json:"msdr"`
+	Correlation *float64 `json:"correlation"`
+}
+
+type xvalCollection struct {
+	Type     string      `json:"type"`
+	Summary  xvalSummary `json:"summary"`
+	Features []feature   `json:"features"`
+}
+
+// WriteXvalGeoJSONToWriter writes cross-validation results as Point features
+// of a FeatureCollection, with the summary statistics as foreign member
+// summary of the collection.
+func WriteXvalGeoJSONToWriter(w io.Writer, results []crossval.Result, summary crossval.Summary, is3D bool) error {
+	fc := xvalCollection{
+		Type: "FeatureCollection",
+		Summary: xvalSummary{
+			N:           summary.N,
+			Failed:      summary.Failed,
+			ME:          numberOrNull(summary.ME),
+			MAE:         numberOrNull(summary.MAE),
+			RMSE:        numberOrNull(summary.RMSE),
+			MSDR:        numberOrNull(summary.MSDR),
+			Correlation: numberOrNull(summary.Correlation),
+		},
+		Features: make([]feature, len(results)),
+	}
+	for i, r := range results {
+		fc.Features[i] = pointFeature(r.Point, is3D, xvalProperties{
+			Index:     r.Index,
+			Fold:      r.Fold,
+			Observed:  numberOrNull(r.Observed),
+			Predicted: numberOrNull(r.Predicted),
+			Variance:  numberOrNull(r.Variance),
+			Error:     numberOrNull(r.Error),
+			StdError:  numberOrNull(r.StdError),
+		})
+	}
+	return json.NewEncoder(w).Encode(fc)
+}
+
+// WriteXvalGeoJSON creates path and writes the results with
+// WriteXvalGeoJSONToWriter.
+func WriteXvalGeoJSON(path string, results []crossval.Result, summary crossval.Summary, is3D bool) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer f.Close()
+
+	return WriteXvalGeoJSONToWriter(f, results, summary, is3D)
+}
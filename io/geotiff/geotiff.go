@@ -0,0 +1,63 @@
+// Package geotiff reads and writes single- and multi-band floating point
+// GeoTIFF rasters in pure Go.
+//
+// Rasters are written uncompressed with one strip per row and pixel
+// interleaved bands, so that estimations can be streamed to disk in the row
+// order of grid.Spec. Ensembles of SGS realizations store the bands one after
+// another instead, one strip per row and band, so that every realization is
+// written as soon as it is complete. The georeference is stored as pixel scale and tie point,
+// with an optional EPSG code and the GDAL nodata tag.
+package geotiff
+
+// TIFF tags
+const (
+	tagImageWidth          = 256
+	tagImageLength         = 257
+	tagBitsPerSample       = 258
+	tagCompression         = 259
+	tagPhotometric         = 262
+	tagStripOffsets        = 273
+	tagSamplesPerPixel     = 277
+	tagRowsPerStrip        = 278
+	tagStripByteCounts     = 279
+	tagPlanarConfiguration = 284
+	tagPredictor           = 317
+	tagTileWidth           = 322
+	tagTileLength          = 323
+	tagTileOffsets         = 324
+	tagTileByteCounts      = 325
+	tagExtraSamples        = 338
+	tagSampleFormat        = 339
+	tagModelPixelScale     = 33550
+	tagModelTiepoint       = 33922
+	tagGeoKeyDirectory     = 34735
+	tagGDALNoData          = 42113
+)
+
+// TIFF field types
+const (
+	typeASCII  = 2
+	typeShort  = 3
+	typeLong   = 4
+	typeDouble = 12
+)
+
+// GeoKeys
+const (
+	keyModelType       = 1024
+	keyRasterType      = 1025
+	keyGeographicType  = 2048
+	keyProjectedCSType = 3072
+
+	modelProjected  = 1
+	modelGeographic = 2
+	rasterPixelArea = 1
+	rasterPixel     = 2
+)
+
+// Sample formats
+const (
+	sampleUint  = 1
+	sampleInt   = 2
+	sampleFloat = 3
+)
@@ -0,0 +1,191 @@
+package geotiff
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+func TestSinkRoundTrip(t *testing.T) {
+	spec := grid.Spec{X0: 0.5, Y0: 10.5, DX: 1, DY: 2, NX: 3, NY: 2}
+	for _, wide := range []bool{false, true} {
+		var buf bytes.Buffer
+		opts := Options{Float64: wide, NoData: -9999, EPSG: 25832}
+		field := func(e types.Estimation) float64 { return e.Field }
+		variance := func(e types.Estimation) float64 { return e.Variance }
+		sink, err := NewSink(&buf, spec, opts, field, variance)
+		if err != nil {
+			t.Fatalf("NewSink() error = %v", err)
+		}
+		for i := 0; i < spec.Len(); i++ {
+			e := types.Estimation{Field: value(i), Variance: 1}
+			if i == 4 {
+				e.Field = math.NaN()
+			}
+			if err := sink.Write(spec.At(i), e); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		}
+		if err := sink.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+
+		got, values, err := Read(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		if err := spec.Aligned(got); err != nil {
+			t.Errorf("read grid does not align: %v", err)
+		}
+		for i, v := range values {
+			if i == 4 {
+				if !math.IsNaN(v) {
+					t.Errorf("nodata should be read as NaN, got %v", v)
+				}
+				continue
+			}
+			if v != value(i) {
+				t.Errorf("value %d = %v, want %v", i, v, value(i))
+			}
+		}
+	}
+}
+
+func value(i int) float64 {
+	return float64(i) + 0.5
+}
+
+func TestSinkIncomplete(t *testing.T) {
+	spec := grid.Spec{DX: 1, DY: 1, NX: 2, NY: 2}
+	var buf bytes.Buffer
+	sink, err := NewSink(&buf, spec, DefaultOptions(), func(e types.Estimation) float64 { return e.Field })
+	if err != nil {
+		t.Fatalf("NewSink() error = %v", err)
+	}
+	if err := sink.Flush(); err == nil {
+		t.Error("expected an error for an incomplete grid")
+	}
+}
+
+func TestWriteBandsLength(t *testing.T) {
+	spec := grid.Spec{DX: 1, DY: 1, NX: 2, NY: 2}
+	var buf bytes.Buffer
+	if err := WriteBands(&buf, spec, DefaultOptions(), []float64{1, 2, 3}); err == nil {
+		t.Error("expected an error for a band of the wrong length")
+	}
+}
+
+func TestWriteBandsRoundTrip(t *testing.T) {
+	spec := grid.Spec{X0: 0.5, Y0: 0.5, DX: 1, DY: 1, NX: 3, NY: 2}
+	first := make([]float64, spec.Len())
+	second := make([]float64, spec.Len())
+	for i := range first {
+		first[i], second[i] = value(i), -value(i)
+	}
+	var buf bytes.Buffer
+	if err := WriteBands(&buf, spec, DefaultOptions(), first, second); err != nil {
+		t.Fatalf("WriteBands() error = %v", err)
+	}
+	_, values, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	for i, v := range values {
+		if v != first[i] {
+			t.Errorf("value %d = %v, want %v", i, v, first[i])
+		}
+	}
+}
+
+func TestSinkEPSGRange(t *testing.T) {
+	spec := grid.Spec{DX: 1, DY: 1, NX: 2, NY: 2}
+	field := func(e types.Estimation) float64 { return e.Field }
+	for _, code := range []int{-1, 70000} {
+		opts := DefaultOptions()
+		opts.EPSG = code
+		if _, err := NewSink(&bytes.Buffer{}, spec, opts, field); err == nil {
+			t.Errorf("expected an error for EPSG code %d", code)
+		}
+	}
+}
+
+func TestSinkModelType(t *testing.T) {
+	spec := grid.Spec{DX: 1, DY: 1, NX: 2, NY: 2}
+	field := func(e types.Estimation) float64 { return e.Field }
+	for _, opts := range []Options{
+		{EPSG: 25832},
+		{EPSG: 4326},
+		{EPSG: 4326, Geographic: true},
+		{EPSG: 7844, Geographic: true},
+	} {
+		var buf bytes.Buffer
+		sink, err := NewSink(&buf, spec, opts, field)
+		if err != nil {
+			t.Fatalf("NewSink() error = %v", err)
+		}
+		for i := 0; i < spec.Len(); i++ {
+			if err := sink.Write(spec.At(i), types.Estimation{}); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		}
+		if err := sink.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		// the model type follows the option, not the EPSG code
+		model, key := uint16(modelProjected), uint16(keyProjectedCSType)
+		if opts.Geographic {
+			model, key = modelGeographic, keyGeographicType
+		}
+		b := buf.Bytes()
+		if !bytes.Contains(b, shorts(keyModelType, 0, 1, model)) || !bytes.Contains(b, shorts(key, 0, 1, uint16(opts.EPSG))) {
+			t.Errorf("%+v: missing model type %d with EPSG key %d", opts, model, key)
+		}
+	}
+}
+
+func TestEnsemble(t *testing.T) {
+	spec := grid.Spec{X0: 0.5, Y0: 0.5, DX: 1, DY: 1, NX: 3, NY: 2}
+	f, err := os.Create(filepath.Join(t.TempDir(), "sgs.tif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ens, err := NewEnsemble(f, spec, 3, DefaultOptions())
+	if err != nil {
+		t.Fatalf("NewEnsemble() error = %v", err)
+	}
+	// realizations may complete in any order
+	for _, sim := range []int{2, 0, 1} {
+		sink, err := ens.Realization(sim)
+		if err != nil {
+			t.Fatalf("Realization(%d) error = %v", sim, err)
+		}
+		for i := 0; i < spec.Len(); i++ {
+			if err := sink.Write(spec.At(i), types.Estimation{Field: float64(10*sim) + value(i)}); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		}
+		if err := sink.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+	}
+	if _, err := ens.Realization(3); err == nil {
+		t.Error("expected an error for a realization out of range")
+	}
+
+	// the reader returns the first band
+	_, values, err := Read(f)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	for i, v := range values {
+		if v != value(i) {
+			t.Errorf("value %d = %v, want %v", i, v, value(i))
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package geotiff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+)
+
+// field is a directory entry with its raw value bytes
+type field struct {
+	typ   uint16
+	count uint32
+	raw   []byte
+}
+
+// typeSize returns the byte size of a single value of TIFF field type typ
+func typeSize(typ uint16) int {
+	switch typ {
+	case 1, 2, 6, 7:
+		return 1
+	case 3, 8:
+		return 2
+	case 4, 9, 11:
+		return 4
+	case 5, 10, 12:
+		return 8
+	}
+	return 0
+}
+
+// ifd is a decoded image file directory
+type ifd struct {
+	order  binary.ByteOrder
+	fields map[uint16]field
+}
+
+func (d ifd) has(tag uint16) bool {
+	_, ok := d.fields[tag]
+	return ok
+}
+
+// ints returns the values of an integer field
+func (d ifd) ints(tag uint16) []int {
+	f, ok := d.fields[tag]
+	if !ok {
+		return nil
+	}
+	v := make([]int, f.count)
+	for i := range v {
+		switch f.typ {
+		case 1, 7:
+			v[i] = int(f.raw[i])
+		case 6:
+			v[i] = int(int8(f.raw[i]))
+		case 3:
+			v[i] = int(d.order.Uint16(f.raw[2*i:]))
+		case 8:
+			v[i] = int(int16(d.order.Uint16(f.raw[2*i:])))
+		case 4:
+			v[i] = int(d.order.Uint32(f.raw[4*i:]))
+		case 9:
+			v[i] = int(int32(d.order.Uint32(f.raw[4*i:])))
+		}
+	}
+	return v
+}
+
+// int returns the first value of an integer field, or def if it is missing
+func (d ifd) int(tag uint16, def int) int {
+	if v := d.ints(tag); len(v) > 0 {
+		return v[0]
+	}
+	return def
+}
+
+// floats returns the values of a floating point field
+func (d ifd) floats(tag uint16) []float64 {
+	f, ok := d.fields[tag]
+	if !ok {
+		return nil
+	}
+	v := make([]float64, f.count)
+	for i := range v {
+		switch f.typ {
+		case 11:
+			v[i] = float64(math.Float32frombits(d.order.Uint32(f.raw[4*i:])))
+		case 12:
+			v[i] = math.Float64frombits(d.order.Uint64(f.raw[8*i:]))
+		}
+	}
+	return v
+}
+
+// readIFD reads the first image file directory of the TIFF in r
+func readIFD(r io.ReaderAt) (ifd, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return ifd{}, fmt.Errorf("failed to read TIFF header: %w", err)
+	}
+	d := ifd{fields: make(map[uint16]field)}
+	switch string(header[:2]) {
+	case "II":
+		d.order = binary.LittleEndian
+	case "MM":
+		d.order = binary.BigEndian
+	default:
+		return ifd{}, fmt.Errorf("not a TIFF file")
+	}
+	switch d.order.Uint16(header[2:]) {
+	case 42:
+	case 43:
+		return ifd{}, fmt.Errorf("BigTIFF files are not supported")
+	default:
+		return ifd{}, fmt.Errorf("not a TIFF file")
+	}
+
+	offset := int64(d.order.Uint32(header[4:]))
+	b := make([]byte, 2)
+	if _, err := r.ReadAt(b, offset); err != nil {
+		return ifd{}, fmt.Errorf("failed to read directory: %w", err)
+	}
+	n := int(d.order.Uint16(b))
+	entries := make([]byte, 12*n)
+	if _, err := r.ReadAt(entries, offset+2); err != nil {
+		return ifd{}, fmt.Errorf("failed to read directory: %w", err)
+	}
+	for i := 0; i < n; i++ {
+		e := entries[12*i : 12*i+12]
+		f := field{typ: d.order.Uint16(e[2:]), count: d.order.Uint32(e[4:])}
+		size := typeSize(f.typ) * int(f.count)
+		if size == 0 {
+			// unknown types cannot be interpreted and are skipped
+			continue
+		}
+		if size <= 4 {
+			f.raw = e[8 : 8+size]
+		} else {
+			f.raw = make([]byte, size)
+			if _, err := r.ReadAt(f.raw, int64(d.order.Uint32(e[8:]))); err != nil {
+				return ifd{}, fmt.Errorf("failed to read tag %d: %w", d.order.Uint16(e), err)
+			}
+		}
+		d.fields[d.order.Uint16(e)] = f
+	}
+	return d, nil
+}
+
+// spec derives the grid spec from the pixel scale and tie point
+func (d ifd) spec() (grid.Spec, error) {
+	spec := grid.Spec{
+		NX:           d.int(tagImageWidth, 0),
+		NY:           d.int(tagImageLength, 0),
+		Registration: grid.CellCorner,
+	}
+	scale := d.floats(tagModelPixelScale)
+	tie := d.floats(tagModelTiepoint)
+	if len(scale) < 2 || len(tie) < 6 {
+		return spec, fmt.Errorf("GeoTIFF has no pixel scale and tie point")
+	}
+	spec.DX, spec.DY = scale[0], scale[1]
+	x := tie[3] - tie[0]*spec.DX
+	y := tie[4] + tie[1]*spec.DY
+	if d.rasterType() == rasterPixel {
+		// the tie point refers to the centre of the pixel
+		x -= spec.DX / 2
+		y += spec.DY / 2
+	}
+	spec.X0 = x
+	spec.Y0 = y - float64(spec.NY)*spec.DY
+	return spec, spec.Validate()
+}
+
+// rasterType returns the GTRasterTypeGeoKey, PixelIsArea by default
+func (d ifd) rasterType() int {
+	keys := d.ints(tagGeoKeyDirectory)
+	for i := 4; i+3 < len(keys); i += 4 {
+		// only keys stored in the directory itself are considered
+		if keys[i] == keyRasterType && keys[i+1] == 0 {
+			return keys[i+3]
+		}
+	}
+	return rasterPixelArea
+}
+
+// nodata returns the GDAL nodata value, or NaN if there is none
+func (d ifd) nodata() float64 {
+	f, ok := d.fields[tagGDALNoData]
+	if !ok {
+		return math.NaN()
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimRight(string(f.raw), "\x00")), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return v
+}
+
+// Read reads the first band of a GeoTIFF. It returns the grid spec and the
+// cell values in the order of grid.Spec.At, with nodata cells as NaN.
+// Striped and tiled rasters with integer or floating point samples are
+// supported, either uncompressed or deflate compressed without predictor.
+func Read(r io.ReaderAt) (grid.Spec, []float64, error) {
+	d, err := readIFD(r)
+	if err != nil {
+		return grid.Spec{}, nil, err
+	}
+	spec, err := d.spec()
+	if err != nil {
+		return grid.Spec{}, nil, err
+	}
+
+	compression := d.int(tagCompression, 1)
+	if compression != 1 && compression != 8 && compression != 32946 {
+		return grid.Spec{}, nil, fmt.Errorf("unsupported compression %d", compression)
+	}
+	if p := d.int(tagPredictor, 1); p != 1 {
+		return grid.Spec{}, nil, fmt.Errorf("unsupported predictor %d", p)
+	}
+	bits := d.int(tagBitsPerSample, 1)
+	format := d.int(tagSampleFormat, sampleUint)
+	decode, err := decoder(d.order, bits, format)
+	if err != nil {
+		return grid.Spec{}, nil, err
+	}
+	sampleBytes := bits / 8
+	samples := d.int(tagSamplesPerPixel, 1)
+	pixelBytes := samples * sampleBytes
+	if d.int(tagPlanarConfiguration, 1) == 2 {
+		// the chunks of the first band come first
+		pixelBytes = sampleBytes
+	}
+
+	// strips are handled as tiles spanning the full width
+	width, height := spec.NX, d.int(tagRowsPerStrip, spec.NY)
+	offsets, counts := d.ints(tagStripOffsets), d.ints(tagStripByteCounts)
+	if d.has(tagTileOffsets) {
+		width, height = d.int(tagTileWidth, 0), d.int(tagTileLength, 0)
+		offsets, counts = d.ints(tagTileOffsets), d.ints(tagTileByteCounts)
+	}
+	if width < 1 || height < 1 || len(offsets) == 0 || len(offsets) != len(counts) {
+		return grid.Spec{}, nil, fmt.Errorf("invalid strip or tile layout")
+	}
+	across := (spec.NX + width - 1) / width
+	down := (spec.NY + height - 1) / height
+	if len(offsets) < across*down {
+		return grid.Spec{}, nil, fmt.Errorf("expected %d strips or tiles, got %d", across*down, len(offsets))
+	}
+
+	nodata := d.nodata()
+	if bits == 32 && format == sampleFloat {
+		nodata = float64(float32(nodata))
+	}
+	values := make([]float64, spec.Len())
+	for c := 0; c < across*down; c++ {
+		chunk, err := readChunk(r, int64(offsets[c]), counts[c], compression)
+		if err != nil {
+			return grid.Spec{}, nil, fmt.Errorf("failed to read chunk %d: %w", c, err)
+		}
+		col0, row0 := (c%across)*width, (c/across)*height
+		for row := 0; row < height && row0+row < spec.NY; row++ {
+			for col := 0; col < width && col0+col < spec.NX; col++ {
+				at := (row*width + col) * pixelBytes
+				if at+sampleBytes > len(chunk) {
+					return grid.Spec{}, nil, fmt.Errorf("chunk %d is truncated", c)
+				}
+				v := decode(chunk[at:])
+				if v == nodata {
+					v = math.NaN()
+				}
+				values[spec.Offset(col0+col, row0+row, 0)] = v
+			}
+		}
+	}
+	return spec, values, nil
+}
+
+// readChunk reads and decompresses a strip or tile
+func readChunk(r io.ReaderAt, offset int64, size, compression int) ([]byte, error) {
+	b := make([]byte, size)
+	if _, err := r.ReadAt(b, offset); err != nil {
+		return nil, err
+	}
+	if compression == 1 {
+		return b, nil
+	}
+	z, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	return io.ReadAll(z)
+}
+
+// decoder returns the function decoding a single sample
+func decoder(order binary.ByteOrder, bits, format int) (func([]byte) float64, error) {
+	switch {
+	case format == sampleFloat && bits == 32:
+		return func(b []byte) float64 { return float64(math.Float32frombits(order.Uint32(b))) }, nil
+	case format == sampleFloat && bits == 64:
+		return func(b []byte) float64 { return math.Float64frombits(order.Uint64(b)) }, nil
+	case format == sampleUint && bits == 8:
+		return func(b []byte) float64 { return float64(b[0]) }, nil
+	case format == sampleInt && bits == 8:
+		return func(b []byte) float64 { return float64(int8(b[0])) }, nil
+	case format == sampleUint && bits == 16:
+		return func(b []byte) float64 { return float64(order.Uint16(b)) }, nil
+	case format == sampleInt && bits == 16:
+		return func(b []byte) float64 { return float64(int16(order.Uint16(b))) }, nil
+	case format == sampleUint && bits == 32:
+		return func(b []byte) float64 { return float64(order.Uint32(b)) }, nil
+	case format == sampleInt && bits == 32:
+		return func(b []byte) float64 { return float64(int32(order.Uint32(b))) }, nil
+	}
+	return nil, fmt.Errorf("unsupported sample format %d with %d bits", format, bits)
+}
+
+// ReadFile opens path and reads it with Read.
+func ReadFile(path string) (grid.Spec, []float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return grid.Spec{}, nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// ReadSpec reads only the directory of the GeoTIFF at path and returns its
+// grid spec, e.g. to check its alignment with a template.
+func ReadSpec(path string) (grid.Spec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return grid.Spec{}, err
+	}
+	defer f.Close()
+	d, err := readIFD(f)
+	if err != nil {
+		return grid.Spec{}, err
+	}
+	return d.spec()
+}
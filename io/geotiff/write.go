@@ -0,0 +1,396 @@
+package geotiff
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// Options control the sample type and georeference of written rasters.
+type Options struct {
+	// Float64 writes 64-bit samples instead of 32-bit
+	Float64 bool
+	// NoData is written for NaN values and stored in the GDAL nodata tag
+	NoData float64
+	// EPSG is the code of the coordinate reference system, 0 to omit it.
+	EPSG int
+	// Geographic marks EPSG as a geographic system of longitude and latitude
+	// rather than a projected one.
+	Geographic bool
+}
+
+// DefaultOptions returns 32-bit samples with a nodata value of -9999.
+func DefaultOptions() Options {
+	return Options{NoData: -9999}
+}
+
+// Sink writes one band per value selector and estimation into a GeoTIFF as
+// soon as the estimation is produced. Estimations have to arrive in the order
+// of grid.Spec.At. It implements types.EstimationSink.
+type Sink struct {
+	bw     *bandWriter
+	spec   grid.Spec
+	bands  []func(types.Estimation) float64
+	values []float64
+	n      int
+	finish int
+}
+
+// NewSink writes the TIFF header and directory for a raster of spec with one
+// band per value selector, e.g. asc.Field and asc.Variance, and returns the
+// sink writing the pixels.
+func NewSink(w io.Writer, spec grid.Spec, opts Options, bands ...func(types.Estimation) float64) (*Sink, error) {
+	if len(bands) == 0 {
+		return nil, fmt.Errorf("at least one band is needed")
+	}
+	header, err := encodeHeader(spec, len(bands), opts, false)
+	if err != nil {
+		return nil, err
+	}
+	s := &Sink{
+		bw:     newBandWriter(w, opts),
+		spec:   spec,
+		bands:  bands,
+		values: make([]float64, len(bands)),
+		finish: spec.Len(),
+	}
+	if _, err := s.bw.w.Write(header); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// bandWriter encodes samples, with NaN as nodata
+type bandWriter struct {
+	w    *bufio.Writer
+	opts Options
+	buf  []byte
+}
+
+func newBandWriter(w io.Writer, opts Options) *bandWriter {
+	return &bandWriter{w: bufio.NewWriter(w), opts: opts, buf: make([]byte, sampleSize(opts))}
+}
+
+// write encodes the samples in the order they are stored in the file
+func (b *bandWriter) write(values []float64) error {
+	for _, v := range values {
+		if math.IsNaN(v) {
+			v = b.opts.NoData
+		}
+		if b.opts.Float64 {
+			binary.LittleEndian.PutUint64(b.buf, math.Float64bits(v))
+		} else {
+			binary.LittleEndian.PutUint32(b.buf, math.Float32bits(float32(v)))
+		}
+		if _, err := b.w.Write(b.buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sampleSize(opts Options) int {
+	if opts.Float64 {
+		return 8
+	}
+	return 4
+}
+
+// entry is a directory entry with its encoded value
+type entry struct {
+	tag, typ uint16
+	count    uint32
+	data     []byte
+}
+
+func shorts(v ...uint16) []byte {
+	b := make([]byte, 2*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint16(b[2*i:], x)
+	}
+	return b
+}
+
+func longs(v ...uint32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], x)
+	}
+	return b
+}
+
+func doubles(v ...float64) []byte {
+	b := make([]byte, 8*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint64(b[8*i:], math.Float64bits(x))
+	}
+	return b
+}
+
+// encodeHeader returns the TIFF header and directory of a raster of spec
+// with nb bands, up to the first pixel. Bands are interleaved by pixel, or
+// stored one after another if planar is set.
+func encodeHeader(spec grid.Spec, nb int, opts Options, planar bool) ([]byte, error) {
+	if spec.Is3D() {
+		return nil, fmt.Errorf("3D grids are not supported by GeoTIFF")
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.EPSG < 0 || opts.EPSG > math.MaxUint16 {
+		return nil, fmt.Errorf("EPSG code %d out of range [0, %d]", opts.EPSG, math.MaxUint16)
+	}
+	nx, ny := spec.NX, spec.NY
+	size := sampleSize(opts)
+	rowBytes := uint64(nx * nb * size)
+	if rowBytes*uint64(ny) > math.MaxUint32-1<<20 {
+		return nil, fmt.Errorf("raster of %d bytes exceeds the classic TIFF size limit", rowBytes*uint64(ny))
+	}
+
+	bits := make([]uint16, nb)
+	formats := make([]uint16, nb)
+	for i := range bits {
+		bits[i] = uint16(8 * size)
+		formats[i] = sampleFloat
+	}
+
+	// one strip per row, and per band if the bands are planar
+	strips, stripBytes, planarConfig := ny, rowBytes, uint16(1)
+	if planar {
+		strips, stripBytes, planarConfig = ny*nb, uint64(nx*size), 2
+	}
+
+	xll, yll, _ := spec.Corner()
+	entries := []entry{
+		{tagImageWidth, typeLong, 1, longs(uint32(nx))},
+		{tagImageLength, typeLong, 1, longs(uint32(ny))},
+		{tagBitsPerSample, typeShort, uint32(nb), shorts(bits...)},
+		{tagCompression, typeShort, 1, shorts(1)},
+		{tagPhotometric, typeShort, 1, shorts(1)},
+		{tagStripOffsets, typeLong, uint32(strips), nil},
+		{tagSamplesPerPixel, typeShort, 1, shorts(uint16(nb))},
+		{tagRowsPerStrip, typeLong, 1, longs(1)},
+		{tagStripByteCounts, typeLong, uint32(strips), nil},
+		{tagPlanarConfiguration, typeShort, 1, shorts(planarConfig)},
+		{tagSampleFormat, typeShort, uint32(nb), shorts(formats...)},
+		{tagModelPixelScale, typeDouble, 3, doubles(spec.DX, spec.DY, 0)},
+		{tagModelTiepoint, typeDouble, 6, doubles(0, 0, 0, xll, yll+float64(ny)*spec.DY, 0)},
+	}
+	if nb > 1 {
+		entries = append(entries, entry{tagExtraSamples, typeShort, uint32(nb - 1), make([]byte, 2*(nb-1))})
+	}
+
+	keys := []uint16{keyRasterType, 0, 1, rasterPixelArea}
+	if opts.EPSG > 0 {
+		if opts.Geographic {
+			keys = append([]uint16{keyModelType, 0, 1, modelGeographic}, keys...)
+			keys = append(keys, keyGeographicType, 0, 1, uint16(opts.EPSG))
+		} else {
+			keys = append([]uint16{keyModelType, 0, 1, modelProjected}, keys...)
+			keys = append(keys, keyProjectedCSType, 0, 1, uint16(opts.EPSG))
+		}
+	}
+	dir := append([]uint16{1, 1, 0, uint16(len(keys) / 4)}, keys...)
+	entries = append(entries, entry{tagGeoKeyDirectory, typeShort, uint32(len(dir)), shorts(dir...)})
+
+	nodata := strconv.FormatFloat(opts.NoData, 'g', -1, 64) + "\x00"
+	entries = append(entries, entry{tagGDALNoData, typeASCII, uint32(len(nodata)), []byte(nodata)})
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	// layout: header, directory, values too large for the entries, pixels
+	const ifdOffset = 8
+	extraOffset := uint32(ifdOffset + 2 + 12*len(entries) + 4)
+	extraSize := uint32(0)
+	for _, e := range entries {
+		size := uint32(len(e.data))
+		if e.tag == tagStripOffsets || e.tag == tagStripByteCounts {
+			size = 4 * uint32(strips)
+		}
+		if size > 4 {
+			extraSize += size + size%2
+		}
+	}
+	dataOffset := extraOffset + extraSize
+
+	offsets := make([]uint32, strips)
+	counts := make([]uint32, strips)
+	for i := range offsets {
+		offsets[i] = dataOffset + uint32(i)*uint32(stripBytes)
+		counts[i] = uint32(stripBytes)
+	}
+
+	b := make([]byte, 0, dataOffset)
+	b = append(b, 'I', 'I', 42, 0)
+	b = binary.LittleEndian.AppendUint32(b, ifdOffset)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(entries)))
+	extra := make([]byte, 0, extraSize)
+	for _, e := range entries {
+		switch e.tag {
+		case tagStripOffsets:
+			e.data = longs(offsets...)
+		case tagStripByteCounts:
+			e.data = longs(counts...)
+		}
+		b = binary.LittleEndian.AppendUint16(b, e.tag)
+		b = binary.LittleEndian.AppendUint16(b, e.typ)
+		b = binary.LittleEndian.AppendUint32(b, e.count)
+		if len(e.data) <= 4 {
+			value := make([]byte, 4)
+			copy(value, e.data)
+			b = append(b, value...)
+			continue
+		}
+		b = binary.LittleEndian.AppendUint32(b, extraOffset+uint32(len(extra)))
+		extra = append(extra, e.data...)
+		if len(e.data)%2 == 1 {
+			extra = append(extra, 0)
+		}
+	}
+	// no further directories
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	return append(b, extra...), nil
+}
+
+func (s *Sink) Write(p types.Point, e types.Estimation) error {
+	if s.n >= s.finish {
+		return fmt.Errorf("more values than the %d x %d grid holds", s.spec.NX, s.spec.NY)
+	}
+	s.n++
+	for i, band := range s.bands {
+		s.values[i] = band(e)
+	}
+	return s.bw.write(s.values)
+}
+
+func (s *Sink) Flush() error {
+	if s.n != s.finish {
+		return fmt.Errorf("grid incomplete: got %d of %d values", s.n, s.finish)
+	}
+	return s.bw.w.Flush()
+}
+
+// WriteBands writes a raster of spec with one band per slice of values, each
+// holding one value per node in the order of spec.At.
+func WriteBands(w io.Writer, spec grid.Spec, opts Options, values ...[]float64) error {
+	if len(values) == 0 {
+		return fmt.Errorf("at least one band is needed")
+	}
+	for b, v := range values {
+		if len(v) != spec.Len() {
+			return fmt.Errorf("band %d has %d values, want %d", b, len(v), spec.Len())
+		}
+	}
+	header, err := encodeHeader(spec, len(values), opts, false)
+	if err != nil {
+		return err
+	}
+	bw := newBandWriter(w, opts)
+	if _, err := bw.w.Write(header); err != nil {
+		return err
+	}
+	// rows of pixel interleaved bands
+	row := make([]float64, spec.NX*len(values))
+	for r := 0; r < spec.NY; r++ {
+		for col := 0; col < spec.NX; col++ {
+			for b, v := range values {
+				row[col*len(values)+b] = v[spec.Offset(col, r, 0)]
+			}
+		}
+		if err := bw.write(row); err != nil {
+			return err
+		}
+	}
+	return bw.w.Flush()
+}
+
+// WriteBandsFile creates path and writes the bands with WriteBands.
+func WriteBandsFile(path string, spec grid.Spec, opts Options, values ...[]float64) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if err := WriteBands(f, spec, opts, values...); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Ensemble writes a stack of n realizations, e.g. of SGS, as the bands of a
+// single GeoTIFF. The bands are stored one after another, so that every
+// realization is written to its own section of w as soon as it is complete,
+// in any order.
+type Ensemble struct {
+	w     io.WriterAt
+	spec  grid.Spec
+	opts  Options
+	n     int
+	begin int64
+	band  int64
+}
+
+// NewEnsemble writes the TIFF header and directory for n realizations of
+// spec to w.
+func NewEnsemble(w io.WriterAt, spec grid.Spec, n int, opts Options) (*Ensemble, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("at least one realization is needed")
+	}
+	header, err := encodeHeader(spec, n, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.WriteAt(header, 0); err != nil {
+		return nil, err
+	}
+	return &Ensemble{
+		w:     w,
+		spec:  spec,
+		opts:  opts,
+		n:     n,
+		begin: int64(len(header)),
+		band:  int64(spec.Len() * sampleSize(opts)),
+	}, nil
+}
+
+// Realization returns the sink for realization sim, counted from 0. It
+// matches the sink factory of sgs.SGS.SimulateTo.
+func (e *Ensemble) Realization(sim int) (types.EstimationSink, error) {
+	if sim < 0 || sim >= e.n {
+		return nil, fmt.Errorf("realization %d out of range [0, %d)", sim, e.n)
+	}
+	w := io.NewOffsetWriter(e.w, e.begin+int64(sim)*e.band)
+	return &realization{bw: newBandWriter(w, e.opts), spec: e.spec, values: make([]float64, 1)}, nil
+}
+
+// realization writes the field of a single realization as one band
+type realization struct {
+	bw     *bandWriter
+	spec   grid.Spec
+	values []float64
+	n      int
+}
+
+func (r *realization) Write(p types.Point, e types.Estimation) error {
+	if r.n >= r.spec.Len() {
+		return fmt.Errorf("more values than the %d x %d grid holds", r.spec.NX, r.spec.NY)
+	}
+	r.n++
+	r.values[0] = e.Field
+	return r.bw.write(r.values)
+}
+
+func (r *realization) Flush() error {
+	if r.n != r.spec.Len() {
+		return fmt.Errorf("realization incomplete: got %d of %d values", r.n, r.spec.Len())
+	}
+	return r.bw.w.Flush()
+}
@@ -0,0 +1,267 @@
+package gslib
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"github.com/mmaelicke/go-geostat/internal/variogram"
+)
+
+func TestReadPointsFromReader(t *testing.T) {
+	input := `Walker Lake sample
+4
+Xlocation
+Ylocation
+V
+U
+11 8 0.0 -999
+8 30 -999.0 -999
+9 48 224.4 10.5
+-1500 -2000 -1200.5 -999.5
+`
+	points, report, err := ReadPointsFromReader(strings.NewReader(input), Columns{X: "xlocation", Y: "ylocation", Values: []string{"V", "U"}})
+	if err != nil {
+		t.Fatalf("ReadPointsFromReader() error = %v", err)
+	}
+	// the second row has no value at all
+	if len(points.Points) != 3 {
+		t.Fatalf("got %d points, want 3", len(points.Points))
+	}
+	if report.Rows != 4 || report.Points != 3 || len(report.Skipped) != 1 || report.Skipped[0].Line != 8 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	// only -999 itself is missing, and never in coordinates
+	if p := points.Points[2]; p.X != -1500 || p.Y != -2000 || p.Value != -1200.5 || p.Attributes["U"] != -999.5 {
+		t.Errorf("unexpected last point %+v", p)
+	}
+	if p := points.Points[0]; p.X != 11 || p.Y != 8 || p.Value != 0 || !math.IsNaN(p.Attributes["U"]) {
+		t.Errorf("unexpected first point %+v", p)
+	}
+	if p := points.Points[1]; p.Value != 224.4 || p.Attributes["U"] != 10.5 {
+		t.Errorf("unexpected second point %+v", p)
+	}
+}
+
+func TestReadPointsFromReaderMissingVariable(t *testing.T) {
+	input := "title\n2\nx\ny\n1 2\n"
+	if _, _, err := ReadPointsFromReader(strings.NewReader(input), Columns{}); err == nil {
+		t.Error("expected an error for a missing value variable")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for input, want := range map[string]bool{
+		"Walker Lake\n3\nx\ny\nV\n1 2 3\n":       true,
+		"grid (2 0.5 1)\n1 2 2 1\nvalue\n1\n2\n": true,
+		"x,y,value\n1,2,3\n":                     false,
+		"x y value\n1 2 3\n4 5 6\n":              false,
+		"title\n3\nx\ny\nV\n1 2\n":               false,
+	} {
+		if got := Detect(strings.NewReader(input)); got != want {
+			t.Errorf("Detect(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestWriteGrid(t *testing.T) {
+	spec := grid.Spec{X0: 0.5, Y0: 0.5, DX: 1, DY: 1, NX: 2, NY: 2}
+	var buf bytes.Buffer
+	// values in the order of At, starting in the north-west
+	err := WriteGrid(&buf, spec, "test", Column{"value", []float64{1, 2, 3, math.NaN()}})
+	if err != nil {
+		t.Fatalf("WriteGrid() error = %v", err)
+	}
+	want := "test (2 0.5 1 2 0.5 1 1 0 1)\n1\nvalue\n3\n-999\n1\n2\n"
+	if buf.String() != want {
+		t.Errorf("WriteGrid() =\n%q\nwant\n%q", buf.String(), want)
+	}
+}
+
+// readTable reads a GSLIB file back into its header and rows
+func readTable(t *testing.T, r io.Reader) (header, [][]float64) {
+	t.Helper()
+	scanner := bufio.NewScanner(r)
+	h, err := readHeader(scanner)
+	if err != nil {
+		t.Fatalf("readHeader() error = %v", err)
+	}
+	var rows [][]float64
+	for scanner.Scan() {
+		var row []float64
+		for _, f := range strings.Fields(scanner.Text()) {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				t.Fatalf("invalid value %q", f)
+			}
+			row = append(row, v)
+		}
+		if len(row) != len(h.names) {
+			t.Fatalf("row %v has %d values, want %d", row, len(row), len(h.names))
+		}
+		rows = append(rows, row)
+	}
+	return h, rows
+}
+
+// readFile reads the GSLIB file at path with readTable
+func readFile(t *testing.T, path string) (header, [][]float64) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	return readTable(t, f)
+}
+
+type sample struct{}
+
+func (sample) GetEdges() []float64         { return []float64{1, 2, 3} }
+func (sample) GetHistogram() []int         { return []int{10, 20, 15} }
+func (sample) GetSemivariances() []float64 { return []float64{0.2, math.NaN(), 0.9} }
+
+func TestWriteVarioGSLIBToWriter(t *testing.T) {
+	m, err := variogram.NewVariogram("spherical", types.BaseParams{Range: 2, Sill: 1, Nugget: 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteVarioGSLIBToWriter(&buf, sample{}, m); err != nil {
+		t.Fatalf("WriteVarioGSLIBToWriter() error = %v", err)
+	}
+	h, rows := readTable(t, &buf)
+	if !strings.Contains(h.title, "model: spherical") || len(h.names) != 5 || h.names[4] != "Model" {
+		t.Errorf("unexpected header %+v", h)
+	}
+	// the semivariance of the second lag is missing
+	want := [][]float64{
+		{1, 1, 0.2, 10, m.Evaluate(1)},
+		{2, 2, Missing, 20, m.Evaluate(2)},
+		{3, 3, 0.9, 15, m.Evaluate(3)},
+	}
+	for i, row := range want {
+		for j, v := range row {
+			if rows[i][j] != v {
+				t.Errorf("row %d, column %s = %v, want %v", i, h.names[j], rows[i][j], v)
+			}
+		}
+	}
+
+	buf.Reset()
+	if err := WriteVarioGSLIBToWriter(&buf, sample{}, nil); err != nil {
+		t.Fatalf("WriteVarioGSLIBToWriter() error = %v", err)
+	}
+	if h, _ := readTable(t, &buf); len(h.names) != 4 {
+		t.Errorf("got variables %v without a model, want 4", h.names)
+	}
+}
+
+func TestKrigGSLIBSink(t *testing.T) {
+	spec := grid.Spec{X0: 0.5, Y0: 0.5, DX: 1, DY: 1, NX: 2, NY: 3}
+	path := filepath.Join(t.TempDir(), "krig.gslib")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sink, err := NewKrigGSLIBSink(f, spec)
+	if err != nil {
+		t.Fatalf("NewKrigGSLIBSink() error = %v", err)
+	}
+	// estimations in the order of At, starting in the north-west
+	fields := []float64{1, 2, 3, math.NaN(), -1234.5678901234567, 6e-300}
+	for i, v := range fields {
+		if err := sink.Flush(); err == nil {
+			t.Fatal("Flush() of an incomplete grid should fail")
+		}
+		if err := sink.Write(types.Point{}, types.Estimation{Field: v, Variance: float64(i)}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if err := sink.Write(types.Point{}, types.Estimation{}); err == nil {
+		t.Error("Write() beyond the grid should fail")
+	}
+
+	h, rows := readFile(t, path)
+	if h.title != "go-geostat kriging (2 0.5 1 3 0.5 1 1 0 1)" || len(h.names) != 2 {
+		t.Errorf("unexpected header %+v", h)
+	}
+	// the south row comes first
+	want := [][]float64{{-1234.5678901234567, 4}, {6e-300, 5}, {3, 2}, {Missing, 3}, {1, 0}, {2, 1}}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, row := range want {
+		if rows[i][0] != row[0] || rows[i][1] != row[1] {
+			t.Errorf("row %d = %v, want %v", i, rows[i], row)
+		}
+	}
+}
+
+func TestEnsemble(t *testing.T) {
+	spec := grid.Spec{X0: 0.5, Y0: 0.5, Z0: 0.5, DX: 1, DY: 1, DZ: 1, NX: 2, NY: 2, NZ: 2}
+	path := filepath.Join(t.TempDir(), "sgs.gslib")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ens, err := NewEnsemble(f, spec, 2)
+	if err != nil {
+		t.Fatalf("NewEnsemble() error = %v", err)
+	}
+	if _, err := ens.Realization(2); err == nil {
+		t.Error("expected an error for a realization out of range")
+	}
+	// realizations arrive in any order
+	for _, sim := range []int{1, 0} {
+		if err := ens.Close(); err == nil {
+			t.Fatal("Close() with incomplete realizations should fail")
+		}
+		sink, err := ens.Realization(sim)
+		if err != nil {
+			t.Fatalf("Realization(%d) error = %v", sim, err)
+		}
+		for i := 0; i < spec.Len(); i++ {
+			if err := sink.Write(types.Point{}, types.Estimation{Field: float64(100*sim + i)}); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		}
+		if err := sink.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+	}
+	if err := ens.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	h, rows := readFile(t, path)
+	if !strings.HasPrefix(h.title, "go-geostat SGS realizations (2)") || len(h.names) != 1 {
+		t.Errorf("unexpected header %+v", h)
+	}
+	// each realization in GSLIB order: the south row first in each layer,
+	// starting with the bottom layer
+	order := []float64{2, 3, 0, 1, 6, 7, 4, 5}
+	if len(rows) != 2*len(order) {
+		t.Fatalf("got %d rows, want %d", len(rows), 2*len(order))
+	}
+	for sim := 0; sim < 2; sim++ {
+		for i, v := range order {
+			if got := rows[sim*len(order)+i][0]; got != float64(100*sim)+v {
+				t.Errorf("realization %d, row %d = %v, want %v", sim, i, got, float64(100*sim)+v)
+			}
+		}
+	}
+}
@@ -0,0 +1,254 @@
+// Package gslib reads and writes the GSLIB (Geo-EAS) file format, so that
+// results can be compared with the GSLIB programs gamv, kt3d and sgsim.
+//
+// A GSLIB file starts with a title line, followed by the number of variables,
+// one variable name per line and whitespace delimited rows of values. Values
+// of -999 are missing.
+package gslib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"github.com/mmaelicke/go-geostat/io/csv"
+)
+
+// Missing is written for NaN values, and values and covariates equal to it
+// are read as missing. Coordinates are never missing.
+const Missing = -999.0
+
+// Columns maps the point fields to GSLIB variable names, which are matched
+// case-insensitively. Empty coordinate names fall back to x and y; an empty
+// Values list falls back to a single value variable.
+type Columns struct {
+	X, Y, Z string
+	// Values are read into Point.Attributes. The first one is also Point.Value.
+	Values []string
+	// Covariates are read into Point.Covariates.
+	Covariates []string
+}
+
+// header is the title and variable names of a GSLIB file.
+type header struct {
+	title string
+	names []string
+}
+
+// readHeader reads the title, variable count and names. Grid files may
+// follow the count by the grid dimensions, which are ignored.
+func readHeader(scanner *bufio.Scanner) (header, error) {
+	var h header
+	if !scanner.Scan() {
+		return h, fmt.Errorf("missing title line")
+	}
+	h.title = strings.TrimSpace(scanner.Text())
+	if !scanner.Scan() {
+		return h, fmt.Errorf("missing number of variables")
+	}
+	fields := strings.Fields(scanner.Text())
+	if len(fields) == 0 {
+		return h, fmt.Errorf("missing number of variables")
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n < 1 {
+		return h, fmt.Errorf("invalid number of variables %q", fields[0])
+	}
+	for i := 0; i < n; i++ {
+		if !scanner.Scan() {
+			return h, fmt.Errorf("expected %d variable names, got %d", n, i)
+		}
+		h.names = append(h.names, strings.TrimSpace(scanner.Text()))
+	}
+	return h, nil
+}
+
+// Detect reports whether r starts with a GSLIB header: a title, a variable
+// count and as many names, followed by rows of at least as many numbers.
+// Delimited text fails, as its second line is a row of data rather than a
+// count followed by names.
+func Detect(r io.Reader) bool {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	h, err := readHeader(scanner)
+	if err != nil {
+		return false
+	}
+	for _, name := range h.names {
+		if name == "" || numbers(strings.Fields(name)) {
+			return false
+		}
+	}
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		return len(fields) >= len(h.names) && numbers(fields)
+	}
+	return true
+}
+
+// numbers reports whether all fields are numbers
+func numbers(fields []string) bool {
+	for _, f := range fields {
+		if _, err := strconv.ParseFloat(f, 64); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// index returns the position of the variable called name, or -1
+func (h header) index(name string) int {
+	for i, n := range h.names {
+		if strings.EqualFold(n, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ReadPointsFromReader reads points from a GSLIB file. A row is skipped if a
+// covariate is missing, or if all of its values are missing; single missing
+// values are stored as NaN. The report lists the skipped rows.
+func ReadPointsFromReader(r io.Reader, cols Columns) (types.Points, csv.Report, error) {
+	var report csv.Report
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	h, err := readHeader(scanner)
+	if err != nil {
+		return types.Points{}, report, err
+	}
+
+	if cols.X == "" {
+		cols.X = "x"
+	}
+	if cols.Y == "" {
+		cols.Y = "y"
+	}
+	if len(cols.Values) == 0 {
+		cols.Values = []string{"value"}
+	}
+	find := func(kind, name string) (int, error) {
+		i := h.index(name)
+		if i == -1 {
+			return -1, fmt.Errorf("missing %s variable %q in %v", kind, name, h.names)
+		}
+		return i, nil
+	}
+	xIdx, err := find("x", cols.X)
+	if err != nil {
+		return types.Points{}, report, err
+	}
+	yIdx, err := find("y", cols.Y)
+	if err != nil {
+		return types.Points{}, report, err
+	}
+	zIdx := -1
+	if cols.Z != "" {
+		if zIdx, err = find("z", cols.Z); err != nil {
+			return types.Points{}, report, err
+		}
+	}
+	valueIdx := make([]int, len(cols.Values))
+	for v, name := range cols.Values {
+		if valueIdx[v], err = find("value", name); err != nil {
+			return types.Points{}, report, err
+		}
+	}
+	covIdx := make([]int, len(cols.Covariates))
+	for c, name := range cols.Covariates {
+		if covIdx[c], err = find("covariate", name); err != nil {
+			return types.Points{}, report, err
+		}
+	}
+
+	// missing returns NaN for the missing value
+	missing := func(v float64) float64 {
+		if v == Missing {
+			return math.NaN()
+		}
+		return v
+	}
+	points := types.Points{Is3D: zIdx != -1}
+	line := 2 + len(h.names)
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		report.Rows++
+		if len(fields) < len(h.names) {
+			return types.Points{}, report, fmt.Errorf("line %d has %d values, want %d", line, len(fields), len(h.names))
+		}
+		values := make([]float64, len(h.names))
+		for i := range values {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return types.Points{}, report, fmt.Errorf("line %d: failed to parse %s: %w", line, h.names[i], err)
+			}
+			values[i] = v
+		}
+		skip := func(reason string) {
+			report.Skipped = append(report.Skipped, csv.SkippedRow{Line: line, Reason: reason})
+		}
+
+		p := types.Point{X: values[xIdx], Y: values[yIdx]}
+		if zIdx != -1 {
+			p.Z, p.Is3D = values[zIdx], true
+		}
+		p.Attributes = make(map[string]float64, len(valueIdx))
+		parsed := 0
+		for v, idx := range valueIdx {
+			p.Attributes[cols.Values[v]] = missing(values[idx])
+			if !math.IsNaN(p.Attributes[cols.Values[v]]) {
+				parsed++
+			}
+		}
+		if parsed == 0 {
+			skip("no value")
+			continue
+		}
+		p.Value = p.Attributes[cols.Values[0]]
+
+		if len(covIdx) > 0 {
+			p.Covariates = make(map[string]float64, len(covIdx))
+		}
+		ok := true
+		for c, idx := range covIdx {
+			v := missing(values[idx])
+			if math.IsNaN(v) {
+				skip(fmt.Sprintf("missing covariate %s", cols.Covariates[c]))
+				ok = false
+				break
+			}
+			p.Covariates[cols.Covariates[c]] = v
+		}
+		if !ok {
+			continue
+		}
+		points.Points = append(points.Points, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return types.Points{}, report, fmt.Errorf("failed to read file: %w", err)
+	}
+	report.Points = len(points.Points)
+	return points, report, nil
+}
+
+// ReadPoints opens path and reads it with ReadPointsFromReader.
+func ReadPoints(path string, cols Columns) (types.Points, csv.Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return types.Points{}, csv.Report{}, err
+	}
+	defer f.Close()
+	return ReadPointsFromReader(f, cols)
+}
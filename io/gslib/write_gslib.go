@@ -0,0 +1,302 @@
+package gslib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// Column is a named variable with one value per row.
+type Column struct {
+	Name   string
+	Values []float64
+}
+
+// writeTable writes a GSLIB file with the title, the column names and n rows
+func writeTable(w io.Writer, title string, n int, columns ...Column) error {
+	for _, c := range columns {
+		if len(c.Values) != n {
+			return fmt.Errorf("column %s has %d values, want %d", c.Name, len(c.Values), n)
+		}
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, title)
+	fmt.Fprintln(bw, len(columns))
+	for _, c := range columns {
+		fmt.Fprintln(bw, c.Name)
+	}
+	for i := 0; i < n; i++ {
+		for j, c := range columns {
+			if j > 0 {
+				bw.WriteByte(' ')
+			}
+			bw.WriteString(formatValue(c.Values[i]))
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// formatValue formats v with full precision, or as Missing if it is NaN
+func formatValue(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		v = Missing
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// gslibOrder returns the values of a grid, given in the order of
+// grid.Spec.At, in GSLIB order: x fastest, then y from south to north, then z.
+func gslibOrder(spec grid.Spec, values []float64) []float64 {
+	out := make([]float64, 0, len(values))
+	for layer := 0; layer < max(spec.NZ, 1); layer++ {
+		for row := spec.NY - 1; row >= 0; row-- {
+			for col := 0; col < spec.NX; col++ {
+				out = append(out, values[spec.Offset(col, row, layer)])
+			}
+		}
+	}
+	return out
+}
+
+// gridTitle appends the grid definition in the order of GSLIB parameter
+// files to title: nx xmn xsiz, ny ymn ysiz, nz zmn zsiz
+func gridTitle(title string, spec grid.Spec) string {
+	x, y, z := spec.Center()
+	nz, dz := max(spec.NZ, 1), spec.DZ
+	if !spec.Is3D() {
+		z, dz = 0, 1
+	}
+	return fmt.Sprintf("%s (%d %g %g %d %g %g %d %g %g)", title,
+		spec.NX, x, spec.DX, spec.NY, y, spec.DY, nz, z, dz)
+}
+
+// WriteGrid writes the columns on the grid spec in GSLIB grid order. Each
+// column holds one value per node in the order of grid.Spec.At. The grid
+// definition is appended to the title.
+func WriteGrid(w io.Writer, spec grid.Spec, title string, columns ...Column) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+	ordered := make([]Column, len(columns))
+	for i, c := range columns {
+		if len(c.Values) != spec.Len() {
+			return fmt.Errorf("column %s has %d values, want %d", c.Name, len(c.Values), spec.Len())
+		}
+		ordered[i] = Column{Name: c.Name, Values: gslibOrder(spec, c.Values)}
+	}
+	return writeTable(w, gridTitle(title, spec), spec.Len(), ordered...)
+}
+
+// width is the width of the values of streamed grids. It fits the shortest
+// representation of any float64, e.g. -1.2345678901234567e-308, so that every
+// row of the file has the same length.
+const width = 24
+
+// layout places the rows of a GSLIB grid file at fixed offsets, so that the
+// nodes can be written as soon as a grid row is complete.
+type layout struct {
+	w     io.WriterAt
+	spec  grid.Spec
+	begin int64 // offset of the first row
+	line  int64 // length of a row
+}
+
+// newLayout writes the title with the grid definition and the variable names
+// of a grid file to w.
+func newLayout(w io.WriterAt, spec grid.Spec, title string, names ...string) (*layout, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	var text strings.Builder
+	fmt.Fprintln(&text, gridTitle(title, spec))
+	fmt.Fprintln(&text, len(names))
+	for _, name := range names {
+		fmt.Fprintln(&text, name)
+	}
+	if _, err := w.WriteAt([]byte(text.String()), 0); err != nil {
+		return nil, err
+	}
+	return &layout{w: w, spec: spec, begin: int64(text.Len()), line: int64(len(names) * (width + 1))}, nil
+}
+
+// rows returns the writer of the grid starting at row first of the file
+func (l *layout) rows(first int) *rows {
+	return &rows{layout: l, begin: l.begin + int64(first)*l.line}
+}
+
+// rows writes the nodes of a grid, given in the order of grid.Spec.At, a
+// grid row at a time to their place in GSLIB grid order
+type rows struct {
+	*layout
+	begin int64
+	buf   []byte
+	n     int
+}
+
+// write writes the values of the next node, one per variable
+func (r *rows) write(values ...float64) error {
+	if r.n >= r.spec.Len() {
+		return fmt.Errorf("more values than the grid holds (%d)", r.spec.Len())
+	}
+	for i, v := range values {
+		if i > 0 {
+			r.buf = append(r.buf, ' ')
+		}
+		r.buf = fmt.Appendf(r.buf, "%*s", width, formatValue(v))
+	}
+	r.buf = append(r.buf, '\n')
+	r.n++
+	if r.n%r.spec.NX != 0 {
+		return nil
+	}
+	_, row, layer := r.spec.Index(r.n - 1)
+	at := r.spec.Offset(0, r.spec.NY-1-row, layer)
+	_, err := r.w.WriteAt(r.buf, r.begin+r.line*int64(at))
+	r.buf = r.buf[:0]
+	return err
+}
+
+func (r *rows) flush() error {
+	if r.n != r.spec.Len() {
+		return fmt.Errorf("grid incomplete: got %d of %d values", r.n, r.spec.Len())
+	}
+	return nil
+}
+
+// KrigGSLIBSink writes kriging estimations like kt3d, as the variables
+// Estimate and EstimationVariance in GSLIB grid order, a grid row at a time
+// as soon as it is complete. Estimations have to arrive in the order of
+// grid.Spec.At. It implements types.EstimationSink.
+type KrigGSLIBSink struct {
+	rows *rows
+}
+
+// NewKrigGSLIBSink writes the header of the grid spec to w, e.g. an
+// *os.File, and returns the sink writing the estimations.
+func NewKrigGSLIBSink(w io.WriterAt, spec grid.Spec) (*KrigGSLIBSink, error) {
+	l, err := newLayout(w, spec, "go-geostat kriging", "Estimate", "EstimationVariance")
+	if err != nil {
+		return nil, err
+	}
+	return &KrigGSLIBSink{rows: l.rows(0)}, nil
+}
+
+func (s *KrigGSLIBSink) Write(p types.Point, e types.Estimation) error {
+	return s.rows.write(e.Field, e.Variance)
+}
+
+func (s *KrigGSLIBSink) Flush() error {
+	return s.rows.flush()
+}
+
+// Ensemble writes a stack of SGS realizations like sgsim: a single variable
+// with one realization after another, each in GSLIB grid order. Realizations
+// may be written in any order, as each one is written at its own offset.
+type Ensemble struct {
+	layout *layout
+	done   []bool
+}
+
+// NewEnsemble writes the header of n realizations on the grid spec to w,
+// e.g. an *os.File.
+func NewEnsemble(w io.WriterAt, spec grid.Spec, n int) (*Ensemble, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("at least one realization is needed")
+	}
+	l, err := newLayout(w, spec, fmt.Sprintf("go-geostat SGS realizations (%d)", n), "value")
+	if err != nil {
+		return nil, err
+	}
+	return &Ensemble{layout: l, done: make([]bool, n)}, nil
+}
+
+// Realization returns the sink for realization sim, counted from 0. It
+// matches the sink factory of sgs.SGS.SimulateTo.
+func (e *Ensemble) Realization(sim int) (types.EstimationSink, error) {
+	if sim < 0 || sim >= len(e.done) {
+		return nil, fmt.Errorf("realization %d out of range [0, %d)", sim, len(e.done))
+	}
+	return &realization{rows: e.layout.rows(sim * e.layout.spec.Len()), done: &e.done[sim]}, nil
+}
+
+// Close fails if any realization is incomplete.
+func (e *Ensemble) Close() error {
+	for sim, done := range e.done {
+		if !done {
+			return fmt.Errorf("realization %d incomplete", sim)
+		}
+	}
+	return nil
+}
+
+// realization writes the field of one realization
+type realization struct {
+	rows *rows
+	done *bool
+}
+
+func (r *realization) Write(p types.Point, e types.Estimation) error {
+	return r.rows.write(e.Field)
+}
+
+func (r *realization) Flush() error {
+	if err := r.rows.flush(); err != nil {
+		return err
+	}
+	*r.done = true
+	return nil
+}
+
+// WriteVarioGSLIBToWriter writes an experimental variogram as GSLIB table
+// with the lag number, the upper lag edge, the semivariance, the number of
+// pairs and, if m is not nil, the model semivariance at the upper edge.
+func WriteVarioGSLIBToWriter(w io.Writer, v types.SampleVariogram, m types.SpatialFunction) error {
+	edges := v.GetEdges()
+	semivariances := v.GetSemivariances()
+	histogram := v.GetHistogram()
+	if len(edges) != len(semivariances) || len(edges) != len(histogram) {
+		return fmt.Errorf("edges, semivariances, and histogram must have the same length")
+	}
+
+	n := len(edges)
+	lags := Column{"Lag", make([]float64, n)}
+	pairs := Column{"Number of pairs", make([]float64, n)}
+	for i := range edges {
+		lags.Values[i] = float64(i + 1)
+		pairs.Values[i] = float64(histogram[i])
+	}
+	columns := []Column{lags, {"Lag distance", edges}, {"Semivariance", semivariances}, pairs}
+
+	title := "go-geostat semivariogram"
+	if m != nil {
+		title = fmt.Sprintf("%s, model: %s, range: %g, sill: %g, nugget: %g", title, m.Name(), m.Range(), m.Sill(), m.Nugget())
+		model := Column{"Model", make([]float64, n)}
+		for i, e := range edges {
+			model.Values[i] = m.Evaluate(e)
+		}
+		columns = append(columns, model)
+	}
+	return writeTable(w, title, n, columns...)
+}
+
+// WriteVarioGSLIB creates path and writes the variogram with
+// WriteVarioGSLIBToWriter.
+func WriteVarioGSLIB(path string, v types.SampleVariogram, m types.SpatialFunction) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer f.Close()
+
+	return WriteVarioGSLIBToWriter(f, v, m)
+}
@@ -0,0 +1,63 @@
+package json
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+type krigPoint struct {
+	X        float64  `json:"x"`
+	Y        float64  `json:"y"`
+	Z        *float64 `json:"z,omitempty"`
+	Value    *float64 `json:"value"`
+	Variance *float64 `json:"variance"`
+}
+
+// KrigJSONSink writes kriging estimations as a JSON array of objects, one
+// per line, as soon as they are produced. It implements types.EstimationSink.
+type KrigJSONSink struct {
+	w     *bufio.Writer
+	is3D  bool
+	count int
+}
+
+// NewKrigJSONSink returns a sink writing x, y, (z,) value and variance
+// objects to w. Values JSON cannot represent are written as null.
+func NewKrigJSONSink(w io.Writer, is3D bool) *KrigJSONSink {
+	return &KrigJSONSink{w: bufio.NewWriter(w), is3D: is3D}
+}
+
+func (s *KrigJSONSink) Write(p types.Point, e types.Estimation) error {
+	kp := krigPoint{X: p.X, Y: p.Y, Value: number(e.Field), Variance: number(e.Variance)}
+	if s.is3D {
+		kp.Z = number(p.Z)
+	}
+	b, err := json.Marshal(kp)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if s.count == 0 {
+		sep = "[\n"
+	}
+	s.count++
+	if _, err := s.w.WriteString(sep); err != nil {
+		return err
+	}
+	_, err = s.w.Write(b)
+	return err
+}
+
+func (s *KrigJSONSink) Flush() error {
+	end := "\n]\n"
+	if s.count == 0 {
+		end = "[]\n"
+	}
+	if _, err := s.w.WriteString(end); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
@@ -0,0 +1,91 @@
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/mmaelicke/go-geostat/internal/crossval"
+)
+
+type xvalPoint struct {
+	Index     int      `json:"index"`
+	Fold      int      `json:"fold"`
+	X         float64  `json:"x"`
+	Y         float64  `json:"y"`
+	Z         *float64 `json:"z,omitempty"`
+	Observed  float64  `json:"observed"`
+	Predicted *float64 `json:"predicted"`
+	Variance  *float64 `json:"variance"`
+	Error     *float64 `json:"error"`
+	StdError  *float64 `json:"std_error"`
+}
+
+type xvalSummary struct {
+	N           int      `json:"n"`
+	Failed      int      `json:"failed"`
+	ME          *float64 `json:"me"`
+	MAE         *float64 `json:"mae"`
+	RMSE        *float64 `json:"rmse"`
+	MSDR        *float64 `json:"msdr"`
+	Correlation *float64 `json:"correlation"`
+}
+
+type xvalJson struct {
+	Summary xvalSummary `json:"summary"`
+	Points  []xvalPoint `json:"points"`
+}
+
+// number returns nil for values JSON cannot represent, which encode as null
+func number(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+func WriteXvalJsonToWriter(w io.Writer, results []crossval.Result, summary crossval.Summary, is3D bool) error {
+	xval := xvalJson{
+		Summary: xvalSummary{
+			N:           summary.N,
+			Failed:      summary.Failed,
+			ME:          number(summary.ME),
+			MAE:         number(summary.MAE),
+			RMSE:        number(summary.RMSE),
+			MSDR:        number(summary.MSDR),
+			Correlation: number(summary.Correlation),
+		},
+		Points: make([]xvalPoint, len(results)),
+	}
+	for i, r := range results {
+		p := xvalPoint{
+			Index:     r.Index,
+			Fold:      r.Fold,
+			X:         r.Point.X,
+			Y:         r.Point.Y,
+			Observed:  r.Observed,
+			Predicted: number(r.Predicted),
+			Variance:  number(r.Variance),
+			Error:     number(r.Error),
+			StdError:  number(r.StdError),
+		}
+		if is3D {
+			p.Z = number(r.Point.Z)
+		}
+		xval.Points[i] = p
+	}
+
+	return json.NewEncoder(w).Encode(xval)
+}
+
+func WriteXvalJson(path string, results []crossval.Result, summary crossval.Summary, is3D bool) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer f.Close()
+
+	return WriteXvalJsonToWriter(f, results, summary, is3D)
+}
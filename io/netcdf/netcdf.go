@@ -0,0 +1,182 @@
+// Package netcdf writes grids of estimations as NetCDF files in pure Go.
+//
+// Files use the classic format, or the 64-bit offset format once the data
+// exceed 2 GiB, and follow the CF conventions: coordinate variables x, y and,
+// for 3D grids, z, an optional time of length one and, for stacks of SGS
+// realizations, a realization dimension. Data variables are laid out with x
+// varying fastest and y from north to south, which is the order of
+// grid.Spec.At, so that values can be written as they are produced.
+package netcdf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// NetCDF header tags and types
+const (
+	tagDimension = 0x0a
+	tagVariable  = 0x0b
+	tagAttribute = 0x0c
+
+	typeChar   = 2
+	typeInt    = 4
+	typeFloat  = 5
+	typeDouble = 6
+)
+
+// maxClassic is the largest offset of the classic format
+const maxClassic = math.MaxInt32
+
+type dimension struct {
+	name string
+	len  int
+}
+
+// attribute holds a string, int32, float32 or float64 value
+type attribute struct {
+	name  string
+	value any
+}
+
+type variable struct {
+	name  string
+	dims  []int
+	attrs []attribute
+	typ   int
+	begin int64
+}
+
+// typeSize returns the byte size of a single value of type typ
+func typeSize(typ int) int64 {
+	switch typ {
+	case typeChar:
+		return 1
+	case typeInt, typeFloat:
+		return 4
+	}
+	return 8
+}
+
+// size returns the number of bytes of the variable's data, padded to 4 bytes
+func (v variable) size(dims []dimension) int64 {
+	n := typeSize(v.typ)
+	for _, d := range v.dims {
+		n *= int64(dims[d].len)
+	}
+	return pad4(n)
+}
+
+func pad4(n int64) int64 {
+	return (n + 3) &^ 3
+}
+
+// header describes the dimensions, global attributes and variables of a file
+type header struct {
+	dims  []dimension
+	attrs []attribute
+	vars  []variable
+}
+
+// layout assigns the variables' offsets, placing their data one after another
+// behind the header. It returns the file version and the total file size.
+func (h *header) layout() (version byte, size int64) {
+	version = 1
+	for {
+		offset := int64(len(h.encode(version)))
+		for i := range h.vars {
+			h.vars[i].begin = offset
+			offset += h.vars[i].size(h.dims)
+		}
+		if version == 2 || h.vars[len(h.vars)-1].begin <= maxClassic {
+			return version, offset
+		}
+		// the offsets no longer fit into the classic format
+		version = 2
+	}
+}
+
+// encode returns the header in the given format version
+func (h *header) encode(version byte) []byte {
+	b := []byte{'C', 'D', 'F', version}
+	// no record dimension
+	b = binary.BigEndian.AppendUint32(b, 0)
+
+	b = appendList(b, tagDimension, len(h.dims))
+	for _, d := range h.dims {
+		b = appendName(b, d.name)
+		b = binary.BigEndian.AppendUint32(b, uint32(d.len))
+	}
+	b = appendAttributes(b, h.attrs)
+	b = appendList(b, tagVariable, len(h.vars))
+	for _, v := range h.vars {
+		b = appendName(b, v.name)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(v.dims)))
+		for _, d := range v.dims {
+			b = binary.BigEndian.AppendUint32(b, uint32(d))
+		}
+		b = appendAttributes(b, v.attrs)
+		b = binary.BigEndian.AppendUint32(b, uint32(v.typ))
+		size := v.size(h.dims)
+		if size > math.MaxUint32-3 {
+			// too large to record, readers compute the size from the dimensions
+			size = math.MaxUint32
+		}
+		b = binary.BigEndian.AppendUint32(b, uint32(size))
+		if version == 1 {
+			b = binary.BigEndian.AppendUint32(b, uint32(v.begin))
+		} else {
+			b = binary.BigEndian.AppendUint64(b, uint64(v.begin))
+		}
+	}
+	return b
+}
+
+// appendList appends the tag and length of a list, or the marker of an
+// absent list if it is empty
+func appendList(b []byte, tag uint32, n int) []byte {
+	if n == 0 {
+		return append(b, 0, 0, 0, 0, 0, 0, 0, 0)
+	}
+	b = binary.BigEndian.AppendUint32(b, tag)
+	return binary.BigEndian.AppendUint32(b, uint32(n))
+}
+
+func appendName(b []byte, name string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(name)))
+	return appendPadded(b, []byte(name))
+}
+
+func appendPadded(b, data []byte) []byte {
+	b = append(b, data...)
+	for i := int64(len(data)); i < pad4(int64(len(data))); i++ {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func appendAttributes(b []byte, attrs []attribute) []byte {
+	b = appendList(b, tagAttribute, len(attrs))
+	for _, a := range attrs {
+		b = appendName(b, a.name)
+		var typ, n int
+		var data []byte
+		switch v := a.value.(type) {
+		case string:
+			typ, n, data = typeChar, len(v), []byte(v)
+		case int32:
+			typ, n, data = typeInt, 1, binary.BigEndian.AppendUint32(nil, uint32(v))
+		case float32:
+			typ, n, data = typeFloat, 1, binary.BigEndian.AppendUint32(nil, math.Float32bits(v))
+		case float64:
+			typ, n, data = typeDouble, 1, binary.BigEndian.AppendUint64(nil, math.Float64bits(v))
+		default:
+			panic(fmt.Sprintf("unsupported attribute type %T", v))
+		}
+		b = binary.BigEndian.AppendUint32(b, uint32(typ))
+		b = binary.BigEndian.AppendUint32(b, uint32(n))
+		b = appendPadded(b, data)
+	}
+	return b
+}
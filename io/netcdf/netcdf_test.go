@@ -0,0 +1,183 @@
+package netcdf
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// parsed is the minimal content of a file read back by parse
+type parsed struct {
+	version byte
+	dims    map[string]int
+	begins  map[string]int64
+}
+
+// parse reads the dimensions and variable offsets of a classic or 64-bit
+// offset file
+func parse(t *testing.T, b []byte) parsed {
+	t.Helper()
+	if string(b[:3]) != "CDF" {
+		t.Fatalf("bad magic %q", b[:4])
+	}
+	p := parsed{version: b[3], dims: map[string]int{}, begins: map[string]int64{}}
+	pos := 8
+	u32 := func() int {
+		v := binary.BigEndian.Uint32(b[pos:])
+		pos += 4
+		return int(v)
+	}
+	name := func() string {
+		n := u32()
+		s := string(b[pos : pos+n])
+		pos += int(pad4(int64(n)))
+		return s
+	}
+	attrs := func() {
+		u32()
+		for n := u32(); n > 0; n-- {
+			name()
+			typ, count := u32(), u32()
+			pos += int(pad4(typeSize(typ) * int64(count)))
+		}
+	}
+
+	u32()
+	var dimNames []string
+	for n := u32(); n > 0; n-- {
+		dn := name()
+		p.dims[dn] = u32()
+		dimNames = append(dimNames, dn)
+	}
+	attrs()
+	u32()
+	for n := u32(); n > 0; n-- {
+		vn := name()
+		for d := u32(); d > 0; d-- {
+			u32()
+		}
+		attrs()
+		u32()
+		u32()
+		if p.version == 1 {
+			p.begins[vn] = int64(u32())
+		} else {
+			p.begins[vn] = int64(binary.BigEndian.Uint64(b[pos:]))
+			pos += 8
+		}
+	}
+	return p
+}
+
+func float32At(b []byte, offset int64, i int) float64 {
+	return float64(math.Float32frombits(binary.BigEndian.Uint32(b[offset+4*int64(i):])))
+}
+
+func TestSink3D(t *testing.T) {
+	spec := grid.Spec{DX: 1, DY: 1, DZ: 2, NX: 3, NY: 2, NZ: 2}
+	path := filepath.Join(t.TempDir(), "krig.nc")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink, err := NewSink(f, spec, DefaultOptions())
+	if err != nil {
+		t.Fatalf("NewSink() error = %v", err)
+	}
+	for i := 0; i < spec.Len(); i++ {
+		e := types.Estimation{Field: float64(i), Variance: float64(2 * i)}
+		if i == 3 {
+			e.Field = math.NaN()
+		}
+		if err := sink.Write(spec.At(i), e); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	f.Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := parse(t, b)
+	if p.version != 1 || p.dims["x"] != 3 || p.dims["y"] != 2 || p.dims["z"] != 2 {
+		t.Fatalf("unexpected version %d and dimensions %v", p.version, p.dims)
+	}
+	// y runs from north to south
+	if y := math.Float64frombits(binary.BigEndian.Uint64(b[p.begins["y"]:])); y != 1 {
+		t.Errorf("first y = %v, want 1", y)
+	}
+	for i := 0; i < spec.Len(); i++ {
+		want := float64(i)
+		if i == 3 {
+			want = -9999
+		}
+		if v := float32At(b, p.begins["field"], i); v != want {
+			t.Errorf("field[%d] = %v, want %v", i, v, want)
+		}
+		if v := float32At(b, p.begins["variance"], i); v != float64(2*i) {
+			t.Errorf("variance[%d] = %v, want %v", i, v, 2*i)
+		}
+	}
+	if end := p.begins["variance"] + 4*int64(spec.Len()); int64(len(b)) != end {
+		t.Errorf("file has %d bytes, want %d", len(b), end)
+	}
+}
+
+func TestEnsemble(t *testing.T) {
+	spec := grid.Spec{DX: 1, DY: 1, NX: 2, NY: 2}
+	path := filepath.Join(t.TempDir(), "sgs.nc")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.TimeUnits = "days since 2000-01-01"
+	ens, err := NewEnsemble(f, spec, 3, opts)
+	if err != nil {
+		t.Fatalf("NewEnsemble() error = %v", err)
+	}
+	// realizations complete in any order
+	for _, sim := range []int{2, 0, 1} {
+		sink, err := ens.Realization(sim)
+		if err != nil {
+			t.Fatalf("Realization() error = %v", err)
+		}
+		for i := 0; i < spec.Len(); i++ {
+			if err := sink.Write(spec.At(i), types.Estimation{Field: float64(10*sim + i)}); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		}
+		if err := sink.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+	}
+	if _, err := ens.Realization(3); err == nil {
+		t.Error("expected an error for a realization out of range")
+	}
+	f.Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := parse(t, b)
+	if p.dims["realization"] != 3 || p.dims["time"] != 1 {
+		t.Fatalf("unexpected dimensions %v", p.dims)
+	}
+	for sim := 0; sim < 3; sim++ {
+		for i := 0; i < spec.Len(); i++ {
+			if v := float32At(b, p.begins["sim"], sim*spec.Len()+i); v != float64(10*sim+i) {
+				t.Errorf("sim[%d][%d] = %v, want %v", sim, i, v, 10*sim+i)
+			}
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package netcdf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// Options control the data type and metadata of written files.
+type Options struct {
+	// Float64 writes doubles instead of floats
+	Float64 bool
+	// FillValue is written for NaN values and stored as _FillValue
+	FillValue float64
+	// TimeUnits, e.g. "days since 2000-01-01", adds a time dimension of
+	// length one holding Time.
+	TimeUnits string
+	Time      float64
+	// Title is stored as global attribute, if not empty
+	Title string
+}
+
+// DefaultOptions returns float data with a fill value of -9999.
+func DefaultOptions() Options {
+	return Options{FillValue: -9999}
+}
+
+// newHeader describes a file with the coordinate variables of spec and one
+// data variable per name. With realizations > 0, the data variables get a
+// leading realization dimension.
+func newHeader(spec grid.Spec, opts Options, realizations int, names ...string) header {
+	h := header{attrs: []attribute{{"Conventions", "CF-1.8"}}}
+	if opts.Title != "" {
+		h.attrs = append(h.attrs, attribute{"title", opts.Title})
+	}
+	h.attrs = append(h.attrs, attribute{"source", "go-geostat"})
+
+	coord := func(name string, n, typ int, attrs ...attribute) int {
+		h.dims = append(h.dims, dimension{name, n})
+		d := len(h.dims) - 1
+		h.vars = append(h.vars, variable{name: name, dims: []int{d}, typ: typ, attrs: attrs})
+		return d
+	}
+	x := coord("x", spec.NX, typeDouble,
+		attribute{"standard_name", "projection_x_coordinate"},
+		attribute{"long_name", "x coordinate of cell centre"},
+		attribute{"axis", "X"})
+	y := coord("y", spec.NY, typeDouble,
+		attribute{"standard_name", "projection_y_coordinate"},
+		attribute{"long_name", "y coordinate of cell centre"},
+		attribute{"axis", "Y"})
+	dims := []int{y, x}
+	if spec.Is3D() {
+		z := coord("z", spec.NZ, typeDouble,
+			attribute{"long_name", "z coordinate of cell centre"},
+			attribute{"axis", "Z"},
+			attribute{"positive", "up"})
+		dims = append([]int{z}, dims...)
+	}
+	if opts.TimeUnits != "" {
+		t := coord("time", 1, typeDouble,
+			attribute{"standard_name", "time"},
+			attribute{"units", opts.TimeUnits},
+			attribute{"axis", "T"})
+		dims = append([]int{t}, dims...)
+	}
+	if realizations > 0 {
+		r := coord("realization", realizations, typeInt,
+			attribute{"standard_name", "realization"},
+			attribute{"long_name", "SGS realization"})
+		dims = append([]int{r}, dims...)
+	}
+
+	typ := typeFloat
+	var fill any = float32(opts.FillValue)
+	if opts.Float64 {
+		typ, fill = typeDouble, opts.FillValue
+	}
+	for _, name := range names {
+		h.vars = append(h.vars, variable{
+			name:  name,
+			dims:  dims,
+			typ:   typ,
+			attrs: []attribute{{"long_name", longNames[name]}, {"_FillValue", fill}},
+		})
+	}
+	return h
+}
+
+var longNames = map[string]string{
+	"field":    "kriging estimate",
+	"variance": "kriging variance",
+	"sim":      "simulated value",
+}
+
+// coordinates returns the data of the coordinate variables, which precede
+// the data variables
+func coordinates(spec grid.Spec, opts Options, realizations int) []byte {
+	var b []byte
+	for col := 0; col < spec.NX; col++ {
+		b = binary.BigEndian.AppendUint64(b, math.Float64bits(spec.Node(col, 0, 0).X))
+	}
+	// rows run from north to south, like the nodes of the grid
+	for row := 0; row < spec.NY; row++ {
+		b = binary.BigEndian.AppendUint64(b, math.Float64bits(spec.Node(0, row, 0).Y))
+	}
+	for layer := 0; layer < spec.NZ; layer++ {
+		b = binary.BigEndian.AppendUint64(b, math.Float64bits(spec.Node(0, 0, layer).Z))
+	}
+	if opts.TimeUnits != "" {
+		b = binary.BigEndian.AppendUint64(b, math.Float64bits(opts.Time))
+	}
+	for r := 0; r < realizations; r++ {
+		b = binary.BigEndian.AppendUint32(b, uint32(r))
+	}
+	return b
+}
+
+// encoder writes values in the data type of the options
+type encoder struct {
+	w    *bufio.Writer
+	opts Options
+	buf  []byte
+}
+
+func newEncoder(w io.Writer, opts Options) *encoder {
+	e := &encoder{w: bufio.NewWriter(w), opts: opts, buf: make([]byte, 4)}
+	if opts.Float64 {
+		e.buf = make([]byte, 8)
+	}
+	return e
+}
+
+func (e *encoder) write(v float64) error {
+	if math.IsNaN(v) {
+		v = e.opts.FillValue
+	}
+	if e.opts.Float64 {
+		binary.BigEndian.PutUint64(e.buf, math.Float64bits(v))
+	} else {
+		binary.BigEndian.PutUint32(e.buf, math.Float32bits(float32(v)))
+	}
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+// Sink writes kriging estimations into a NetCDF file with the variables
+// field and variance. Both are written at their own offset as soon as an
+// estimation is produced. Estimations have to arrive in the order of
+// grid.Spec.At. It implements types.EstimationSink.
+type Sink struct {
+	field, variance *encoder
+	spec            grid.Spec
+	n               int
+}
+
+// NewSink writes the header and coordinates of a file for the grid spec,
+// e.g. into an *os.File, and returns the sink writing the estimations.
+func NewSink(w io.WriterAt, spec grid.Spec, opts Options) (*Sink, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	h := newHeader(spec, opts, 0, "field", "variance")
+	version, _ := h.layout()
+	b := append(h.encode(version), coordinates(spec, opts, 0)...)
+	if _, err := w.WriteAt(b, 0); err != nil {
+		return nil, err
+	}
+	field, variance := h.vars[len(h.vars)-2], h.vars[len(h.vars)-1]
+	return &Sink{
+		field:    newEncoder(io.NewOffsetWriter(w, field.begin), opts),
+		variance: newEncoder(io.NewOffsetWriter(w, variance.begin), opts),
+		spec:     spec,
+	}, nil
+}
+
+func (s *Sink) Write(p types.Point, e types.Estimation) error {
+	if s.n >= s.spec.Len() {
+		return fmt.Errorf("more values than the grid holds (%d)", s.spec.Len())
+	}
+	s.n++
+	if err := s.field.write(e.Field); err != nil {
+		return err
+	}
+	return s.variance.write(e.Variance)
+}
+
+func (s *Sink) Flush() error {
+	if s.n != s.spec.Len() {
+		return fmt.Errorf("grid incomplete: got %d of %d values", s.n, s.spec.Len())
+	}
+	if err := s.field.w.Flush(); err != nil {
+		return err
+	}
+	return s.variance.w.Flush()
+}
+
+// Ensemble writes a stack of SGS realizations into a single NetCDF file with
+// the variable sim. Realizations may be written in any order, as each one is
+// written at its own offset.
+type Ensemble struct {
+	w     io.WriterAt
+	spec  grid.Spec
+	opts  Options
+	n     int
+	begin int64
+	slab  int64
+}
+
+// NewEnsemble writes the header and coordinates of a file for n realizations
+// on the grid spec, e.g. into an *os.File.
+func NewEnsemble(w io.WriterAt, spec grid.Spec, n int, opts Options) (*Ensemble, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if n < 1 {
+		return nil, fmt.Errorf("at least one realization is needed")
+	}
+	h := newHeader(spec, opts, n, "sim")
+	version, _ := h.layout()
+	b := append(h.encode(version), coordinates(spec, opts, n)...)
+	if _, err := w.WriteAt(b, 0); err != nil {
+		return nil, err
+	}
+	sim := h.vars[len(h.vars)-1]
+	return &Ensemble{
+		w:     w,
+		spec:  spec,
+		opts:  opts,
+		n:     n,
+		begin: sim.begin,
+		slab:  int64(spec.Len()) * typeSize(sim.typ),
+	}, nil
+}
+
+// Realization returns the sink for realization sim, counted from 0. It
+// matches the sink factory of sgs.SGS.SimulateTo.
+func (e *Ensemble) Realization(sim int) (types.EstimationSink, error) {
+	if sim < 0 || sim >= e.n {
+		return nil, fmt.Errorf("realization %d out of range [0, %d)", sim, e.n)
+	}
+	w := io.NewOffsetWriter(e.w, e.begin+int64(sim)*e.slab)
+	return &realization{enc: newEncoder(w, e.opts), len: e.spec.Len()}, nil
+}
+
+// realization writes the field of one realization
+type realization struct {
+	enc *encoder
+	n   int
+	len int
+}
+
+func (r *realization) Write(p types.Point, e types.Estimation) error {
+	if r.n >= r.len {
+		return fmt.Errorf("more values than the grid holds (%d)", r.len)
+	}
+	r.n++
+	return r.enc.write(e.Field)
+}
+
+func (r *realization) Flush() error {
+	if r.n != r.len {
+		return fmt.Errorf("grid incomplete: got %d of %d values", r.n, r.len)
+	}
+	return r.enc.w.Flush()
+}
@@ -0,0 +1,123 @@
+package plot
+
+import (
+	"math"
+	"strconv"
+)
+
+// axes maps data coordinates onto a pixel box of a figure
+type axes struct {
+	fig *Figure
+	// left, top, width and height of the box in pixels
+	left, top, width, height float64
+	xmin, xmax, ymin, ymax   float64
+}
+
+// newAxes returns axes on the box with the given pixel margins. The data
+// ranges are widened to nice tick values.
+func newAxes(f *Figure, left, right, top, bottom float64, xmin, xmax, ymin, ymax float64) *axes {
+	a := &axes{
+		fig:    f,
+		left:   left,
+		top:    top,
+		width:  float64(f.Width) - left - right,
+		height: float64(f.Height) - top - bottom,
+	}
+	a.xmin, a.xmax = extent(xmin, xmax)
+	a.ymin, a.ymax = extent(ymin, ymax)
+	return a
+}
+
+// extent widens a range to the outermost nice ticks and makes sure it is
+// not empty
+func extent(lo, hi float64) (float64, float64) {
+	if math.IsNaN(lo) || math.IsNaN(hi) || math.IsInf(lo, 0) || math.IsInf(hi, 0) {
+		return 0, 1
+	}
+	if lo == hi {
+		pad := math.Max(math.Abs(lo)*0.1, 1)
+		lo, hi = lo-pad, hi+pad
+	}
+	step := niceStep(lo, hi)
+	return math.Floor(lo/step) * step, math.Ceil(hi/step) * step
+}
+
+// niceStep returns a tick spacing of 1, 2 or 5 times a power of ten that
+// splits the range into about five intervals
+func niceStep(lo, hi float64) float64 {
+	raw := (hi - lo) / 5
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5} {
+		if raw <= m*mag {
+			return m * mag
+		}
+	}
+	return 10 * mag
+}
+
+// ticks returns the nice tick values within lo and hi
+func ticks(lo, hi float64) []float64 {
+	step := niceStep(lo, hi)
+	var t []float64
+	for v := math.Ceil(lo/step-1e-9) * step; v <= hi+step*1e-9; v += step {
+		// avoid printing -0 and rounding noise
+		t = append(t, math.Round(v/step)*step)
+	}
+	return t
+}
+
+// label formats a value with four significant digits, but keeps large values
+// such as projected coordinates in full
+func label(v float64) string {
+	if v == 0 {
+		return "0"
+	}
+	if a := math.Abs(v); a >= 1e4 && a < 1e9 {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+func (a *axes) px(x float64) float64 {
+	return a.left + (x-a.xmin)/(a.xmax-a.xmin)*a.width
+}
+
+func (a *axes) py(y float64) float64 {
+	return a.top + a.height - (y-a.ymin)/(a.ymax-a.ymin)*a.height
+}
+
+// contains reports whether the data position is inside the box
+func (a *axes) contains(x, y float64) bool {
+	return x >= a.xmin && x <= a.xmax && y >= a.ymin && y <= a.ymax
+}
+
+// frame draws the box, the ticks with their labels and the axis labels.
+// Either axis label may be empty.
+func (a *axes) frame(xlabel, ylabel string) {
+	f := a.fig
+	bottom := a.top + a.height
+	for _, x := range ticks(a.xmin, a.xmax) {
+		p := a.px(x)
+		f.line([]point{{p, bottom}, {p, bottom + 5}}, black, 1, false)
+		f.text(p, bottom+18, label(x), 11, middle, false)
+	}
+	for _, y := range ticks(a.ymin, a.ymax) {
+		p := a.py(y)
+		f.line([]point{{a.left - 5, p}, {a.left, p}}, black, 1, false)
+		f.text(a.left-8, p+4, label(y), 11, end, false)
+	}
+	f.rect(a.left, a.top, a.width, a.height, none, black)
+	if xlabel != "" {
+		f.text(a.left+a.width/2, bottom+38, xlabel, 13, middle, false)
+	}
+	if ylabel != "" {
+		f.text(a.left-52, a.top+a.height/2, ylabel, 13, middle, true)
+	}
+}
+
+// title draws a centred title above the box
+func (a *axes) title(s string) {
+	if s != "" {
+		a.fig.text(a.left+a.width/2, a.top-12, s, 14, middle, false)
+	}
+}
@@ -0,0 +1,358 @@
+package plot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/mmaelicke/go-geostat/internal/crossval"
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// Size of all figures in pixels
+const (
+	width  = 640
+	height = 480
+)
+
+// maxCloudPairs limits the pairs drawn in a variogram cloud, larger clouds
+// are thinned evenly
+const maxCloudPairs = 20000
+
+// Variogram plots the experimental variogram of v at its lag edges, with the
+// pair count of each lag class as bars in a panel above. A fitted model m is
+// drawn as a curve, it may be nil.
+func Variogram(v types.SampleVariogram, m types.SpatialFunction) *Figure {
+	f := newFigure(width, height)
+	edges, gamma, counts := v.GetEdges(), v.GetSemivariances(), v.GetHistogram()
+
+	maxLag, maxGamma, maxCount := 0.0, 0.0, 0
+	for i, e := range edges {
+		maxLag = math.Max(maxLag, e)
+		if i < len(gamma) && !math.IsNaN(gamma[i]) {
+			maxGamma = math.Max(maxGamma, gamma[i])
+		}
+		if i < len(counts) {
+			maxCount = max(maxCount, counts[i])
+		}
+	}
+	if m != nil {
+		maxGamma = math.Max(maxGamma, m.Sill()+m.Nugget())
+	}
+
+	// pair counts
+	bars := newAxes(f, 80, 30, 40, 360, 0, maxLag, 0, float64(maxCount))
+	bars.xmin = 0
+	for i, n := range counts {
+		if i >= len(edges) {
+			break
+		}
+		lo := 0.0
+		if i > 0 {
+			lo = edges[i-1]
+		}
+		x0, x1 := bars.px(lo), bars.px(edges[i])
+		y := bars.py(float64(n))
+		f.rect(x0+1, y, math.Max(x1-x0-2, 1), bars.py(0)-y, grey, none)
+	}
+	f.rect(bars.left, bars.top, bars.width, bars.height, none, black)
+	f.text(bars.left-8, bars.top+10, label(bars.ymax), 11, end, false)
+	f.text(bars.left-52, bars.top+bars.height/2, "N", 13, middle, true)
+
+	// semivariances
+	a := newAxes(f, 80, 30, 140, 60, 0, maxLag, 0, maxGamma)
+	a.xmin, a.ymin = 0, 0
+	a.frame("Lag distance", "Semivariance")
+	if m != nil {
+		var curve []point
+		for i := 0; i <= 200; i++ {
+			h := a.xmax * float64(i) / 200
+			if g := m.Evaluate(h); !math.IsNaN(g) && a.contains(h, g) {
+				curve = append(curve, point{a.px(h), a.py(g)})
+			}
+		}
+		f.line(curve, red, 2, false)
+	}
+	for i, e := range edges {
+		if i < len(gamma) && !math.IsNaN(gamma[i]) {
+			f.circle(a.px(e), a.py(gamma[i]), 4, blue)
+		}
+	}
+
+	// legend
+	x, y := a.left+a.width-10, a.top+20
+	rows := 1
+	if m != nil {
+		rows = 5
+	}
+	f.rect(x-130, y-16, 136, float64(rows*17)+8, white, grey)
+	f.circle(x-6, y-4, 4, blue)
+	f.text(x-16, y, "experimental", 12, end, false)
+	if m != nil {
+		y += 18
+		f.line([]point{{x - 14, y - 4}, {x + 2, y - 4}}, red, 2, false)
+		f.text(x-20, y, m.Name(), 12, end, false)
+		for _, s := range []string{
+			"range = " + label(m.Range()),
+			"sill = " + label(m.Sill()),
+			"nugget = " + label(m.Nugget()),
+		} {
+			y += 16
+			f.text(x-20, y, s, 11, end, false)
+		}
+	}
+	return f
+}
+
+// Cloud plots the semivariance 0.5 (zi - zj)^2 of every point pair against
+// their distance, up to maxLag. A maxLag of 0 includes all pairs.
+func Cloud(points types.Points, dist types.Distance, maxLag float64) *Figure {
+	f := newFigure(width, height)
+	p := points.Points
+	within := func(i, j int) (float64, bool) {
+		d := dist.Compute(&p[i], &p[j])
+		return d, maxLag <= 0 || d <= maxLag
+	}
+	n := 0
+	for i := range p {
+		for j := i + 1; j < len(p); j++ {
+			if _, ok := within(i, j); ok {
+				n++
+			}
+		}
+	}
+	stride := max(1, (n+maxCloudPairs-1)/maxCloudPairs)
+
+	var h, gamma []float64
+	k := 0
+	for i := range p {
+		for j := i + 1; j < len(p); j++ {
+			d, ok := within(i, j)
+			if !ok {
+				continue
+			}
+			if k++; k%stride != 0 {
+				continue
+			}
+			diff := p[i].Value - p[j].Value
+			h = append(h, d)
+			gamma = append(gamma, 0.5*diff*diff)
+		}
+	}
+
+	a := newAxes(f, 80, 30, 40, 60, 0, maxOf(h), 0, maxOf(gamma))
+	a.frame("Lag distance", "Semivariance")
+	title := fmt.Sprintf("Variogram cloud (%d pairs)", len(h))
+	if stride > 1 {
+		title = fmt.Sprintf("Variogram cloud (%d of %d pairs)", len(h), n)
+	}
+	a.title(title)
+	dot := color.NRGBA{blue.R, blue.G, blue.B, 90}
+	for i := range h {
+		f.circle(a.px(h[i]), a.py(gamma[i]), 2, dot)
+	}
+	return f
+}
+
+// CrossValidation plots the predicted against the observed values, with the
+// 1:1 line and the summary statistics. Failed predictions are skipped.
+func CrossValidation(results []crossval.Result, summary crossval.Summary) *Figure {
+	f := newFigure(width, height)
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, r := range results {
+		if math.IsNaN(r.Predicted) {
+			continue
+		}
+		lo = math.Min(lo, math.Min(r.Observed, r.Predicted))
+		hi = math.Max(hi, math.Max(r.Observed, r.Predicted))
+	}
+
+	// a square box with equal scales
+	a := newAxes(f, 80, 150, 40, 60, lo, hi, lo, hi)
+	side := math.Min(a.width, a.height)
+	a.width, a.height = side, side
+	a.frame("Observed", "Predicted")
+	a.title("Cross-validation")
+	f.line([]point{{a.px(a.xmin), a.py(a.ymin)}, {a.px(a.xmax), a.py(a.ymax)}}, grey, 1, true)
+	dot := color.NRGBA{blue.R, blue.G, blue.B, 160}
+	for _, r := range results {
+		if !math.IsNaN(r.Predicted) {
+			f.circle(a.px(r.Observed), a.py(r.Predicted), 3, dot)
+		}
+	}
+
+	x, y := a.left+a.width+20, a.top+20
+	for _, s := range []string{
+		fmt.Sprintf("n = %d", summary.N),
+		fmt.Sprintf("failed = %d", summary.Failed),
+		"ME = " + label(summary.ME),
+		"MAE = " + label(summary.MAE),
+		"RMSE = " + label(summary.RMSE),
+		"MSDR = " + label(summary.MSDR),
+		"r = " + label(summary.Correlation),
+	} {
+		f.text(x, y, s, 12, start, false)
+		y += 18
+	}
+	return f
+}
+
+// Raster plots the values of a 2D grid in spec order with the viridis colour
+// map and a colour bar labelled name. NaN cells are left blank.
+func Raster(spec grid.Spec, values []float64, title, name string) (*Figure, error) {
+	if spec.Is3D() {
+		return nil, fmt.Errorf("cannot plot a 3D grid")
+	}
+	if len(values) != spec.Len() {
+		return nil, fmt.Errorf("got %d values for a grid of %d nodes", len(values), spec.Len())
+	}
+	f := newFigure(width, height)
+	b := spec.Bounds()
+	lo, hi := minOf(values), maxOf(values)
+
+	img := image.NewNRGBA(image.Rect(0, 0, spec.NX, spec.NY))
+	for row := 0; row < spec.NY; row++ {
+		for col := 0; col < spec.NX; col++ {
+			t := 0.5
+			if hi > lo {
+				t = (values[spec.Offset(col, row, 0)] - lo) / (hi - lo)
+			}
+			if math.IsNaN(values[spec.Offset(col, row, 0)]) {
+				t = math.NaN()
+			}
+			img.SetNRGBA(col, row, colormap(t))
+		}
+	}
+
+	// keep the aspect ratio of the grid within the plot box
+	a := newAxes(f, 80, 130, 40, 60, b.MinX, b.MaxX, b.MinY, b.MaxY)
+	a.xmin, a.xmax, a.ymin, a.ymax = b.MinX, b.MaxX, b.MinY, b.MaxY
+	scale := math.Min(a.width/(b.MaxX-b.MinX), a.height/(b.MaxY-b.MinY))
+	a.width, a.height = (b.MaxX-b.MinX)*scale, (b.MaxY-b.MinY)*scale
+	f.image(a.left, a.top, a.width, a.height, img)
+	a.frame("X", "Y")
+	a.title(title)
+	colorbar(f, a.left+a.width+30, a.top, a.height, lo, hi, name)
+	return f, nil
+}
+
+// Histogram plots the distribution of values in bins classes of equal
+// width. NaN values are ignored.
+func Histogram(values []float64, bins int, name string) *Figure {
+	f := newFigure(width, height)
+	bins = max(bins, 1)
+	lo, hi := minOf(values), maxOf(values)
+	counts := make([]int, bins)
+	step := (hi - lo) / float64(bins)
+	for _, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		i := bins - 1
+		if step > 0 {
+			i = min(int((v-lo)/step), bins-1)
+		}
+		counts[i]++
+	}
+	maxCount := 0
+	for _, c := range counts {
+		maxCount = max(maxCount, c)
+	}
+
+	a := newAxes(f, 80, 30, 40, 60, lo, hi, 0, float64(maxCount))
+	a.ymin = 0
+	if step > 0 {
+		a.xmin, a.xmax = lo, hi
+	}
+	for i, c := range counts {
+		x0, x1 := a.px(lo+float64(i)*step), a.px(lo+float64(i+1)*step)
+		if step == 0 {
+			x0, x1 = a.px(lo)-10, a.px(lo)+10
+		}
+		y := a.py(float64(c))
+		f.rect(x0, y, math.Max(x1-x0, 1), a.py(0)-y, blue, white)
+	}
+	a.frame(name, "Count")
+	a.title(fmt.Sprintf("Histogram of %s", name))
+	return f
+}
+
+// Locations maps the sample locations in x and y, coloured by their value,
+// with a colour bar labelled name.
+func Locations(points types.Points, name string) *Figure {
+	f := newFigure(width, height)
+	values := make([]float64, len(points.Points))
+	for i, p := range points.Points {
+		values[i] = p.Value
+	}
+	lo, hi := minOf(values), maxOf(values)
+	b := grid.BoundsOf(points)
+	if len(points.Points) == 0 {
+		b = grid.Bounds{MaxX: 1, MaxY: 1}
+	}
+
+	// equal scales in x and y
+	a := newAxes(f, 80, 130, 40, 60, b.MinX, b.MaxX, b.MinY, b.MaxY)
+	scale := math.Min(a.width/(a.xmax-a.xmin), a.height/(a.ymax-a.ymin))
+	a.width, a.height = (a.xmax-a.xmin)*scale, (a.ymax-a.ymin)*scale
+	a.frame("X", "Y")
+	a.title(fmt.Sprintf("Sample locations (%d)", len(points.Points)))
+	for _, p := range points.Points {
+		t := 0.5
+		if hi > lo {
+			t = (p.Value - lo) / (hi - lo)
+		}
+		f.circle(a.px(p.X), a.py(p.Y), 4, colormap(t))
+	}
+	colorbar(f, a.left+a.width+30, a.top, a.height, lo, hi, name)
+	return f
+}
+
+// colorbar draws the colour map from lo at the bottom to hi at the top
+func colorbar(f *Figure, x, top, height, lo, hi float64, name string) {
+	if math.IsNaN(lo) || math.IsNaN(hi) {
+		return
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 256))
+	for i := 0; i < 256; i++ {
+		img.SetNRGBA(0, i, colormap(1-float64(i)/255))
+	}
+	f.image(x, top, 16, height, img)
+	f.rect(x, top, 16, height, none, black)
+	if hi == lo {
+		f.text(x+22, top+height/2+4, label(lo), 11, start, false)
+	} else {
+		for _, v := range ticks(lo, hi) {
+			y := top + height - (v-lo)/(hi-lo)*height
+			f.line([]point{{x + 16, y}, {x + 20, y}}, black, 1, false)
+			f.text(x+22, y+4, label(v), 11, start, false)
+		}
+	}
+	if name != "" {
+		f.text(x+8, top-12, name, 12, middle, false)
+	}
+}
+
+// minOf returns the smallest non-NaN value, or NaN if there is none
+func minOf(v []float64) float64 {
+	m := math.NaN()
+	for _, x := range v {
+		if !math.IsNaN(x) && (math.IsNaN(m) || x < m) {
+			m = x
+		}
+	}
+	return m
+}
+
+// maxOf returns the largest non-NaN value, or NaN if there is none
+func maxOf(v []float64) float64 {
+	m := math.NaN()
+	for _, x := range v {
+		if !math.IsNaN(x) && (math.IsNaN(m) || x > m) {
+			m = x
+		}
+	}
+	return m
+}
@@ -0,0 +1,35 @@
+package plot
+
+import (
+	"image/color"
+	"math"
+)
+
+// viridis holds evenly spaced stops of the viridis colour map
+var viridis = []color.NRGBA{
+	{0x44, 0x01, 0x54, 255},
+	{0x48, 0x28, 0x78, 255},
+	{0x3E, 0x4A, 0x89, 255},
+	{0x31, 0x68, 0x8E, 255},
+	{0x26, 0x82, 0x8E, 255},
+	{0x1F, 0x9E, 0x89, 255},
+	{0x35, 0xB7, 0x79, 255},
+	{0x6D, 0xCD, 0x59, 255},
+	{0xB4, 0xDE, 0x2C, 255},
+	{0xFD, 0xE7, 0x25, 255},
+}
+
+// colormap returns the viridis colour of t in [0, 1]. NaN is transparent.
+func colormap(t float64) color.NRGBA {
+	if math.IsNaN(t) {
+		return none
+	}
+	t = math.Max(0, math.Min(1, t)) * float64(len(viridis)-1)
+	i := min(int(t), len(viridis)-2)
+	frac := t - float64(i)
+	a, b := viridis[i], viridis[i+1]
+	mix := func(u, v uint8) uint8 {
+		return uint8(math.Round(float64(u) + frac*(float64(v)-float64(u))))
+	}
+	return color.NRGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+}
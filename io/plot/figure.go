@@ -0,0 +1,149 @@
+// Package plot draws variograms, variogram clouds, cross-validation results
+// and colour-mapped rasters as SVG or PNG images, without any dependency
+// outside the standard library.
+//
+// A Figure records drawing operations in pixel coordinates, with the origin
+// in the upper left corner, and renders them to either format. PNG text is
+// drawn with a built-in 5 x 7 pixel font.
+package plot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format is an image format a Figure can be written in.
+type Format int
+
+const (
+	SVG Format = iota
+	PNG
+)
+
+// ParseFormat returns the format called name: svg or png.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "svg":
+		return SVG, nil
+	case "png":
+		return PNG, nil
+	default:
+		return SVG, fmt.Errorf("unsupported plot format: %s", name)
+	}
+}
+
+// anchor aligns text horizontally to its position
+type anchor int
+
+const (
+	start anchor = iota
+	middle
+	end
+)
+
+type opKind int
+
+const (
+	opLine opKind = iota
+	opCircle
+	opRect
+	opText
+	opImage
+)
+
+// op is a single drawing operation
+type op struct {
+	kind opKind
+	// pts is the polyline of opLine
+	pts []point
+	// x, y is the centre of opCircle, the upper left corner of opRect and
+	// opImage, and the baseline position of opText
+	x, y, w, h, r float64
+	stroke        color.NRGBA
+	fill          color.NRGBA
+	width         float64
+	dashed        bool
+	text          string
+	size          float64
+	anchor        anchor
+	// vertical text is rotated by 90 degrees counter-clockwise
+	vertical bool
+	img      *image.NRGBA
+}
+
+type point struct{ x, y float64 }
+
+// Figure is an image of Width x Height pixels built from drawing operations.
+type Figure struct {
+	Width, Height int
+	ops           []op
+}
+
+// newFigure returns an empty figure of w x h pixels
+func newFigure(w, h int) *Figure {
+	return &Figure{Width: w, Height: h}
+}
+
+func (f *Figure) line(pts []point, c color.NRGBA, width float64, dashed bool) {
+	f.ops = append(f.ops, op{kind: opLine, pts: pts, stroke: c, width: width, dashed: dashed})
+}
+
+func (f *Figure) circle(x, y, r float64, fill color.NRGBA) {
+	f.ops = append(f.ops, op{kind: opCircle, x: x, y: y, r: r, fill: fill})
+}
+
+func (f *Figure) rect(x, y, w, h float64, fill, stroke color.NRGBA) {
+	f.ops = append(f.ops, op{kind: opRect, x: x, y: y, w: w, h: h, fill: fill, stroke: stroke, width: 1})
+}
+
+func (f *Figure) text(x, y float64, s string, size float64, a anchor, vertical bool) {
+	f.ops = append(f.ops, op{kind: opText, x: x, y: y, text: s, size: size, anchor: a, vertical: vertical, fill: black})
+}
+
+func (f *Figure) image(x, y, w, h float64, img *image.NRGBA) {
+	f.ops = append(f.ops, op{kind: opImage, x: x, y: y, w: w, h: h, img: img})
+}
+
+// Write renders the figure to w in format.
+func (f *Figure) Write(w io.Writer, format Format) error {
+	switch format {
+	case SVG:
+		return f.writeSVG(w)
+	case PNG:
+		return f.writePNG(w)
+	default:
+		return fmt.Errorf("unsupported plot format %d", format)
+	}
+}
+
+// Save writes the figure to path, in the format given by its extension.
+func (f *Figure) Save(path string) error {
+	format, err := ParseFormat(filepath.Ext(path))
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if err := f.Write(file, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+var (
+	black = color.NRGBA{0, 0, 0, 255}
+	grey  = color.NRGBA{190, 190, 190, 255}
+	white = color.NRGBA{255, 255, 255, 255}
+	none  = color.NRGBA{}
+	// blue and red are the data and model colours
+	blue = color.NRGBA{31, 119, 180, 255}
+	red  = color.NRGBA{214, 39, 40, 255}
+)
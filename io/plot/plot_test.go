@@ -0,0 +1,103 @@
+package plot
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mmaelicke/go-geostat/internal/crossval"
+	"github.com/mmaelicke/go-geostat/internal/grid"
+)
+
+type sample struct{}
+
+func (sample) GetEdges() []float64         { return []float64{1, 2, 3, 4} }
+func (sample) GetHistogram() []int         { return []int{10, 20, 15, 5} }
+func (sample) GetSemivariances() []float64 { return []float64{0.2, 0.5, math.NaN(), 0.9} }
+
+// elements counts the elements of an SVG document by name
+func elements(t *testing.T, svg []byte) map[string]int {
+	t.Helper()
+	count := map[string]int{}
+	dec := xml.NewDecoder(bytes.NewReader(svg))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return count
+		}
+		if err != nil {
+			t.Fatalf("invalid SVG: %v", err)
+		}
+		if el, ok := tok.(xml.StartElement); ok {
+			count[el.Name.Local]++
+		}
+	}
+}
+
+func TestVariogramSVG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Variogram(sample{}, nil).Write(&buf, SVG); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	count := elements(t, buf.Bytes())
+	// three semivariances and the legend marker
+	if count["circle"] != 4 {
+		t.Errorf("got %d circles, want 4", count["circle"])
+	}
+	if !strings.Contains(buf.String(), ">Semivariance</text>") {
+		t.Error("missing axis label")
+	}
+}
+
+func TestCrossValidationPNG(t *testing.T) {
+	results := []crossval.Result{
+		{Observed: 1, Predicted: 1.2},
+		{Observed: 2, Predicted: 1.8},
+		{Observed: 3, Predicted: math.NaN()},
+	}
+	var buf bytes.Buffer
+	if err := CrossValidation(results, crossval.Summarize(results)).Write(&buf, PNG); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != width || b.Dy() != height {
+		t.Errorf("got %v, want %d x %d", b, width, height)
+	}
+}
+
+func TestRaster(t *testing.T) {
+	spec := grid.Spec{DX: 1, DY: 2, NX: 3, NY: 2}
+	values := []float64{0, 1, 2, 3, math.NaN(), 5}
+	fig, err := Raster(spec, values, "field", "")
+	if err != nil {
+		t.Fatalf("Raster() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := fig.Write(&buf, SVG); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	// the raster and the colour bar
+	if n := elements(t, buf.Bytes())["image"]; n != 2 {
+		t.Errorf("got %d images, want 2", n)
+	}
+
+	// the north-west node is drawn in the upper left corner, NaN is blank
+	img := fig.ops[0].img
+	if c := img.NRGBAAt(0, 0); c != colormap(0) {
+		t.Errorf("upper left pixel = %v, want %v", c, colormap(0))
+	}
+	if c := img.NRGBAAt(1, 1); c.A != 0 {
+		t.Errorf("NaN pixel = %v, want transparent", c)
+	}
+
+	if _, err := Raster(spec, values[1:], "", ""); err == nil {
+		t.Error("expected an error for a value count not matching the grid")
+	}
+}
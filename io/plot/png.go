@@ -0,0 +1,152 @@
+package plot
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+// writePNG rasterizes the figure and encodes it as PNG
+func (f *Figure) writePNG(w io.Writer) error {
+	img := image.NewNRGBA(image.Rect(0, 0, f.Width, f.Height))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	r := raster{img}
+	for _, o := range f.ops {
+		r.draw(o)
+	}
+	return png.Encode(w, img)
+}
+
+// raster draws operations into an image without anti-aliasing
+type raster struct {
+	img *image.NRGBA
+}
+
+// blend paints the pixel at x, y with c, respecting its alpha
+func (r raster) blend(x, y int, c color.NRGBA) {
+	if !(image.Point{x, y}.In(r.img.Rect)) || c.A == 0 {
+		return
+	}
+	i := r.img.PixOffset(x, y)
+	a := float64(c.A) / 255
+	for k, v := range [3]uint8{c.R, c.G, c.B} {
+		r.img.Pix[i+k] = uint8(math.Round(float64(v)*a + float64(r.img.Pix[i+k])*(1-a)))
+	}
+	r.img.Pix[i+3] = 255
+}
+
+// disc fills the circle around x, y
+func (r raster) disc(x, y, radius float64, c color.NRGBA) {
+	for py := int(math.Floor(y - radius)); py <= int(math.Ceil(y+radius)); py++ {
+		for px := int(math.Floor(x - radius)); px <= int(math.Ceil(x+radius)); px++ {
+			dx, dy := float64(px)+0.5-x, float64(py)+0.5-y
+			if dx*dx+dy*dy <= radius*radius {
+				r.blend(px, py, c)
+			}
+		}
+	}
+}
+
+// fill fills the rectangle with the upper left corner x, y
+func (r raster) fill(x, y, w, h float64, c color.NRGBA) {
+	for py := int(math.Round(y)); py < int(math.Round(y+h)); py++ {
+		for px := int(math.Round(x)); px < int(math.Round(x+w)); px++ {
+			r.blend(px, py, c)
+		}
+	}
+}
+
+// stroke draws a line of the given width from a to b. Dashed lines are
+// drawn from dash onwards, the distance already covered by the polyline.
+func (r raster) stroke(a, b point, width float64, c color.NRGBA, dashed bool, dash float64) float64 {
+	length := math.Hypot(b.x-a.x, b.y-a.y)
+	steps := int(math.Ceil(length * 2))
+	// stamp each pixel once, so that translucent lines are even
+	seen := map[image.Point]bool{}
+	radius := math.Max(width/2, 0.5)
+	for s := 0; s <= steps; s++ {
+		t := 0.0
+		if steps > 0 {
+			t = float64(s) / float64(steps)
+		}
+		if dashed && math.Mod(dash+t*length, 10) >= 6 {
+			continue
+		}
+		x, y := a.x+t*(b.x-a.x), a.y+t*(b.y-a.y)
+		for py := int(math.Floor(y - radius)); py <= int(math.Ceil(y+radius)); py++ {
+			for px := int(math.Floor(x - radius)); px <= int(math.Ceil(x+radius)); px++ {
+				dx, dy := float64(px)+0.5-x, float64(py)+0.5-y
+				p := image.Point{px, py}
+				if dx*dx+dy*dy <= radius*radius && !seen[p] {
+					seen[p] = true
+					r.blend(px, py, c)
+				}
+			}
+		}
+	}
+	return dash + length
+}
+
+// text draws s with the built-in font, scaled to roughly size pixels
+func (r raster) text(o op) {
+	scale := max(1, int(math.Round(o.size/9)))
+	width := float64(len(o.text)*6*scale - scale)
+	offset := [...]float64{0, width / 2, width}[o.anchor]
+	for i, ch := range o.text {
+		g := glyph(ch)
+		for col := 0; col < 5; col++ {
+			for row := 0; row < 7; row++ {
+				if g[col]&(1<<row) == 0 {
+					continue
+				}
+				// position along and across the text direction
+				along := float64((i*6+col)*scale) - offset
+				across := float64((row - 7) * scale)
+				x, y := o.x+along, o.y+across
+				if o.vertical {
+					x, y = o.x+across, o.y-along-float64(scale)
+				}
+				r.fill(x, y, float64(scale), float64(scale), o.fill)
+			}
+		}
+	}
+}
+
+// draw rasterizes a single operation
+func (r raster) draw(o op) {
+	switch o.kind {
+	case opLine:
+		dash := 0.0
+		for i := 1; i < len(o.pts); i++ {
+			dash = r.stroke(o.pts[i-1], o.pts[i], o.width, o.stroke, o.dashed, dash)
+		}
+	case opCircle:
+		r.disc(o.x, o.y, o.r, o.fill)
+	case opRect:
+		r.fill(o.x, o.y, o.w, o.h, o.fill)
+		if o.stroke.A > 0 {
+			corners := []point{{o.x, o.y}, {o.x + o.w, o.y}, {o.x + o.w, o.y + o.h}, {o.x, o.y + o.h}, {o.x, o.y}}
+			for i := 1; i < len(corners); i++ {
+				r.stroke(corners[i-1], corners[i], 1, o.stroke, false, 0)
+			}
+		}
+	case opText:
+		r.text(o)
+	case opImage:
+		// nearest neighbour scaling
+		b := o.img.Bounds()
+		for py := int(math.Round(o.y)); py < int(math.Round(o.y+o.h)); py++ {
+			for px := int(math.Round(o.x)); px < int(math.Round(o.x+o.w)); px++ {
+				sx := b.Min.X + int((float64(px)+0.5-o.x)/o.w*float64(b.Dx()))
+				sy := b.Min.Y + int((float64(py)+0.5-o.y)/o.h*float64(b.Dy()))
+				if (image.Point{sx, sy}).In(b) {
+					r.blend(px, py, o.img.NRGBAAt(sx, sy))
+				}
+			}
+		}
+	}
+}
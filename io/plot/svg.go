@@ -0,0 +1,113 @@
+package plot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// writeSVG renders the figure as SVG document
+func (f *Figure) writeSVG(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif">`+"\n",
+		f.Width, f.Height, f.Width, f.Height)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	for _, o := range f.ops {
+		if err := o.writeSVG(bw); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+func (o op) writeSVG(w *bufio.Writer) error {
+	switch o.kind {
+	case opLine:
+		var pts strings.Builder
+		for i, p := range o.pts {
+			if i > 0 {
+				pts.WriteByte(' ')
+			}
+			pts.WriteString(num(p.x) + "," + num(p.y))
+		}
+		dash := ""
+		if o.dashed {
+			dash = ` stroke-dasharray="6,4"`
+		}
+		fmt.Fprintf(w, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%s"%s%s/>`+"\n",
+			pts.String(), hex(o.stroke), num(o.width), opacity("stroke", o.stroke), dash)
+	case opCircle:
+		fmt.Fprintf(w, `<circle cx="%s" cy="%s" r="%s" fill="%s"%s/>`+"\n",
+			num(o.x), num(o.y), num(o.r), hex(o.fill), opacity("fill", o.fill))
+	case opRect:
+		fill, stroke := "none", ""
+		if o.fill.A > 0 {
+			fill = hex(o.fill)
+		}
+		if o.stroke.A > 0 {
+			stroke = fmt.Sprintf(` stroke="%s"`, hex(o.stroke))
+		}
+		fmt.Fprintf(w, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"%s%s/>`+"\n",
+			num(o.x), num(o.y), num(o.w), num(o.h), fill, opacity("fill", o.fill), stroke)
+	case opText:
+		anchor := [...]string{"start", "middle", "end"}[o.anchor]
+		transform := ""
+		if o.vertical {
+			transform = fmt.Sprintf(` transform="rotate(-90 %s %s)"`, num(o.x), num(o.y))
+		}
+		fmt.Fprintf(w, `<text x="%s" y="%s" font-size="%s" text-anchor="%s"%s>%s</text>`+"\n",
+			num(o.x), num(o.y), num(o.size), anchor, transform, escape(o.text))
+	case opImage:
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, o.img); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, `<image x="%s" y="%s" width="%s" height="%s" preserveAspectRatio="none" style="image-rendering:pixelated" href="data:image/png;base64,%s"/>`+"\n",
+			num(o.x), num(o.y), num(o.w), num(o.h), base64.StdEncoding.EncodeToString(buf.Bytes()))
+	}
+	return nil
+}
+
+// num formats a pixel coordinate with at most two decimals
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// hex returns the colour as #rrggbb
+func hex(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// opacity returns the opacity attribute of translucent colours
+func opacity(attr string, c color.NRGBA) string {
+	if c.A == 255 || c.A == 0 {
+		return ""
+	}
+	return fmt.Sprintf(` %s-opacity="%.3g"`, attr, float64(c.A)/255)
+}
+
+// escape escapes text for XML character data
+func escape(s string) string {
+	var b bytes.Buffer
+	for _, r := range s {
+		switch r {
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '&':
+			b.WriteString("&amp;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
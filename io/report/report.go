@@ -0,0 +1,257 @@
+// Package report writes a geostatistical analysis as a single, self-contained
+// HTML file for sharing: summary statistics, histogram and location map of
+// the data, the empirical and fitted variogram with fit statistics, the
+// cross-validation results and the kriging field and variance maps.
+//
+// All figures are embedded as inline SVG drawn by io/plot, so the file has no
+// external dependencies and opens in any browser.
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mmaelicke/go-geostat/internal/crossval"
+	"github.com/mmaelicke/go-geostat/internal/fitting"
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"github.com/mmaelicke/go-geostat/io/plot"
+	"gonum.org/v1/gonum/stat"
+)
+
+// Setting is an analysis parameter listed in the report.
+type Setting struct {
+	Name, Value string
+}
+
+// Variable holds the analysis results of a single variable. Sections without
+// results are left out: the variogram if Variogram is nil, the fitted model
+// if Model is nil, the cross-validation if Results is empty and the maps if
+// Field is nil.
+type Variable struct {
+	Name      string
+	Points    types.Points
+	Variogram types.SampleVariogram
+	Model     types.SpatialFunction
+	Results   []crossval.Result
+	Summary   crossval.Summary
+	// Grid holds the 2D kriging grid of Field and Variance, in spec order
+	Grid     grid.Spec
+	Field    []float64
+	Variance []float64
+}
+
+// Report is an analysis of one or more variables.
+type Report struct {
+	Title   string
+	Source  string
+	Created time.Time
+	// Settings are listed in order
+	Settings  []Setting
+	Variables []Variable
+}
+
+// Stats are the summary statistics of a set of values.
+type Stats struct {
+	N                        int
+	Mean, Std, Skewness      float64
+	Min, Q1, Median, Q3, Max float64
+}
+
+// Describe returns the summary statistics of the values, ignoring NaN.
+func Describe(values []float64) Stats {
+	v := make([]float64, 0, len(values))
+	for _, x := range values {
+		if !math.IsNaN(x) {
+			v = append(v, x)
+		}
+	}
+	if len(v) == 0 {
+		nan := math.NaN()
+		return Stats{Mean: nan, Std: nan, Skewness: nan, Min: nan, Q1: nan, Median: nan, Q3: nan, Max: nan}
+	}
+	sort.Float64s(v)
+	mean, std := stat.MeanStdDev(v, nil)
+	return Stats{
+		N:        len(v),
+		Mean:     mean,
+		Std:      std,
+		Skewness: stat.Skew(v, nil),
+		Min:      v[0],
+		Q1:       quantile(v, 0.25),
+		Median:   quantile(v, 0.5),
+		Q3:       quantile(v, 0.75),
+		Max:      v[len(v)-1],
+	}
+}
+
+// quantile interpolates the p-quantile of the sorted values linearly between
+// order statistics, like R and numpy do by default
+func quantile(sorted []float64, p float64) float64 {
+	h := p * float64(len(sorted)-1)
+	i := int(h)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (h-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// lag is a row of the experimental variogram table
+type lag struct {
+	Distance, Semivariance float64
+	Pairs                  int
+}
+
+// section is the rendered content of a variable
+type section struct {
+	Name      string
+	Stats     Stats
+	Histogram template.HTML
+	Map       template.HTML
+
+	Variogram template.HTML
+	Lags      []lag
+	Model     types.SpatialFunction
+	Fit       fitting.Statistics
+
+	CrossValidation template.HTML
+	Summary         crossval.Summary
+	Method          string
+
+	Field, Variance           template.HTML
+	FieldStats, VarianceStats Stats
+	Grid                      grid.Spec
+}
+
+// Write renders the report as HTML to w.
+func Write(w io.Writer, r Report) error {
+	data := struct {
+		Report
+		Sections []section
+	}{Report: r}
+	for _, v := range r.Variables {
+		s, err := newSection(v)
+		if err != nil {
+			return fmt.Errorf("variable %s: %w", v.Name, err)
+		}
+		data.Sections = append(data.Sections, s)
+	}
+	return page.Execute(w, data)
+}
+
+// WriteFile writes the report to an HTML file at path.
+func WriteFile(path string, r Report) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if err := Write(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// newSection computes the statistics and draws the figures of v
+func newSection(v Variable) (section, error) {
+	values := make([]float64, len(v.Points.Points))
+	for i, p := range v.Points.Points {
+		values[i] = p.Value
+	}
+	s := section{Name: v.Name, Stats: Describe(values), Model: v.Model}
+
+	var err error
+	bins := max(5, min(30, int(math.Ceil(math.Sqrt(float64(len(values)))))))
+	if s.Histogram, err = svg(plot.Histogram(values, bins, v.Name)); err != nil {
+		return s, err
+	}
+	if s.Map, err = svg(plot.Locations(v.Points, v.Name)); err != nil {
+		return s, err
+	}
+
+	if v.Variogram != nil {
+		if s.Variogram, err = svg(plot.Variogram(v.Variogram, v.Model)); err != nil {
+			return s, err
+		}
+		edges, gamma, counts := v.Variogram.GetEdges(), v.Variogram.GetSemivariances(), v.Variogram.GetHistogram()
+		for i, e := range edges {
+			l := lag{Distance: e, Semivariance: math.NaN()}
+			if i < len(gamma) {
+				l.Semivariance = gamma[i]
+			}
+			if i < len(counts) {
+				l.Pairs = counts[i]
+			}
+			s.Lags = append(s.Lags, l)
+		}
+		if v.Model != nil {
+			s.Fit = fitting.Goodness(v.Variogram, v.Model)
+		}
+	}
+
+	if len(v.Results) > 0 {
+		s.Summary = v.Summary
+		folds := 0
+		for _, r := range v.Results {
+			folds = max(folds, r.Fold+1)
+		}
+		s.Method = "leave-one-out"
+		if folds < len(v.Results) {
+			s.Method = fmt.Sprintf("%d-fold", folds)
+		}
+		if s.CrossValidation, err = svg(plot.CrossValidation(v.Results, v.Summary)); err != nil {
+			return s, err
+		}
+	}
+
+	if v.Field != nil {
+		s.Grid = v.Grid
+		s.FieldStats = Describe(v.Field)
+		fig, err := plot.Raster(v.Grid, v.Field, "Kriging estimate", v.Name)
+		if err != nil {
+			return s, err
+		}
+		if s.Field, err = svg(fig); err != nil {
+			return s, err
+		}
+		if v.Variance != nil {
+			s.VarianceStats = Describe(v.Variance)
+			fig, err := plot.Raster(v.Grid, v.Variance, "Kriging variance", "variance")
+			if err != nil {
+				return s, err
+			}
+			if s.Variance, err = svg(fig); err != nil {
+				return s, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// svg renders a figure for inlining into the page
+func svg(f *plot.Figure) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := f.Write(&buf, plot.SVG); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
+
+// num formats a value with four significant digits, but keeps large values
+// in full, like the figure labels
+func num(v float64) string {
+	if math.IsNaN(v) {
+		return "–"
+	}
+	if a := math.Abs(v); a >= 1e4 && a < 1e9 {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmaelicke/go-geostat/internal/crossval"
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+func TestDescribe(t *testing.T) {
+	s := Describe([]float64{4, math.NaN(), 1, 3, 2, 5})
+	if s.N != 5 || s.Mean != 3 || s.Min != 1 || s.Max != 5 || s.Median != 3 {
+		t.Errorf("Describe() = %+v", s)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	points := types.Points{Points: []types.Point{
+		{X: 0, Y: 0, Value: 1}, {X: 1, Y: 0, Value: 2}, {X: 0, Y: 1, Value: 3}, {X: 1, Y: 1, Value: 4},
+	}}
+	results := []crossval.Result{
+		{Index: 0, Fold: 0, Observed: 1, Predicted: 1.5},
+		{Index: 1, Fold: 1, Observed: 2, Predicted: 2.5},
+	}
+	r := Report{
+		Title:    "Test <report>",
+		Created:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Settings: []Setting{{Name: "Model", Value: "spherical"}},
+		Variables: []Variable{{
+			Name:     "value",
+			Points:   points,
+			Results:  results,
+			Summary:  crossval.Summarize(results),
+			Grid:     grid.Spec{DX: 1, DY: 1, NX: 2, NY: 2},
+			Field:    []float64{1, 2, 3, math.NaN()},
+			Variance: []float64{0.1, 0.2, 0.3, math.NaN()},
+		}},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, r); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	html := buf.String()
+
+	// histogram, location map, cross-validation, estimate and variance
+	if n := strings.Count(html, "<svg "); n != 5 {
+		t.Errorf("got %d figures, want 5", n)
+	}
+	for _, want := range []string{"Test &lt;report&gt;", "<h3>Cross-validation</h3>", "leave-one-out", "<h3>Kriging</h3>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+	// without a variogram its section is left out
+	if strings.Contains(html, "<h3>Variogram</h3>") {
+		t.Error("unexpected variogram section")
+	}
+	if strings.Contains(html, "src=") || strings.Contains(html, "<link") {
+		t.Error("report should not load external resources")
+	}
+}
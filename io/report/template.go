@@ -0,0 +1,110 @@
+package report
+
+import "html/template"
+
+// page is the HTML document of a report
+var page = template.Must(template.New("report").Funcs(template.FuncMap{"num": num}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 1320px; padding: 0 1em; color: #222; }
+h1 { margin-bottom: 0.2em; }
+h2 { border-bottom: 2px solid #1f77b4; padding-bottom: 0.2em; margin-top: 2em; }
+.meta { color: #666; }
+table { border-collapse: collapse; margin: 0.5em 0 1em; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.7em; }
+th { background: #f3f3f3; text-align: left; }
+td { text-align: right; }
+.figures { display: flex; flex-wrap: wrap; gap: 1em; align-items: flex-start; }
+svg { max-width: 100%; height: auto; border: 1px solid #eee; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">{{if .Source}}Data: {{.Source}} &middot; {{end}}Created {{.Created.Format "2006-01-02 15:04 MST"}}</p>
+{{- if .Settings}}
+<table>
+{{- range .Settings}}
+<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- range .Sections}}
+
+<h2>{{.Name}}</h2>
+<h3>Data</h3>
+{{template "stats" .Stats}}
+<div class="figures">
+{{.Histogram}}
+{{.Map}}
+</div>
+{{- if .Variogram}}
+
+<h3>Variogram</h3>
+<div class="figures">
+{{.Variogram}}
+<div>
+{{- if .Model}}
+<table>
+<tr><th>Model</th><td>{{.Model.Name}}</td></tr>
+<tr><th>Range</th><td>{{num .Model.Range}}</td></tr>
+<tr><th>Sill</th><td>{{num .Model.Sill}}</td></tr>
+<tr><th>Nugget</th><td>{{num .Model.Nugget}}</td></tr>
+<tr><th>Lags fitted</th><td>{{.Fit.N}}</td></tr>
+<tr><th>RMSE</th><td>{{num .Fit.RMSE}}</td></tr>
+<tr><th>Pair weighted RMSE</th><td>{{num .Fit.WRMSE}}</td></tr>
+<tr><th>R&sup2;</th><td>{{num .Fit.R2}}</td></tr>
+</table>
+{{- end}}
+<table>
+<tr><th>Lag</th><th>Pairs</th><th>Semivariance</th></tr>
+{{- range .Lags}}
+<tr><td>{{num .Distance}}</td><td>{{.Pairs}}</td><td>{{num .Semivariance}}</td></tr>
+{{- end}}
+</table>
+</div>
+</div>
+{{- end}}
+{{- if .CrossValidation}}
+
+<h3>Cross-validation</h3>
+<div class="figures">
+{{.CrossValidation}}
+<table>
+<tr><th>Method</th><td>{{.Method}}</td></tr>
+<tr><th>Predicted</th><td>{{.Summary.N}}</td></tr>
+<tr><th>Failed</th><td>{{.Summary.Failed}}</td></tr>
+<tr><th>Mean error</th><td>{{num .Summary.ME}}</td></tr>
+<tr><th>Mean absolute error</th><td>{{num .Summary.MAE}}</td></tr>
+<tr><th>RMSE</th><td>{{num .Summary.RMSE}}</td></tr>
+<tr><th>MSDR</th><td>{{num .Summary.MSDR}}</td></tr>
+<tr><th>Correlation</th><td>{{num .Summary.Correlation}}</td></tr>
+</table>
+</div>
+{{- end}}
+{{- if .Field}}
+
+<h3>Kriging</h3>
+<p>{{.Grid.NX}} &times; {{.Grid.NY}} nodes, spacing {{num .Grid.DX}} &times; {{num .Grid.DY}}</p>
+<div class="figures">
+{{.Field}}
+{{.Variance}}
+</div>
+<table>
+<tr><th></th><th>N</th><th>Mean</th><th>Std</th><th>Min</th><th>Median</th><th>Max</th></tr>
+<tr><th>Estimate</th><td>{{.FieldStats.N}}</td><td>{{num .FieldStats.Mean}}</td><td>{{num .FieldStats.Std}}</td><td>{{num .FieldStats.Min}}</td><td>{{num .FieldStats.Median}}</td><td>{{num .FieldStats.Max}}</td></tr>
+{{- if .Variance}}
+<tr><th>Variance</th><td>{{.VarianceStats.N}}</td><td>{{num .VarianceStats.Mean}}</td><td>{{num .VarianceStats.Std}}</td><td>{{num .VarianceStats.Min}}</td><td>{{num .VarianceStats.Median}}</td><td>{{num .VarianceStats.Max}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+</body>
+</html>
+{{define "stats"}}<table>
+<tr><th>N</th><th>Mean</th><th>Std</th><th>Skewness</th><th>Min</th><th>Q1</th><th>Median</th><th>Q3</th><th>Max</th></tr>
+<tr><td>{{.N}}</td><td>{{num .Mean}}</td><td>{{num .Std}}</td><td>{{num .Skewness}}</td><td>{{num .Min}}</td><td>{{num .Q1}}</td><td>{{num .Median}}</td><td>{{num .Q3}}</td><td>{{num .Max}}</td></tr>
+</table>{{end}}
+`))
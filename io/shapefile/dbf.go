@@ -0,0 +1,146 @@
+package shapefile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field describes one column of a dBASE table
+type field struct {
+	name   string
+	kind   byte
+	offset int
+	length int
+}
+
+// table reads the records of a dBASE III/IV table, as stored in the .dbf
+// file of a shapefile
+type table struct {
+	r       *bufio.Reader
+	fields  []field
+	n       int
+	record  []byte
+	current int
+}
+
+// newTable reads the header and field descriptors of a dBASE table
+func newTable(r io.Reader) (*table, error) {
+	br := bufio.NewReader(r)
+	head := make([]byte, 32)
+	if _, err := io.ReadFull(br, head); err != nil {
+		return nil, fmt.Errorf("failed to read DBF header: %w", err)
+	}
+	n := int(binary.LittleEndian.Uint32(head[4:]))
+	headerLen := int(binary.LittleEndian.Uint16(head[8:]))
+	recordLen := int(binary.LittleEndian.Uint16(head[10:]))
+	if headerLen < 33 || recordLen < 1 {
+		return nil, fmt.Errorf("invalid DBF header: header length %d, record length %d", headerLen, recordLen)
+	}
+
+	descriptors := make([]byte, headerLen-32)
+	if _, err := io.ReadFull(br, descriptors); err != nil {
+		return nil, fmt.Errorf("failed to read DBF fields: %w", err)
+	}
+	t := &table{r: br, n: n, record: make([]byte, recordLen)}
+	// the first byte of each record is the deletion flag
+	offset := 1
+	for i := 0; i+32 <= len(descriptors) && descriptors[i] != 0x0D; i += 32 {
+		d := descriptors[i : i+32]
+		name := d[:11]
+		if end := bytes.IndexByte(name, 0); end != -1 {
+			name = name[:end]
+		}
+		f := field{name: strings.TrimSpace(string(name)), kind: d[11], offset: offset, length: int(d[16])}
+		if offset+f.length > recordLen {
+			return nil, fmt.Errorf("DBF field %s exceeds the record length %d", f.name, recordLen)
+		}
+		t.fields = append(t.fields, f)
+		offset += f.length
+	}
+	return t, nil
+}
+
+// index returns the position of the field called name, matched
+// case-insensitively, or -1
+func (t *table) index(name string) int {
+	for i, f := range t.fields {
+		if strings.EqualFold(f.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// names returns the field names
+func (t *table) names() []string {
+	names := make([]string, len(t.fields))
+	for i, f := range t.fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// next reads the next record. It returns false for deleted records and
+// io.EOF after the last one.
+func (t *table) next() (bool, error) {
+	if t.current >= t.n {
+		return false, io.EOF
+	}
+	t.current++
+	if _, err := io.ReadFull(t.r, t.record); err != nil {
+		return false, fmt.Errorf("failed to read DBF record %d: %w", t.current, err)
+	}
+	return t.record[0] != '*', nil
+}
+
+// raw returns the bytes of field i in the current record
+func (t *table) raw(i int) []byte {
+	f := t.fields[i]
+	return t.record[f.offset : f.offset+f.length]
+}
+
+// text returns field i of the current record as text
+func (t *table) text(i int) string {
+	return strings.TrimSpace(strings.TrimRight(string(t.raw(i)), "\x00"))
+}
+
+// number returns field i of the current record as number, and false if it
+// is empty or not a number
+func (t *table) number(i int) (float64, bool) {
+	raw := t.raw(i)
+	switch t.fields[i].kind {
+	case 'I':
+		if len(raw) == 4 {
+			return float64(int32(binary.LittleEndian.Uint32(raw))), true
+		}
+	case 'O':
+		if len(raw) == 8 {
+			v := math.Float64frombits(binary.LittleEndian.Uint64(raw))
+			return v, !math.IsNaN(v)
+		}
+	case 'L', 'D':
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(t.text(i), 64)
+	return v, err == nil && !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// date returns field i of the current record as midnight in loc, or UTC, if
+// it is a date field
+func (t *table) date(i int, loc *time.Location) (time.Time, bool, error) {
+	if t.fields[i].kind != 'D' {
+		return time.Time{}, false, nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	d, err := time.ParseInLocation("20060102", t.text(i), loc)
+	return d, true, err
+}
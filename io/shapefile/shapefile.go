@@ -0,0 +1,271 @@
+// Package shapefile reads point data from ESRI shapefiles: the Point, PointM
+// or PointZ geometries of the .shp file together with the attributes of the
+// .dbf file next to it.
+package shapefile
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mmaelicke/go-geostat/internal/timeaxis"
+	"github.com/mmaelicke/go-geostat/internal/types"
+	"github.com/mmaelicke/go-geostat/io/csv"
+)
+
+// Fields maps the point fields to DBF attribute names, which are matched
+// case-insensitively. An empty Values list falls back to a single value
+// attribute.
+type Fields struct {
+	// Values are read into Point.Attributes. The first one is also Point.Value.
+	Values []string
+	// Covariates are read into Point.Covariates.
+	Covariates []string
+	// Group is an optional attribute of group ids, e.g. drillhole names.
+	Group string
+	// ErrorVariance is an optional attribute of measurement error variances.
+	ErrorVariance string
+	// Time is an optional date attribute, or a text attribute parsed by
+	// trying TimeLayouts in order. They default to timeaxis.DefaultLayouts.
+	Time        string
+	TimeLayouts []string
+	// Date is an optional date attribute. If set, the time attribute holds
+	// the time of day. A DBF date is combined with the time of day parsed by
+	// TimeLayouts or as 15:04:05; a text date is joined to it by a space
+	// before parsing.
+	Date string
+	// Location is the time zone of times without zone. It defaults to UTC.
+	Location *time.Location
+}
+
+// ReadPointsFromReader reads the points of a .shp file and their attributes
+// from the matching .dbf file. Points are 3D for PointZ shapefiles. A record
+// is skipped if its shape is null, its DBF record is deleted, none of its
+// value attributes is a number, if it lacks a covariate, or if its error
+// variance or time is invalid; single missing values are stored as NaN. The
+// report lists the skipped records by their number, counted from 1.
+func ReadPointsFromReader(shp, dbf io.Reader, fields Fields) (types.Points, csv.Report, error) {
+	var report csv.Report
+	shps, err := newShapes(shp)
+	if err != nil {
+		return types.Points{}, report, err
+	}
+	tbl, err := newTable(dbf)
+	if err != nil {
+		return types.Points{}, report, err
+	}
+	if len(fields.Values) == 0 {
+		fields.Values = []string{"value"}
+	}
+
+	find := func(kind, name string) (int, error) {
+		i := tbl.index(name)
+		if i == -1 {
+			return -1, fmt.Errorf("missing %s attribute %q in %v", kind, name, tbl.names())
+		}
+		return i, nil
+	}
+	valueIdx := make([]int, len(fields.Values))
+	for v, name := range fields.Values {
+		if valueIdx[v], err = find("value", name); err != nil {
+			return types.Points{}, report, err
+		}
+	}
+	covIdx := make([]int, len(fields.Covariates))
+	for c, name := range fields.Covariates {
+		if covIdx[c], err = find("covariate", name); err != nil {
+			return types.Points{}, report, err
+		}
+	}
+	gIdx, eIdx, tIdx := -1, -1, -1
+	if fields.Group != "" {
+		if gIdx, err = find("group", fields.Group); err != nil {
+			return types.Points{}, report, err
+		}
+	}
+	if fields.ErrorVariance != "" {
+		if eIdx, err = find("error variance", fields.ErrorVariance); err != nil {
+			return types.Points{}, report, err
+		}
+	}
+	if fields.Time != "" {
+		if tIdx, err = find("time", fields.Time); err != nil {
+			return types.Points{}, report, err
+		}
+	}
+	dIdx := -1
+	if fields.Date != "" {
+		if dIdx, err = find("date", fields.Date); err != nil {
+			return types.Points{}, report, err
+		}
+	}
+	times := timeaxis.NewParser(fields.TimeLayouts...)
+	times.Location = fields.Location
+
+	points := types.Points{Is3D: shps.is3D()}
+	for {
+		pos, err := shps.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return types.Points{}, report, err
+		}
+		valid, err := tbl.next()
+		if err == io.EOF {
+			return types.Points{}, report, fmt.Errorf("DBF has fewer records than the shapefile (%d)", tbl.n)
+		}
+		if err != nil {
+			return types.Points{}, report, err
+		}
+		report.Rows++
+		skip := func(reason string) {
+			report.Skipped = append(report.Skipped, csv.SkippedRow{Line: report.Rows, Reason: reason})
+		}
+		if pos.null {
+			skip("null shape")
+			continue
+		}
+		if !valid {
+			skip("deleted record")
+			continue
+		}
+
+		p := types.Point{X: pos.x, Y: pos.y, Attributes: make(map[string]float64, len(valueIdx))}
+		if points.Is3D {
+			p.Z, p.Is3D = pos.z, true
+		}
+		parsed := 0
+		for v, idx := range valueIdx {
+			value, ok := tbl.number(idx)
+			if !ok {
+				value = math.NaN()
+			} else {
+				parsed++
+			}
+			p.Attributes[fields.Values[v]] = value
+		}
+		if parsed == 0 {
+			skip("no value")
+			continue
+		}
+		p.Value = p.Attributes[fields.Values[0]]
+
+		if len(covIdx) > 0 {
+			p.Covariates = make(map[string]float64, len(covIdx))
+		}
+		ok := true
+		for c, idx := range covIdx {
+			cov, isNumber := tbl.number(idx)
+			if !isNumber {
+				skip(fmt.Sprintf("missing covariate %s", fields.Covariates[c]))
+				ok = false
+				break
+			}
+			p.Covariates[fields.Covariates[c]] = cov
+		}
+		if !ok {
+			continue
+		}
+
+		if gIdx != -1 {
+			p.Group = tbl.text(gIdx)
+		}
+		if eIdx != -1 {
+			ev, ok := tbl.number(eIdx)
+			if !ok || ev < 0 {
+				skip("invalid error variance")
+				continue
+			}
+			p.ErrorVariance = ev
+		}
+		if tIdx != -1 || dIdx != -1 {
+			t, err := recordTime(tbl, dIdx, tIdx, times)
+			if err != nil {
+				skip(err.Error())
+				continue
+			}
+			p.Time, p.HasTime = t, true
+		}
+		points.Points = append(points.Points, p)
+	}
+	report.Points = len(points.Points)
+	return points, report, nil
+}
+
+// recordTime returns the time of the current record. A single DBF date
+// field is taken as it is, text is parsed by times. A DBF date and the time
+// of day are parsed separately and combined, a text date is joined to the
+// time of day by a space before parsing.
+func recordTime(tbl *table, dIdx, tIdx int, times timeaxis.Parser) (time.Time, error) {
+	if dIdx == -1 {
+		if t, isDate, err := tbl.date(tIdx, times.Location); isDate {
+			return t, err
+		}
+		return times.Parse(tbl.text(tIdx))
+	}
+	var clock string
+	if tIdx != -1 {
+		if clock = tbl.text(tIdx); clock == "" {
+			return time.Time{}, fmt.Errorf("missing time of day")
+		}
+	}
+	d, isDate, err := tbl.date(dIdx, times.Location)
+	if !isDate {
+		if clock != "" {
+			return times.Parse(tbl.text(dIdx) + " " + clock)
+		}
+		return times.Parse(tbl.text(dIdx))
+	}
+	if err != nil || clock == "" {
+		return d, err
+	}
+	clocks := timeaxis.NewParser(append(slices.Clone(times.Layouts), clockLayouts...)...)
+	clocks.Location = times.Location
+	c, err := clocks.Parse(clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(d.Year(), d.Month(), d.Day(), c.Hour(), c.Minute(), c.Second(), c.Nanosecond(), c.Location()), nil
+}
+
+// clockLayouts parse the time of day next to a DBF date, if the time layouts
+// of the Fields do not
+var clockLayouts = []string{"15:04:05.999999999", "15:04"}
+
+// ReadPoints opens the .shp file at path and the .dbf file next to it, and
+// reads them with ReadPointsFromReader. The extension of path may be left
+// out.
+func ReadPoints(path string, fields Fields) (types.Points, csv.Report, error) {
+	base := path
+	if ext := filepath.Ext(path); strings.EqualFold(ext, ".shp") {
+		base = strings.TrimSuffix(path, ext)
+	}
+	shp, err := open(base, ".shp")
+	if err != nil {
+		return types.Points{}, csv.Report{}, err
+	}
+	defer shp.Close()
+	dbf, err := open(base, ".dbf")
+	if err != nil {
+		return types.Points{}, csv.Report{}, err
+	}
+	defer dbf.Close()
+	return ReadPointsFromReader(shp, dbf, fields)
+}
+
+// open opens base with the extension ext, in lower or upper case
+func open(base, ext string) (*os.File, error) {
+	f, err := os.Open(base + ext)
+	if os.IsNotExist(err) {
+		if upper, errUpper := os.Open(base + strings.ToUpper(ext)); errUpper == nil {
+			return upper, nil
+		}
+	}
+	return f, err
+}
@@ -0,0 +1,163 @@
+package shapefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mmaelicke/go-geostat/io/csv"
+)
+
+// pointZShp writes a PointZ .shp file; nil positions are null shapes
+func pointZShp(positions [][]float64) []byte {
+	var records bytes.Buffer
+	for i, pos := range positions {
+		content := binary.LittleEndian.AppendUint32(nil, nullShape)
+		if pos != nil {
+			content = binary.LittleEndian.AppendUint32(nil, pointZ)
+			for _, v := range append(pos, 0) {
+				content = binary.LittleEndian.AppendUint64(content, math.Float64bits(v))
+			}
+		}
+		records.Write(binary.BigEndian.AppendUint32(nil, uint32(i+1)))
+		records.Write(binary.BigEndian.AppendUint32(nil, uint32(len(content)/2)))
+		records.Write(content)
+	}
+	head := make([]byte, 100)
+	binary.BigEndian.PutUint32(head, 9994)
+	binary.BigEndian.PutUint32(head[24:], uint32((100+records.Len())/2))
+	binary.LittleEndian.PutUint32(head[28:], 1000)
+	binary.LittleEndian.PutUint32(head[32:], pointZ)
+	return append(head, records.Bytes()...)
+}
+
+// dbf writes a table of 10 character wide fields; rows starting with * are
+// deleted
+func dbf(kinds string, names []string, rows [][]string) []byte {
+	const width = 10
+	head := make([]byte, 32)
+	head[0] = 3
+	binary.LittleEndian.PutUint32(head[4:], uint32(len(rows)))
+	binary.LittleEndian.PutUint16(head[8:], uint16(32+32*len(names)+1))
+	binary.LittleEndian.PutUint16(head[10:], uint16(1+width*len(names)))
+	for i, name := range names {
+		d := make([]byte, 32)
+		copy(d, name)
+		d[11] = kinds[i]
+		d[16] = width
+		head = append(head, d...)
+	}
+	head = append(head, 0x0D)
+	for _, row := range rows {
+		flag := " "
+		if len(row) > 0 && row[0] == "*" {
+			flag, row = "*", row[1:]
+		}
+		head = append(head, flag...)
+		for _, v := range row {
+			head = append(head, fmt.Sprintf("%*s", width, v)...)
+		}
+	}
+	return append(head, 0x1A)
+}
+
+func TestReadPointsFromReader(t *testing.T) {
+	shp := pointZShp([][]float64{{1, 2, 3}, nil, {4, 5, 6}, {7, 8, 9}, {10, 11, 12}})
+	table := dbf("NNCD", []string{"ZINC", "ELEV", "WELL", "SAMPLED"}, [][]string{
+		{"100.5", "3", "a", "20240301"},
+		{"200", "4", "b", "20240302"},
+		{"", "5", "c", "20240303"},
+		{"*", "400", "6", "d", "20240304"},
+		{"500", "7", "e", "20240305"},
+	})
+	points, report, err := ReadPointsFromReader(bytes.NewReader(shp), bytes.NewReader(table), Fields{
+		Values:     []string{"zinc"},
+		Covariates: []string{"elev"},
+		Group:      "well",
+		Time:       "sampled",
+	})
+	if err != nil {
+		t.Fatalf("ReadPointsFromReader() error = %v", err)
+	}
+
+	// the null shape, the missing value and the deleted record are skipped
+	if !points.Is3D || len(points.Points) != 2 {
+		t.Fatalf("expected 2 3D points, got %+v", points)
+	}
+	want := []csv.SkippedRow{{Line: 2, Reason: "null shape"}, {Line: 3, Reason: "no value"}, {Line: 4, Reason: "deleted record"}}
+	if report.Rows != 5 || report.Points != 2 || !slices.Equal(report.Skipped, want) {
+		t.Errorf("report = %+v, want skipped %v", report, want)
+	}
+	p := points.Points[0]
+	if p.X != 1 || p.Y != 2 || p.Z != 3 || p.Value != 100.5 || p.Group != "a" {
+		t.Errorf("unexpected point %+v", p)
+	}
+	if elev, _ := p.Covariate("elev"); elev != 3 {
+		t.Errorf("covariate elev = %v, want 3", elev)
+	}
+	if !p.HasTime || p.Time.Day() != 1 {
+		t.Errorf("time = %v, want 2024-03-01", p.Time)
+	}
+	if v := points.Points[1].Value; v != 500 {
+		t.Errorf("value of the last point = %v, want 500", v)
+	}
+
+	if _, _, err := ReadPointsFromReader(bytes.NewReader(shp), bytes.NewReader(table), Fields{}); err == nil {
+		t.Error("expected an error for the missing value attribute")
+	}
+}
+
+func TestReadPointsDateAndTime(t *testing.T) {
+	shp := pointZShp([][]float64{{1, 2, 3}, {4, 5, 6}})
+	table := dbf("NDC", []string{"ZINC", "DAY", "AT"}, [][]string{
+		{"1", "20240301", "14:30"},
+		{"2", "20240302", ""},
+	})
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	points, report, err := ReadPointsFromReader(bytes.NewReader(shp), bytes.NewReader(table), Fields{
+		Values:   []string{"zinc"},
+		Time:     "at",
+		Date:     "day",
+		Location: zone,
+	})
+	if err != nil {
+		t.Fatalf("ReadPointsFromReader() error = %v", err)
+	}
+	// the point without a time of day is skipped
+	if len(points.Points) != 1 || len(report.Skipped) != 1 || report.Skipped[0].Reason != "missing time of day" {
+		t.Fatalf("expected 1 point, got %+v and report %+v", points, report)
+	}
+	want := time.Date(2024, 3, 1, 14, 30, 0, 0, zone)
+	if p := points.Points[0]; !p.HasTime || !p.Time.Equal(want) {
+		t.Errorf("time = %v, want %v", p.Time, want)
+	}
+
+	// the time of day is parsed on its own, by a custom layout or as a clock
+	for _, layouts := range [][]string{{"3.04PM"}, {"02.01.2006 15:04"}} {
+		clock := "14:30"
+		if layouts[0] == "3.04PM" {
+			clock = "2.30PM"
+		}
+		table := dbf("NDC", []string{"ZINC", "DAY", "AT"}, [][]string{{"1", "20240301", clock}, {"2", "20240302", "noon"}})
+		points, report, err := ReadPointsFromReader(bytes.NewReader(shp), bytes.NewReader(table), Fields{
+			Values:      []string{"zinc"},
+			Time:        "at",
+			Date:        "day",
+			TimeLayouts: layouts,
+			Location:    zone,
+		})
+		if err != nil {
+			t.Fatalf("%v: ReadPointsFromReader() error = %v", layouts, err)
+		}
+		if len(points.Points) != 1 || !points.Points[0].Time.Equal(want) {
+			t.Errorf("%v: got %+v, want a point at %v", layouts, points.Points, want)
+		}
+		if len(report.Skipped) != 1 || report.Skipped[0].Line != 2 {
+			t.Errorf("%v: report = %+v, want record 2 skipped", layouts, report)
+		}
+	}
+}
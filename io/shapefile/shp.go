@@ -0,0 +1,96 @@
+package shapefile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// shape types of the ESRI shapefile specification
+const (
+	nullShape = 0
+	point     = 1
+	pointM    = 21
+	pointZ    = 11
+)
+
+// position is a decoded point shape. Null shapes have null set.
+type position struct {
+	x, y, z float64
+	null    bool
+}
+
+// shapes reads the records of a point, pointM or pointZ .shp file
+type shapes struct {
+	r       *bufio.Reader
+	kind    int
+	current int
+}
+
+// newShapes reads the header of a .shp file
+func newShapes(r io.Reader) (*shapes, error) {
+	br := bufio.NewReader(r)
+	head := make([]byte, 100)
+	if _, err := io.ReadFull(br, head); err != nil {
+		return nil, fmt.Errorf("failed to read SHP header: %w", err)
+	}
+	if code := binary.BigEndian.Uint32(head); code != 9994 {
+		return nil, fmt.Errorf("not a shapefile: file code %d, want 9994", code)
+	}
+	s := &shapes{r: br, kind: int(binary.LittleEndian.Uint32(head[32:]))}
+	switch s.kind {
+	case point, pointM, pointZ:
+	default:
+		return nil, fmt.Errorf("unsupported shape type %d, need Point (1), PointZ (11) or PointM (21)", s.kind)
+	}
+	return s, nil
+}
+
+// is3D reports whether the shapes carry a z coordinate
+func (s *shapes) is3D() bool {
+	return s.kind == pointZ
+}
+
+// next reads the next record, or returns io.EOF after the last one
+func (s *shapes) next() (position, error) {
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(s.r, head); err == io.EOF {
+		return position{}, io.EOF
+	} else if err != nil {
+		return position{}, fmt.Errorf("failed to read SHP record %d: %w", s.current+1, err)
+	}
+	s.current++
+	// content length in 16-bit words
+	content := make([]byte, 2*int(binary.BigEndian.Uint32(head[4:])))
+	if _, err := io.ReadFull(s.r, content); err != nil {
+		return position{}, fmt.Errorf("failed to read SHP record %d: %w", s.current, err)
+	}
+	if len(content) < 4 {
+		return position{}, fmt.Errorf("SHP record %d is empty", s.current)
+	}
+
+	kind := int(binary.LittleEndian.Uint32(content))
+	if kind == nullShape {
+		return position{null: true}, nil
+	}
+	if kind != s.kind {
+		return position{}, fmt.Errorf("SHP record %d has shape type %d, want %d", s.current, kind, s.kind)
+	}
+	need := 20
+	if kind == pointZ {
+		need = 28
+	}
+	if len(content) < need {
+		return position{}, fmt.Errorf("SHP record %d is truncated", s.current)
+	}
+	float := func(at int) float64 {
+		return math.Float64frombits(binary.LittleEndian.Uint64(content[at:]))
+	}
+	p := position{x: float(4), y: float(12)}
+	if kind == pointZ {
+		p.z = float(20)
+	}
+	return p, nil
+}
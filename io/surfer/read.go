@@ -0,0 +1,206 @@
+package surfer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+)
+
+// Read reads a Surfer 6 ASCII, Surfer 6 binary or Surfer 7 grid, detected by
+// its first bytes. It returns the grid spec and the values in the order of
+// grid.Spec.At, with blanked nodes as NaN.
+func Read(r io.Reader) (grid.Spec, []float64, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return grid.Spec{}, nil, fmt.Errorf("failed to read Surfer grid: %w", err)
+	}
+
+	var h header
+	var values []float64
+	switch string(magic) {
+	case "DSAA":
+		h, values, err = readASCII(br)
+		h.nodata = Blank
+	case "DSBB":
+		h, values, err = readSurfer6(br)
+		h.nodata = Blank
+	case "DSRB":
+		h, values, err = readSurfer7(br)
+	default:
+		return grid.Spec{}, nil, fmt.Errorf("not a Surfer grid: starts with %q", magic)
+	}
+	if err != nil {
+		return grid.Spec{}, nil, err
+	}
+	spec, err := h.spec()
+	if err != nil {
+		return grid.Spec{}, nil, err
+	}
+	for i, v := range values {
+		values[i] = h.unblank(v)
+	}
+	return spec, specOrder(spec, values), nil
+}
+
+// ReadFile opens path and reads it with Read.
+func ReadFile(path string) (grid.Spec, []float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return grid.Spec{}, nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// readASCII reads a DSAA grid, whose values may be wrapped over any number
+// of lines
+func readASCII(r *bufio.Reader) (header, []float64, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	scanner.Split(bufio.ScanWords)
+	scanner.Scan() // DSAA
+
+	// nx ny xlo xhi ylo yhi zlo zhi
+	head := make([]float64, 8)
+	for i := range head {
+		if !scanner.Scan() {
+			return header{}, nil, fmt.Errorf("incomplete DSAA header")
+		}
+		v, err := strconv.ParseFloat(scanner.Text(), 64)
+		if err != nil {
+			return header{}, nil, fmt.Errorf("invalid DSAA header: %w", err)
+		}
+		head[i] = v
+	}
+	h := header{
+		nx: int(head[0]), ny: int(head[1]),
+		xlo: head[2], xhi: head[3], ylo: head[4], yhi: head[5], zlo: head[6], zhi: head[7],
+	}
+	if h.nx < 1 || h.ny < 1 {
+		return header{}, nil, fmt.Errorf("invalid grid size %d x %d", h.nx, h.ny)
+	}
+
+	values := make([]float64, 0, h.nx*h.ny)
+	for scanner.Scan() && len(values) < h.nx*h.ny {
+		v, err := strconv.ParseFloat(scanner.Text(), 64)
+		if err != nil {
+			return header{}, nil, fmt.Errorf("failed to parse value %d: %w", len(values), err)
+		}
+		values = append(values, v)
+	}
+	if err := scanner.Err(); err != nil {
+		return header{}, nil, fmt.Errorf("failed to read grid: %w", err)
+	}
+	if len(values) != h.nx*h.ny {
+		return header{}, nil, fmt.Errorf("expected %d values, got %d", h.nx*h.ny, len(values))
+	}
+	return h, values, nil
+}
+
+// readSurfer6 reads a DSBB grid
+func readSurfer6(r io.Reader) (header, []float64, error) {
+	head := make([]byte, 4+2+2+6*8)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return header{}, nil, fmt.Errorf("failed to read DSBB header: %w", err)
+	}
+	f := func(i int) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(head[8+8*i:])) }
+	h := header{
+		nx:  int(int16(binary.LittleEndian.Uint16(head[4:]))),
+		ny:  int(int16(binary.LittleEndian.Uint16(head[6:]))),
+		xlo: f(0), xhi: f(1), ylo: f(2), yhi: f(3), zlo: f(4), zhi: f(5),
+	}
+	if h.nx < 1 || h.ny < 1 {
+		return header{}, nil, fmt.Errorf("invalid grid size %d x %d", h.nx, h.ny)
+	}
+	data := make([]byte, 4*h.nx*h.ny)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return header{}, nil, fmt.Errorf("failed to read DSBB values: %w", err)
+	}
+	values := make([]float64, h.nx*h.ny)
+	for i := range values {
+		values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:])))
+	}
+	return h, values, nil
+}
+
+// readSurfer7 reads the grid and data sections of a DSRB grid and skips any
+// other section, e.g. fault traces
+func readSurfer7(r io.Reader) (header, []float64, error) {
+	var h header
+	var values []float64
+	version := 0
+	tag := make([]byte, 8)
+	for values == nil {
+		if _, err := io.ReadFull(r, tag); err != nil {
+			return header{}, nil, fmt.Errorf("failed to read DSRB section: %w", err)
+		}
+		id := binary.LittleEndian.Uint32(tag)
+		size := int64(binary.LittleEndian.Uint32(tag[4:]))
+		switch id {
+		case tagHeader:
+			if size != 4 {
+				return header{}, nil, fmt.Errorf("DSRB header section of %d bytes, want 4", size)
+			}
+			b := make([]byte, 4)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return header{}, nil, fmt.Errorf("failed to read DSRB header: %w", err)
+			}
+			if version = int(binary.LittleEndian.Uint32(b)); version != 1 && version != 2 {
+				return header{}, nil, fmt.Errorf("unsupported DSRB version %d", version)
+			}
+		case tagGrid:
+			if size < 72 {
+				return header{}, nil, fmt.Errorf("DSRB grid section of %d bytes, want 72", size)
+			}
+			section := make([]byte, size)
+			if _, err := io.ReadFull(r, section); err != nil {
+				return header{}, nil, fmt.Errorf("failed to read DSRB grid section: %w", err)
+			}
+			f := func(i int) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(section[8+8*i:])) }
+			h = header{
+				ny:  int(int32(binary.LittleEndian.Uint32(section))),
+				nx:  int(int32(binary.LittleEndian.Uint32(section[4:]))),
+				xlo: f(0), ylo: f(1), dx: f(2), dy: f(3), zlo: f(4), zhi: f(5),
+				hasSpacing: true,
+				nodata:     f(7),
+				version:    version,
+			}
+			if rotation := f(6); rotation != 0 {
+				return header{}, nil, fmt.Errorf("rotated Surfer grids are not supported (rotation %g)", rotation)
+			}
+			if h.nx < 1 || h.ny < 1 {
+				return header{}, nil, fmt.Errorf("invalid grid size %d x %d", h.nx, h.ny)
+			}
+			h.xhi = h.xlo + float64(h.nx-1)*h.dx
+			h.yhi = h.ylo + float64(h.ny-1)*h.dy
+		case tagData:
+			if !h.hasSpacing {
+				return header{}, nil, fmt.Errorf("DSRB data section before the grid section")
+			}
+			n := h.nx * h.ny
+			if size != int64(8*n) {
+				return header{}, nil, fmt.Errorf("DSRB data section of %d bytes, want %d", size, 8*n)
+			}
+			data := make([]byte, size)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return header{}, nil, fmt.Errorf("failed to read DSRB values: %w", err)
+			}
+			values = make([]float64, n)
+			for i := range values {
+				values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[8*i:]))
+			}
+		default:
+			if _, err := io.CopyN(io.Discard, r, size); err != nil {
+				return header{}, nil, fmt.Errorf("failed to skip DSRB section: %w", err)
+			}
+		}
+	}
+	return h, values, nil
+}
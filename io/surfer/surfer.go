@@ -0,0 +1,159 @@
+// Package surfer reads and writes Golden Software Surfer grids: Surfer 6
+// ASCII (DSAA), Surfer 6 binary (DSBB) and Surfer 7 binary (DSRB) GRD files.
+//
+// Surfer grids are node registered and stored row by row from south to
+// north. Their nodes are the cell centres of grid.Spec, so a grid written and
+// read back has cell centre registration.
+package surfer
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+)
+
+// Format is one of the Surfer GRD formats.
+type Format int
+
+const (
+	// Surfer7 is the binary format of Surfer 7 and later with float64 values.
+	Surfer7 Format = iota
+	// Surfer6 is the binary format of Surfer 6 with float32 values.
+	Surfer6
+	// ASCII is the text format of Surfer 6.
+	ASCII
+)
+
+// ParseFormat returns the format called name: grd (Surfer 7), grd6 (Surfer 6
+// binary) or grdtxt (Surfer 6 ASCII).
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "grd", "surfer7":
+		return Surfer7, nil
+	case "grd6", "surfer6":
+		return Surfer6, nil
+	case "grdtxt", "dsaa":
+		return ASCII, nil
+	default:
+		return Surfer7, fmt.Errorf("unsupported Surfer format: %s", name)
+	}
+}
+
+// Blank is the value Surfer uses for blanked nodes. Values at or above it
+// are read as NaN. Surfer 7 grids may declare another blank value, which is
+// matched exactly.
+const Blank = 1.70141e38
+
+// header is the geometry and value range of a Surfer grid
+type header struct {
+	nx, ny     int
+	xlo, xhi   float64
+	ylo, yhi   float64
+	zlo, zhi   float64
+	dx, dy     float64
+	hasSpacing bool
+	// nodata is the blank value, Blank unless a Surfer 7 grid sets another
+	nodata float64
+	// version of a Surfer 7 grid, whose version 2 matches nodata exactly
+	version int
+}
+
+// headerOf returns the header of the 2D grid spec holding values in the
+// order of grid.Spec.At
+func headerOf(spec grid.Spec, values []float64) (header, error) {
+	if err := spec.Validate(); err != nil {
+		return header{}, err
+	}
+	if spec.Is3D() {
+		return header{}, fmt.Errorf("3D grids are not supported by Surfer grids")
+	}
+	if len(values) != spec.Len() {
+		return header{}, fmt.Errorf("got %d values for %d nodes", len(values), spec.Len())
+	}
+	x, y, _ := spec.Center()
+	h := header{
+		nx: spec.NX, ny: spec.NY,
+		xlo: x, xhi: x + float64(spec.NX-1)*spec.DX,
+		ylo: y, yhi: y + float64(spec.NY-1)*spec.DY,
+		dx: spec.DX, dy: spec.DY,
+		zlo: math.Inf(1), zhi: math.Inf(-1),
+	}
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			h.zlo = math.Min(h.zlo, v)
+			h.zhi = math.Max(h.zhi, v)
+		}
+	}
+	if h.zlo > h.zhi {
+		h.zlo, h.zhi = 0, 0
+	}
+	return h, nil
+}
+
+// spec returns the cell centre registered grid of the header. The spacing of
+// a single row or column is taken from the other axis, or 1 for a single
+// node, unless the format stores it.
+func (h header) spec() (grid.Spec, error) {
+	if h.nx < 1 || h.ny < 1 {
+		return grid.Spec{}, fmt.Errorf("invalid grid size %d x %d", h.nx, h.ny)
+	}
+	dx, dy := h.dx, h.dy
+	if !h.hasSpacing {
+		dx, dy = 0, 0
+		if h.nx > 1 {
+			dx = (h.xhi - h.xlo) / float64(h.nx-1)
+		}
+		if h.ny > 1 {
+			dy = (h.yhi - h.ylo) / float64(h.ny-1)
+		}
+		if dx == 0 {
+			dx = dy
+		}
+		if dy == 0 {
+			dy = dx
+		}
+		if dx == 0 {
+			dx, dy = 1, 1
+		}
+	}
+	spec := grid.Spec{X0: h.xlo, Y0: h.ylo, DX: dx, DY: dy, NX: h.nx, NY: h.ny}
+	return spec, spec.Validate()
+}
+
+// surferOrder returns the values, given in the order of grid.Spec.At, in
+// Surfer order: rows from south to north
+func surferOrder(spec grid.Spec, values []float64) []float64 {
+	out := make([]float64, 0, len(values))
+	for row := spec.NY - 1; row >= 0; row-- {
+		out = append(out, values[spec.Offset(0, row, 0):spec.Offset(0, row, 0)+spec.NX]...)
+	}
+	return out
+}
+
+// specOrder is the inverse of surferOrder
+func specOrder(spec grid.Spec, values []float64) []float64 {
+	out := make([]float64, len(values))
+	for row := 0; row < spec.NY; row++ {
+		src := (spec.NY - 1 - row) * spec.NX
+		copy(out[spec.Offset(0, row, 0):], values[src:src+spec.NX])
+	}
+	return out
+}
+
+// blank returns Blank for NaN and infinite values
+func blank(v float64) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return Blank
+	}
+	return v
+}
+
+// unblank returns NaN for blanked values: values equal to the blank value,
+// and values at or above the classic Blank unless the grid is of version 2
+func (h header) unblank(v float64) float64 {
+	if math.IsNaN(v) || v == h.nodata || (h.version != 2 && h.nodata >= Blank && v >= h.nodata) {
+		return math.NaN()
+	}
+	return v
+}
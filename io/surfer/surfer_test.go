@@ -0,0 +1,117 @@
+package surfer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+func TestRoundTrip(t *testing.T) {
+	spec := grid.Spec{X0: 10, Y0: 20, DX: 2, DY: 1, NX: 3, NY: 2}
+	values := []float64{0, 1, 2, 3, math.NaN(), 5.5}
+	for _, format := range []Format{Surfer7, Surfer6, ASCII} {
+		var buf bytes.Buffer
+		if err := Write(&buf, spec, values, format); err != nil {
+			t.Fatalf("Write(%d) error = %v", format, err)
+		}
+		got, read, err := Read(&buf)
+		if err != nil {
+			t.Fatalf("Read(%d) error = %v", format, err)
+		}
+		if err := spec.Aligned(got); err != nil {
+			t.Errorf("format %d: %v", format, err)
+		}
+		for i, v := range values {
+			if read[i] != v && !(math.IsNaN(read[i]) && math.IsNaN(v)) {
+				t.Errorf("format %d: value %d = %v, want %v", format, i, read[i], v)
+			}
+		}
+	}
+}
+
+func TestWriteASCII(t *testing.T) {
+	spec := grid.Spec{X0: 0, Y0: 0, DX: 1, DY: 1, NX: 2, NY: 2}
+	sink, err := NewSink(&bytes.Buffer{}, spec, ASCII, func(e types.Estimation) float64 { return e.Field })
+	if err != nil {
+		t.Fatalf("NewSink() error = %v", err)
+	}
+	if err := sink.Flush(); err == nil {
+		t.Error("Flush() of an incomplete grid should fail")
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, spec, []float64{1, 2, 3, 4}, ASCII); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	// the south row comes first
+	want := "DSAA\n2 2\n0 1\n0 1\n1 4\n3 4\n\n1 2\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Write() = %q, want %q", got, want)
+	}
+	if _, _, err := Read(strings.NewReader("DSAA\n2 2\n0 1\n0 1\n1 4\n3 4\n")); err == nil {
+		t.Error("Read() of a truncated grid should fail")
+	}
+}
+
+// dsrb returns a 2 x 2 Surfer 7 grid of the given version and blank value,
+// with values in Surfer order
+func dsrb(version uint32, blankValue float64, values []float64) []byte {
+	u32 := binary.LittleEndian.AppendUint32
+	f64 := func(b []byte, v float64) []byte { return binary.LittleEndian.AppendUint64(b, math.Float64bits(v)) }
+	b := u32(u32(u32(nil, tagHeader), 4), version)
+	b = u32(u32(u32(u32(b, tagGrid), 72), 2), 2)
+	for _, v := range []float64{0, 0, 1, 1, -10000, 0, 0, blankValue} {
+		b = f64(b, v)
+	}
+	b = u32(u32(b, tagData), uint32(8*len(values)))
+	for _, v := range values {
+		b = f64(b, v)
+	}
+	return b
+}
+
+func TestReadSurfer7BlankValue(t *testing.T) {
+	// the south row comes first
+	data := []float64{-9999, -10000, -5, -9998.5}
+	want := []float64{-5, -9998.5, math.NaN(), -10000}
+	for _, version := range []uint32{1, 2} {
+		spec, values, err := Read(bytes.NewReader(dsrb(version, -9999, data)))
+		if err != nil {
+			t.Fatalf("version %d: Read() error = %v", version, err)
+		}
+		// written back with the classic blank value
+		var buf bytes.Buffer
+		if err := Write(&buf, spec, values, Surfer7); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		_, again, err := Read(&buf)
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		for i, v := range want {
+			for _, got := range []float64{values[i], again[i]} {
+				if got != v && !(math.IsNaN(got) && math.IsNaN(v)) {
+					t.Errorf("version %d: value %d = %v, want %v", version, i, got, v)
+				}
+			}
+		}
+	}
+
+	// version 2 only blanks the exact blank value
+	_, values, err := Read(bytes.NewReader(dsrb(2, Blank, []float64{2 * Blank, Blank, 1, 2})))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if values[2] != 2*Blank || !math.IsNaN(values[3]) {
+		t.Errorf("version 2 values = %v, want %v and NaN in the south row", values, 2*Blank)
+	}
+
+	if _, _, err := Read(bytes.NewReader(dsrb(3, Blank, data))); err == nil {
+		t.Error("expected an error for an unsupported version")
+	}
+}
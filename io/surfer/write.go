@@ -0,0 +1,174 @@
+package surfer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// tags of the Surfer 7 sections
+const (
+	tagHeader = 0x42525344 // DSRB
+	tagGrid   = 0x44495247 // GRID
+	tagData   = 0x41544144 // DATA
+)
+
+// Write writes values, given in the order of grid.Spec.At, as Surfer grid on
+// the nodes of the 2D grid spec. NaN values are blanked.
+func Write(w io.Writer, spec grid.Spec, values []float64, format Format) error {
+	h, err := headerOf(spec, values)
+	if err != nil {
+		return err
+	}
+	ordered := surferOrder(spec, values)
+	bw := bufio.NewWriter(w)
+	switch format {
+	case ASCII:
+		err = writeASCII(bw, h, ordered)
+	case Surfer6:
+		err = writeSurfer6(bw, h, ordered)
+	case Surfer7:
+		err = writeSurfer7(bw, h, ordered)
+	default:
+		err = fmt.Errorf("unsupported Surfer format %d", format)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// WriteFile creates path and writes the grid with Write.
+func WriteFile(path string, spec grid.Spec, values []float64, format Format) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if err := Write(f, spec, values, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeASCII writes a DSAA grid with ten values per line and an empty line
+// after each row, like Surfer does
+func writeASCII(w *bufio.Writer, h header, values []float64) error {
+	format := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	fmt.Fprintln(w, "DSAA")
+	fmt.Fprintf(w, "%d %d\n", h.nx, h.ny)
+	fmt.Fprintf(w, "%s %s\n", format(h.xlo), format(h.xhi))
+	fmt.Fprintf(w, "%s %s\n", format(h.ylo), format(h.yhi))
+	fmt.Fprintf(w, "%s %s\n", format(h.zlo), format(h.zhi))
+	for row := 0; row < h.ny; row++ {
+		for col := 0; col < h.nx; col++ {
+			w.WriteString(format(blank(values[row*h.nx+col])))
+			if col%10 == 9 || col == h.nx-1 {
+				w.WriteByte('\n')
+			} else {
+				w.WriteByte(' ')
+			}
+		}
+		if _, err := w.WriteString("\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSurfer6 writes a DSBB grid, which counts rows and columns in 16 bits
+func writeSurfer6(w *bufio.Writer, h header, values []float64) error {
+	if h.nx > math.MaxInt16 || h.ny > math.MaxInt16 {
+		return fmt.Errorf("Surfer 6 grids hold at most %d x %d nodes, got %d x %d", math.MaxInt16, math.MaxInt16, h.nx, h.ny)
+	}
+	b := []byte("DSBB")
+	b = binary.LittleEndian.AppendUint16(b, uint16(h.nx))
+	b = binary.LittleEndian.AppendUint16(b, uint16(h.ny))
+	for _, v := range []float64{h.xlo, h.xhi, h.ylo, h.yhi, h.zlo, h.zhi} {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	buf := make([]byte, 4)
+	for _, v := range values {
+		binary.LittleEndian.PutUint32(buf, math.Float32bits(float32(blank(v))))
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSurfer7 writes a DSRB grid of a header, a grid and a data section
+func writeSurfer7(w *bufio.Writer, h header, values []float64) error {
+	u32 := binary.LittleEndian.AppendUint32
+	b := u32(nil, tagHeader)
+	b = u32(b, 4)
+	b = u32(b, 1)
+	b = u32(b, tagGrid)
+	b = u32(b, 72)
+	b = u32(b, uint32(h.ny))
+	b = u32(b, uint32(h.nx))
+	for _, v := range []float64{h.xlo, h.ylo, h.dx, h.dy, h.zlo, h.zhi, 0, Blank} {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+	}
+	b = u32(b, tagData)
+	b = u32(b, uint32(8*len(values)))
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	buf := make([]byte, 8)
+	for _, v := range values {
+		binary.LittleEndian.PutUint64(buf, math.Float64bits(blank(v)))
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sink collects one value per estimation and writes the Surfer grid on
+// Flush, as the header holds the value range. Estimations have to arrive in
+// the order of grid.Spec.At. It implements types.EstimationSink.
+type Sink struct {
+	w      io.Writer
+	spec   grid.Spec
+	format Format
+	value  func(types.Estimation) float64
+	values []float64
+}
+
+// NewSink returns a sink writing the value selected by value, e.g. asc.Field
+// or asc.Variance, on the 2D grid spec to w.
+func NewSink(w io.Writer, spec grid.Spec, format Format, value func(types.Estimation) float64) (*Sink, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if spec.Is3D() {
+		return nil, fmt.Errorf("3D grids are not supported by Surfer grids")
+	}
+	return &Sink{w: w, spec: spec, format: format, value: value, values: make([]float64, 0, spec.Len())}, nil
+}
+
+func (s *Sink) Write(p types.Point, e types.Estimation) error {
+	if len(s.values) >= s.spec.Len() {
+		return fmt.Errorf("more values than the grid holds (%d)", s.spec.Len())
+	}
+	s.values = append(s.values, s.value(e))
+	return nil
+}
+
+func (s *Sink) Flush() error {
+	if len(s.values) != s.spec.Len() {
+		return fmt.Errorf("grid incomplete: got %d of %d values", len(s.values), s.spec.Len())
+	}
+	return Write(s.w, s.spec, s.values, s.format)
+}
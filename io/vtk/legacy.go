@@ -0,0 +1,49 @@
+package vtk
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+)
+
+// WriteStructuredPoints writes the arrays on the grid spec as legacy VTK
+// structured points. Each array holds one value per node in the order of
+// grid.Spec.At.
+func WriteStructuredPoints(w io.Writer, spec grid.Spec, arrays ...Array) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+	if err := checkArrays(spec.Len(), arrays); err != nil {
+		return err
+	}
+	dims, origin, spacing := geometry(spec)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# vtk DataFile Version 3.0")
+	fmt.Fprintln(bw, "go-geostat")
+	fmt.Fprintln(bw, "BINARY")
+	fmt.Fprintln(bw, "DATASET STRUCTURED_POINTS")
+	fmt.Fprintf(bw, "DIMENSIONS %d %d %d\n", dims[0], dims[1], dims[2])
+	fmt.Fprintf(bw, "ORIGIN %s %s %s\n", formatFloat(origin[0]), formatFloat(origin[1]), formatFloat(origin[2]))
+	fmt.Fprintf(bw, "SPACING %s %s %s\n", formatFloat(spacing[0]), formatFloat(spacing[1]), formatFloat(spacing[2]))
+	fmt.Fprintf(bw, "POINT_DATA %d\n", spec.Len())
+
+	buf := make([]byte, 4)
+	for _, a := range arrays {
+		fmt.Fprintf(bw, "SCALARS %s float 1\n", a.Name)
+		fmt.Fprintln(bw, "LOOKUP_TABLE default")
+		// legacy binary data is big-endian
+		for _, v := range vtkOrder(spec, a.Values) {
+			binary.BigEndian.PutUint32(buf, math.Float32bits(v))
+			if _, err := bw.Write(buf); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
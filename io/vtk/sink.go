@@ -0,0 +1,226 @@
+package vtk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// layout places float32 arrays on a grid at fixed offsets of a file, so that
+// their rows can be written as soon as they are complete. Legacy files hold
+// big-endian arrays after their SCALARS lines, image data files hold them as
+// raw appended data.
+type layout struct {
+	w      io.WriterAt
+	spec   grid.Spec
+	order  binary.AppendByteOrder
+	begins []int64
+}
+
+// newLayout writes everything but the values of the arrays names on spec
+// in format to w.
+func newLayout(w io.WriterAt, spec grid.Spec, format Format, names ...string) (*layout, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	size := 4 * int64(spec.Len())
+	if size > math.MaxUint32 {
+		return nil, fmt.Errorf("arrays of %d bytes exceed the VTK size limit", size)
+	}
+	l := &layout{w: w, spec: spec}
+	var text strings.Builder
+	// segments holds the text preceding each array and the trailing text
+	var segments []string
+	dims, origin, spacing := geometry(spec)
+
+	if format == ImageData {
+		l.order = binary.LittleEndian
+		extent := fmt.Sprintf("0 %d 0 %d 0 %d", dims[0]-1, dims[1]-1, dims[2]-1)
+		fmt.Fprintln(&text, `<?xml version="1.0"?>`)
+		fmt.Fprintln(&text, `<VTKFile type="ImageData" version="1.0" byte_order="LittleEndian" header_type="UInt32">`)
+		fmt.Fprintf(&text, "<ImageData WholeExtent=\"%s\" Origin=\"%s %s %s\" Spacing=\"%s %s %s\">\n", extent,
+			formatFloat(origin[0]), formatFloat(origin[1]), formatFloat(origin[2]),
+			formatFloat(spacing[0]), formatFloat(spacing[1]), formatFloat(spacing[2]))
+		fmt.Fprintf(&text, "<Piece Extent=\"%s\">\n", extent)
+		if len(names) > 0 {
+			fmt.Fprintf(&text, "<PointData Scalars=\"%s\">\n", escape(names[0]))
+		} else {
+			fmt.Fprintln(&text, "<PointData>")
+		}
+		// every appended array is preceded by its byte count
+		for i, name := range names {
+			fmt.Fprintf(&text, "<DataArray type=\"Float32\" Name=\"%s\" format=\"appended\" offset=\"%d\"/>\n", escape(name), int64(i)*(4+size))
+		}
+		fmt.Fprintln(&text, "</PointData>")
+		fmt.Fprintln(&text, "</Piece>")
+		fmt.Fprintln(&text, "</ImageData>")
+		fmt.Fprint(&text, "<AppendedData encoding=\"raw\">\n_")
+		count := string(binary.LittleEndian.AppendUint32(nil, uint32(size)))
+		segments = append(segments, text.String()+count)
+		for i := 1; i < len(names); i++ {
+			segments = append(segments, count)
+		}
+		segments = append(segments, "\n</AppendedData>\n</VTKFile>\n")
+	} else {
+		l.order = binary.BigEndian
+		fmt.Fprintln(&text, "# vtk DataFile Version 3.0")
+		fmt.Fprintln(&text, "go-geostat")
+		fmt.Fprintln(&text, "BINARY")
+		fmt.Fprintln(&text, "DATASET STRUCTURED_POINTS")
+		fmt.Fprintf(&text, "DIMENSIONS %d %d %d\n", dims[0], dims[1], dims[2])
+		fmt.Fprintf(&text, "ORIGIN %s %s %s\n", formatFloat(origin[0]), formatFloat(origin[1]), formatFloat(origin[2]))
+		fmt.Fprintf(&text, "SPACING %s %s %s\n", formatFloat(spacing[0]), formatFloat(spacing[1]), formatFloat(spacing[2]))
+		fmt.Fprintf(&text, "POINT_DATA %d\n", spec.Len())
+		prefix := text.String()
+		for _, name := range names {
+			segments = append(segments, fmt.Sprintf("%sSCALARS %s float 1\nLOOKUP_TABLE default\n", prefix, name))
+			prefix = "\n"
+		}
+		segments = append(segments, "\n")
+	}
+
+	offset := int64(0)
+	for i, s := range segments {
+		if _, err := w.WriteAt([]byte(s), offset); err != nil {
+			return nil, err
+		}
+		offset += int64(len(s))
+		if i < len(names) {
+			l.begins = append(l.begins, offset)
+			offset += size
+		}
+	}
+	return l, nil
+}
+
+// rows returns the writer of array i
+func (l *layout) rows(i int) *rows {
+	return &rows{layout: l, begin: l.begins[i], buf: make([]byte, 0, 4*l.spec.NX)}
+}
+
+// rows writes the values of an array, given in the order of grid.Spec.At, a
+// row at a time to its place in VTK order with y increasing
+type rows struct {
+	*layout
+	begin int64
+	buf   []byte
+	n     int
+}
+
+func (r *rows) write(v float64) error {
+	if r.n >= r.spec.Len() {
+		return fmt.Errorf("more values than the grid holds (%d)", r.spec.Len())
+	}
+	r.buf = r.order.AppendUint32(r.buf, math.Float32bits(float32(v)))
+	r.n++
+	if r.n%r.spec.NX != 0 {
+		return nil
+	}
+	_, row, layer := r.spec.Index(r.n - 1)
+	at := r.spec.Offset(0, r.spec.NY-1-row, layer)
+	_, err := r.w.WriteAt(r.buf, r.begin+4*int64(at))
+	r.buf = r.buf[:0]
+	return err
+}
+
+func (r *rows) flush() error {
+	if r.n != r.spec.Len() {
+		return fmt.Errorf("grid incomplete: got %d of %d values", r.n, r.spec.Len())
+	}
+	return nil
+}
+
+// Sink writes kriging estimations as the arrays field and variance, a row
+// at a time as soon as it is complete. Estimations have to arrive in the
+// order of grid.Spec.At. It implements types.EstimationSink.
+type Sink struct {
+	field, variance *rows
+}
+
+// NewSink writes the header of the grid spec in format to w, e.g. an
+// *os.File, and returns the sink writing the estimations.
+func NewSink(w io.WriterAt, spec grid.Spec, format Format) (*Sink, error) {
+	l, err := newLayout(w, spec, format, "field", "variance")
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{field: l.rows(0), variance: l.rows(1)}, nil
+}
+
+func (s *Sink) Write(p types.Point, e types.Estimation) error {
+	if err := s.field.write(e.Field); err != nil {
+		return err
+	}
+	return s.variance.write(e.Variance)
+}
+
+func (s *Sink) Flush() error {
+	return s.field.flush()
+}
+
+// Ensemble writes a stack of SGS realizations as the arrays sim_0, sim_1,
+// ... of a single file. Realizations may be written in any order, as each
+// one is written at its own offset.
+type Ensemble struct {
+	layout *layout
+	done   []bool
+}
+
+// NewEnsemble writes the header of n realizations on the grid spec in format
+// to w, e.g. an *os.File.
+func NewEnsemble(w io.WriterAt, spec grid.Spec, n int, format Format) (*Ensemble, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("at least one realization is needed")
+	}
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("sim_%d", i)
+	}
+	l, err := newLayout(w, spec, format, names...)
+	if err != nil {
+		return nil, err
+	}
+	return &Ensemble{layout: l, done: make([]bool, n)}, nil
+}
+
+// Realization returns the sink for realization sim, counted from 0. It
+// matches the sink factory of sgs.SGS.SimulateTo.
+func (e *Ensemble) Realization(sim int) (types.EstimationSink, error) {
+	if sim < 0 || sim >= len(e.done) {
+		return nil, fmt.Errorf("realization %d out of range [0, %d)", sim, len(e.done))
+	}
+	return &realization{rows: e.layout.rows(sim), done: &e.done[sim]}, nil
+}
+
+// Close fails if any realization is incomplete.
+func (e *Ensemble) Close() error {
+	for sim, done := range e.done {
+		if !done {
+			return fmt.Errorf("sim_%d incomplete", sim)
+		}
+	}
+	return nil
+}
+
+// realization writes the field of one realization
+type realization struct {
+	rows *rows
+	done *bool
+}
+
+func (r *realization) Write(p types.Point, e types.Estimation) error {
+	return r.rows.write(e.Field)
+}
+
+func (r *realization) Flush() error {
+	if err := r.rows.flush(); err != nil {
+		return err
+	}
+	*r.done = true
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package vtk writes grids and point clouds for visualisation in ParaView and
+// other VTK based tools.
+//
+// Regular grids are written as legacy structured points (.vtk) or XML image
+// data (.vti), arbitrary locations such as the conditioning data or the nodes
+// of types.Points grids as XML unstructured grids (.vtu) of vertex cells.
+// Values are stored in binary, so NaN estimations survive as NaN.
+//
+// VTK orders the nodes of a grid from south to north, while grid.Spec.At runs
+// from north to south. The writers take values in the order of grid.Spec.At
+// and reorder them. The sinks write each row to its place in the file as soon
+// as it is complete, which is why they need an io.WriterAt, and store .vti
+// arrays as raw appended data.
+package vtk
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+)
+
+// Format is the file format of grid output.
+type Format int
+
+const (
+	// Legacy writes the legacy structured points format, usually .vtk
+	Legacy Format = iota
+	// ImageData writes XML image data, usually .vti
+	ImageData
+)
+
+// ParseFormat returns the format for the file extension name: vtk or vti.
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "vtk":
+		return Legacy, nil
+	case "vti":
+		return ImageData, nil
+	default:
+		return Legacy, fmt.Errorf("unsupported VTK format: %s", name)
+	}
+}
+
+// Array is a named point data array, e.g. the kriging field or one SGS
+// realization.
+type Array struct {
+	Name   string
+	Values []float64
+}
+
+// checkArrays returns an error unless every array holds n values
+func checkArrays(n int, arrays []Array) error {
+	for _, a := range arrays {
+		if len(a.Values) != n {
+			return fmt.Errorf("array %s has %d values, want %d", a.Name, len(a.Values), n)
+		}
+	}
+	return nil
+}
+
+// vtkOrder returns the values of a grid, given in the order of grid.Spec.At,
+// in VTK order with y increasing, as float32.
+func vtkOrder(spec grid.Spec, values []float64) []float32 {
+	nz := max(spec.NZ, 1)
+	out := make([]float32, 0, len(values))
+	for layer := 0; layer < nz; layer++ {
+		for row := spec.NY - 1; row >= 0; row-- {
+			for col := 0; col < spec.NX; col++ {
+				out = append(out, float32(values[spec.Offset(col, row, layer)]))
+			}
+		}
+	}
+	return out
+}
+
+// geometry returns the dimensions, origin and spacing of the grid in VTK
+// terms. 2D grids are a single layer at z = 0.
+func geometry(spec grid.Spec) (dims [3]int, origin, spacing [3]float64) {
+	x, y, z := spec.Center()
+	dims = [3]int{spec.NX, spec.NY, max(spec.NZ, 1)}
+	origin = [3]float64{x, y, z}
+	spacing = [3]float64{spec.DX, spec.DY, spec.DZ}
+	if !spec.Is3D() {
+		origin[2], spacing[2] = 0, 1
+	}
+	return dims, origin, spacing
+}
+
+// formatFloat formats coordinates for the text parts of the files
+func formatFloat(v float64) string {
+	if math.IsNaN(v) {
+		return "nan"
+	}
+	return fmt.Sprintf("%g", v)
+}
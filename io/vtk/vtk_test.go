@@ -0,0 +1,222 @@
+package vtk
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// spec is a 2 x 2 grid with the values 0 to 3 in the order of At, so the
+// south-west node holds 2
+var spec = grid.Spec{X0: 10, Y0: 20, DX: 1, DY: 1, NX: 2, NY: 2}
+
+func TestWriteStructuredPoints(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteStructuredPoints(&buf, spec, Array{"field", []float64{0, 1, 2, math.NaN()}})
+	if err != nil {
+		t.Fatalf("WriteStructuredPoints() error = %v", err)
+	}
+	out := buf.String()
+	for _, line := range []string{"DIMENSIONS 2 2 1\n", "ORIGIN 10 20 0\n", "POINT_DATA 4\n", "LOOKUP_TABLE default\n"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("missing %q in\n%s", line, out)
+		}
+	}
+	data := []byte(out[strings.Index(out, "LOOKUP_TABLE default\n")+21:])
+	want := []float64{2, math.NaN(), 0, 1}
+	for i, w := range want {
+		v := float64(math.Float32frombits(binary.BigEndian.Uint32(data[4*i:])))
+		if v != w && !(math.IsNaN(v) && math.IsNaN(w)) {
+			t.Errorf("value %d = %v, want %v", i, v, w)
+		}
+	}
+}
+
+type dataArray struct {
+	Name string `xml:"Name,attr"`
+	Data string `xml:",chardata"`
+}
+
+// arrays decodes all data arrays of an XML file
+func arrays(t *testing.T, b []byte) map[string][]byte {
+	t.Helper()
+	var all []dataArray
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid XML: %v", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "DataArray" {
+			var a dataArray
+			if err := dec.DecodeElement(&a, &start); err != nil {
+				t.Fatalf("invalid data array: %v", err)
+			}
+			all = append(all, a)
+		}
+	}
+	out := map[string][]byte{}
+	for _, a := range all {
+		raw, err := base64.StdEncoding.DecodeString(a.Data)
+		if err != nil {
+			t.Fatalf("invalid base64 in %s: %v", a.Name, err)
+		}
+		if n := binary.LittleEndian.Uint32(raw); int(n) != len(raw)-4 {
+			t.Errorf("%s header counts %d bytes, got %d", a.Name, n, len(raw)-4)
+		}
+		out[a.Name] = raw[4:]
+	}
+	return out
+}
+
+// tempFile returns a new file in the test's temporary directory
+func tempFile(t *testing.T, name string) *os.File {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// readAll returns the contents of f
+func readAll(t *testing.T, f *os.File) []byte {
+	t.Helper()
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// appended decodes the raw appended float32 arrays of an XML file
+func appended(t *testing.T, b []byte) map[string][]float32 {
+	t.Helper()
+	const start = "<AppendedData encoding=\"raw\">\n_"
+	i := bytes.Index(b, []byte(start))
+	if i < 0 {
+		t.Fatalf("no raw appended data in\n%s", b)
+	}
+	data := b[i+len(start):]
+	if !bytes.HasSuffix(b, []byte("\n</AppendedData>\n</VTKFile>\n")) {
+		t.Errorf("file does not end with the appended data")
+	}
+	out := map[string][]float32{}
+	dec := xml.NewDecoder(bytes.NewReader(b[:i]))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "DataArray" {
+			continue
+		}
+		var name string
+		var offset int
+		for _, a := range start.Attr {
+			switch a.Name.Local {
+			case "Name":
+				name = a.Value
+			case "offset":
+				offset, _ = strconv.Atoi(a.Value)
+			}
+		}
+		n := int(binary.LittleEndian.Uint32(data[offset:])) / 4
+		values := make([]float32, n)
+		for j := range values {
+			values[j] = math.Float32frombits(binary.LittleEndian.Uint32(data[offset+4+4*j:]))
+		}
+		out[name] = values
+	}
+	return out
+}
+
+func TestEnsembleImageData(t *testing.T) {
+	f := tempFile(t, "sgs.vti")
+	ens, err := NewEnsemble(f, spec, 2, ImageData)
+	if err != nil {
+		t.Fatalf("NewEnsemble() error = %v", err)
+	}
+	if err := ens.Close(); err == nil {
+		t.Error("expected an error for incomplete realizations")
+	}
+	for _, sim := range []int{1, 0} {
+		sink, _ := ens.Realization(sim)
+		for i := 0; i < spec.Len(); i++ {
+			sink.Write(spec.At(i), types.Estimation{Field: float64(10*sim + i)})
+		}
+		if err := sink.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+	}
+	if err := ens.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	got := appended(t, readAll(t, f))
+	want := map[string][]float32{"sim_0": {2, 3, 0, 1}, "sim_1": {12, 13, 10, 11}}
+	for name, w := range want {
+		if fmt.Sprint(got[name]) != fmt.Sprint(w) {
+			t.Errorf("%s = %v, want %v", name, got[name], w)
+		}
+	}
+}
+
+func TestSinkStructuredPoints(t *testing.T) {
+	f := tempFile(t, "krig.vtk")
+	sink, err := NewSink(f, spec, Legacy)
+	if err != nil {
+		t.Fatalf("NewSink() error = %v", err)
+	}
+	for i := 0; i < spec.Len(); i++ {
+		if err := sink.Write(spec.At(i), types.Estimation{Field: float64(i), Variance: float64(10 + i)}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	// the sink writes the same file as WriteStructuredPoints
+	var buf bytes.Buffer
+	WriteStructuredPoints(&buf, spec, Array{"field", []float64{0, 1, 2, 3}}, Array{"variance", []float64{10, 11, 12, 13}})
+	if got := readAll(t, f); !bytes.Equal(got, buf.Bytes()) {
+		t.Errorf("sink wrote\n%q\nwant\n%q", got, buf.Bytes())
+	}
+}
+
+func TestWritePoints(t *testing.T) {
+	points := types.Points{Points: []types.Point{
+		{X: 1, Y: 2, Value: 3, Covariates: map[string]float64{"elev": 4}},
+		{X: 5, Y: 6, Value: 7},
+	}}
+	var buf bytes.Buffer
+	if err := WritePoints(&buf, points); err != nil {
+		t.Fatalf("WritePoints() error = %v", err)
+	}
+	got := arrays(t, buf.Bytes())
+	if _, ok := got["error_variance"]; ok {
+		t.Error("error_variance should be left out without measurement errors")
+	}
+	elev := got["elev"]
+	if len(elev) != 8 || !math.IsNaN(float64(math.Float32frombits(binary.LittleEndian.Uint32(elev[4:])))) {
+		t.Errorf("missing covariates should be NaN, got %v", elev)
+	}
+}
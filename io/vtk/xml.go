@@ -0,0 +1,183 @@
+package vtk
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// xmlWriter writes the elements of XML VTK files with inline binary arrays
+type xmlWriter struct {
+	w *bufio.Writer
+}
+
+func (x xmlWriter) open(dataset string) {
+	fmt.Fprintln(x.w, `<?xml version="1.0"?>`)
+	fmt.Fprintf(x.w, `<VTKFile type="%s" version="1.0" byte_order="LittleEndian" header_type="UInt32">`+"\n", dataset)
+}
+
+func (x xmlWriter) close() error {
+	fmt.Fprintln(x.w, "</VTKFile>")
+	return x.w.Flush()
+}
+
+// escape escapes s for use in an attribute value
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// array writes a binary data array of type typ, which is base64 encoded
+// together with its byte count as header
+func (x xmlWriter) array(typ, name string, components int, data []byte) {
+	attrs := fmt.Sprintf(`type="%s"`, typ)
+	if name != "" {
+		attrs += fmt.Sprintf(` Name="%s"`, escape(name))
+	}
+	if components > 1 {
+		attrs += fmt.Sprintf(` NumberOfComponents="%d"`, components)
+	}
+	raw := binary.LittleEndian.AppendUint32(nil, uint32(len(data)))
+	raw = append(raw, data...)
+	fmt.Fprintf(x.w, "<DataArray %s format=\"binary\">%s</DataArray>\n", attrs, base64.StdEncoding.EncodeToString(raw))
+}
+
+// pointData writes the arrays as float32 point data
+func (x xmlWriter) pointData(arrays []Array, order func([]float64) []float32) {
+	if len(arrays) == 0 {
+		fmt.Fprintln(x.w, "<PointData>")
+	} else {
+		fmt.Fprintf(x.w, "<PointData Scalars=\"%s\">\n", escape(arrays[0].Name))
+	}
+	for _, a := range arrays {
+		values := order(a.Values)
+		data := make([]byte, 0, 4*len(values))
+		for _, v := range values {
+			data = binary.LittleEndian.AppendUint32(data, math.Float32bits(v))
+		}
+		x.array("Float32", a.Name, 1, data)
+	}
+	fmt.Fprintln(x.w, "</PointData>")
+}
+
+// WriteImageData writes the arrays on the grid spec as XML VTK image data.
+// Each array holds one value per node in the order of grid.Spec.At.
+func WriteImageData(w io.Writer, spec grid.Spec, arrays ...Array) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+	if err := checkArrays(spec.Len(), arrays); err != nil {
+		return err
+	}
+	dims, origin, spacing := geometry(spec)
+	extent := fmt.Sprintf("0 %d 0 %d 0 %d", dims[0]-1, dims[1]-1, dims[2]-1)
+
+	x := xmlWriter{bufio.NewWriter(w)}
+	x.open("ImageData")
+	fmt.Fprintf(x.w, "<ImageData WholeExtent=\"%s\" Origin=\"%s %s %s\" Spacing=\"%s %s %s\">\n", extent,
+		formatFloat(origin[0]), formatFloat(origin[1]), formatFloat(origin[2]),
+		formatFloat(spacing[0]), formatFloat(spacing[1]), formatFloat(spacing[2]))
+	fmt.Fprintf(x.w, "<Piece Extent=\"%s\">\n", extent)
+	x.pointData(arrays, func(v []float64) []float32 { return vtkOrder(spec, v) })
+	fmt.Fprintln(x.w, "</Piece>")
+	fmt.Fprintln(x.w, "</ImageData>")
+	return x.close()
+}
+
+// WriteUnstructured writes the points as XML VTK unstructured grid of vertex
+// cells, e.g. the nodes of an irregular target grid. Each array holds one
+// value per point.
+func WriteUnstructured(w io.Writer, points types.Points, arrays ...Array) error {
+	n := len(points.Points)
+	if err := checkArrays(n, arrays); err != nil {
+		return err
+	}
+
+	coords := make([]byte, 0, 24*n)
+	connectivity := make([]byte, 0, 4*n)
+	offsets := make([]byte, 0, 4*n)
+	cells := make([]byte, n)
+	for i, p := range points.Points {
+		coords = binary.LittleEndian.AppendUint64(coords, math.Float64bits(p.X))
+		coords = binary.LittleEndian.AppendUint64(coords, math.Float64bits(p.Y))
+		coords = binary.LittleEndian.AppendUint64(coords, math.Float64bits(p.Z))
+		connectivity = binary.LittleEndian.AppendUint32(connectivity, uint32(i))
+		offsets = binary.LittleEndian.AppendUint32(offsets, uint32(i+1))
+		// VTK_VERTEX
+		cells[i] = 1
+	}
+
+	x := xmlWriter{bufio.NewWriter(w)}
+	x.open("UnstructuredGrid")
+	fmt.Fprintln(x.w, "<UnstructuredGrid>")
+	fmt.Fprintf(x.w, "<Piece NumberOfPoints=\"%d\" NumberOfCells=\"%d\">\n", n, n)
+	fmt.Fprintln(x.w, "<Points>")
+	x.array("Float64", "", 3, coords)
+	fmt.Fprintln(x.w, "</Points>")
+	fmt.Fprintln(x.w, "<Cells>")
+	x.array("Int32", "connectivity", 1, connectivity)
+	x.array("Int32", "offsets", 1, offsets)
+	x.array("UInt8", "types", 1, cells)
+	fmt.Fprintln(x.w, "</Cells>")
+	x.pointData(arrays, func(v []float64) []float32 {
+		out := make([]float32, len(v))
+		for i := range v {
+			out[i] = float32(v[i])
+		}
+		return out
+	})
+	fmt.Fprintln(x.w, "</Piece>")
+	fmt.Fprintln(x.w, "</UnstructuredGrid>")
+	return x.close()
+}
+
+// WritePoints writes conditioning data as point cloud, see WriteUnstructured,
+// with the arrays value, the error variance if any point has one, and one
+// array per covariate, which is NaN where a point lacks the covariate.
+func WritePoints(w io.Writer, points types.Points) error {
+	n := len(points.Points)
+	value := Array{Name: "value", Values: make([]float64, n)}
+	errVar := Array{Name: "error_variance", Values: make([]float64, n)}
+	hasErr := false
+	names := map[string]bool{}
+	for i, p := range points.Points {
+		value.Values[i] = p.Value
+		errVar.Values[i] = p.ErrorVariance
+		hasErr = hasErr || p.ErrorVariance != 0
+		for name := range p.Covariates {
+			names[name] = true
+		}
+	}
+
+	arrays := []Array{value}
+	if hasErr {
+		arrays = append(arrays, errVar)
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		a := Array{Name: name, Values: make([]float64, n)}
+		for i, p := range points.Points {
+			v, ok := p.Covariate(name)
+			if !ok {
+				v = math.NaN()
+			}
+			a.Values[i] = v
+		}
+		arrays = append(arrays, a)
+	}
+	return WriteUnstructured(w, points, arrays...)
+}
@@ -0,0 +1,145 @@
+// Package xyz reads and writes grids as plain XYZ text: one node per line
+// with its coordinates followed by its values, separated by spaces.
+package xyz
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+// Options control the missing value and precision of XYZ text.
+type Options struct {
+	// NoData is written for NaN values, and read as NaN
+	NoData float64
+	// Precision is the number of decimals of the values, -1 for the shortest
+	// representation that reads back exactly
+	Precision int
+}
+
+// DefaultOptions returns a nodata value of -9999 and full precision.
+func DefaultOptions() Options {
+	return Options{NoData: -9999, Precision: -1}
+}
+
+// Sink writes a line of x, y, z for 3D grids, and one column per value
+// selector as soon as an estimation is produced. Estimations may arrive in
+// any order. It implements types.EstimationSink.
+type Sink struct {
+	w      *bufio.Writer
+	is3D   bool
+	values []func(types.Estimation) float64
+	opts   Options
+}
+
+// NewSink returns a sink writing the nodes of spec with one column per value
+// selector, e.g. asc.Field and asc.Variance.
+func NewSink(w io.Writer, spec grid.Spec, opts Options, values ...func(types.Estimation) float64) (*Sink, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("at least one value column is needed")
+	}
+	return &Sink{w: bufio.NewWriter(w), is3D: spec.Is3D(), values: values, opts: opts}, nil
+}
+
+func (s *Sink) Write(p types.Point, e types.Estimation) error {
+	coord := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	s.w.WriteString(coord(p.X))
+	s.w.WriteByte(' ')
+	s.w.WriteString(coord(p.Y))
+	if s.is3D {
+		s.w.WriteByte(' ')
+		s.w.WriteString(coord(p.Z))
+	}
+	for _, value := range s.values {
+		v := value(e)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			v = s.opts.NoData
+		}
+		s.w.WriteByte(' ')
+		s.w.WriteString(strconv.FormatFloat(v, 'f', s.opts.Precision, 64))
+	}
+	return s.w.WriteByte('\n')
+}
+
+func (s *Sink) Flush() error {
+	return s.w.Flush()
+}
+
+// Read reads a 2D grid from XYZ text with x, y and value in the first three
+// columns. Lines that do not start with a number, like a header, are
+// skipped. The grid is derived from the node coordinates; nodes missing from
+// the text are NaN, as are values equal to NoData. The values are returned in
+// the order of grid.Spec.At.
+func Read(r io.Reader, opts Options) (grid.Spec, []float64, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var nodes []types.Point
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.FieldsFunc(scanner.Text(), func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ','
+		})
+		if len(fields) == 0 {
+			continue
+		}
+		if _, err := strconv.ParseFloat(fields[0], 64); err != nil {
+			continue
+		}
+		if len(fields) < 3 {
+			return grid.Spec{}, nil, fmt.Errorf("line %d has %d columns, need x, y and value", line, len(fields))
+		}
+		var xyv [3]float64
+		for i := range xyv {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return grid.Spec{}, nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			xyv[i] = v
+		}
+		if xyv[2] == opts.NoData {
+			xyv[2] = math.NaN()
+		}
+		nodes = append(nodes, types.Point{X: xyv[0], Y: xyv[1], Value: xyv[2]})
+	}
+	if err := scanner.Err(); err != nil {
+		return grid.Spec{}, nil, fmt.Errorf("failed to read XYZ: %w", err)
+	}
+	if len(nodes) == 0 {
+		return grid.Spec{}, nil, fmt.Errorf("no grid nodes found")
+	}
+
+	spec := grid.FromNodes(nodes)
+	values := make([]float64, spec.Len())
+	for i := range values {
+		values[i] = math.NaN()
+	}
+	for _, p := range nodes {
+		i, ok := spec.Locate(p)
+		if !ok {
+			return grid.Spec{}, nil, fmt.Errorf("node (%g, %g) is off the grid %+v", p.X, p.Y, spec)
+		}
+		values[i] = p.Value
+	}
+	return spec, values, nil
+}
+
+// ReadFile opens path and reads it with Read.
+func ReadFile(path string, opts Options) (grid.Spec, []float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return grid.Spec{}, nil, err
+	}
+	defer f.Close()
+	return Read(f, opts)
+}
@@ -0,0 +1,52 @@
+package xyz
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mmaelicke/go-geostat/internal/grid"
+	"github.com/mmaelicke/go-geostat/internal/types"
+)
+
+func TestRoundTrip(t *testing.T) {
+	spec := grid.Spec{X0: 10, Y0: 20, DX: 2, DY: 1, NX: 3, NY: 2}
+	var buf bytes.Buffer
+	sink, err := NewSink(&buf, spec, DefaultOptions(),
+		func(e types.Estimation) float64 { return e.Field },
+		func(e types.Estimation) float64 { return e.Variance })
+	if err != nil {
+		t.Fatalf("NewSink() error = %v", err)
+	}
+	for i := 0; i < spec.Len(); i++ {
+		field := float64(i)
+		if i == 4 {
+			field = math.NaN()
+		}
+		sink.Write(spec.At(i), types.Estimation{Field: field, Variance: 0.5})
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if first := strings.SplitN(buf.String(), "\n", 2)[0]; first != "10 21 0 0.5" {
+		t.Errorf("first line = %q, want the north-west node", first)
+	}
+
+	got, values, err := Read(strings.NewReader("x y field variance\n"+buf.String()), DefaultOptions())
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if err := spec.Aligned(got); err != nil {
+		t.Error(err)
+	}
+	for i, v := range values {
+		if i == 4 {
+			if !math.IsNaN(v) {
+				t.Errorf("NoData should be read as NaN, got %v", v)
+			}
+		} else if v != float64(i) {
+			t.Errorf("value %d = %v, want %d", i, v, i)
+		}
+	}
+}